@@ -0,0 +1,244 @@
+// Package augmentation provides random transformations of images that are
+// applied to every mini-batch while training, so that the network sees a
+// slightly different version of each training image in every epoch.
+//
+// Each column of a mini-batch is an image flattened in the same layout as
+// InputArray.ToNumberArray in main.go, i.e: the value of the row i, column j
+// and channel c is in the feature i*width*channels + j*channels + c
+package augmentation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Image holds the values of an image indexed by row, column and channel
+type Image [][][]float64
+
+// Creates an image of the given size with all its values set to 0
+func newImage(height, width, channels int) Image {
+	image := make(Image, height)
+	for i := range image {
+		image[i] = make([][]float64, width)
+		for j := range image[i] {
+			image[i][j] = make([]float64, channels)
+		}
+	}
+	return image
+}
+
+// Augmentation is a random transformation of an image that keeps its size
+type Augmentation interface {
+	Apply(image Image, random *rand.Rand) Image
+}
+
+// Pipeline applies its augmentations in order to every image of a mini-batch
+type Pipeline struct {
+	Height        int
+	Width         int
+	Channels      int
+	Augmentations []Augmentation
+}
+
+// NewPipeline creates a Pipeline for images of the given size
+func NewPipeline(height, width, channels int, augmentations ...Augmentation) *Pipeline {
+	pipeline := new(Pipeline)
+	pipeline.Height = height
+	pipeline.Width = width
+	pipeline.Channels = channels
+	pipeline.Augmentations = augmentations
+	return pipeline
+}
+
+// TransformBatch returns a new mini-batch with the augmentations applied to
+// each of the images of X, drawing the random values from random
+func (pipeline *Pipeline) TransformBatch(X matrix.NumberArray, random *rand.Rand) (matrix.NumberArray, error) {
+	size := pipeline.Height * pipeline.Width * pipeline.Channels
+	if X.GetRows() != size {
+		return nil, fmt.Errorf("Can't augment images of %vx%vx%v from an input"+
+			" with %v features", pipeline.Height, pipeline.Width, pipeline.Channels, X.GetRows())
+	}
+	resultingMatrix, _ := matrix.NewMatrix(X.GetRows(), X.GetColumns())
+	image := newImage(pipeline.Height, pipeline.Width, pipeline.Channels)
+	for example := 0; example < X.GetColumns(); example++ {
+		pipeline.unflatten(X, example, image)
+		augmented := image
+		for _, augmentation := range pipeline.Augmentations {
+			augmented = augmentation.Apply(augmented, random)
+		}
+		pipeline.flatten(augmented, resultingMatrix, example)
+	}
+	return resultingMatrix, nil
+}
+
+// Copies the image in the given column of X into image
+func (pipeline *Pipeline) unflatten(X matrix.NumberArray, example int, image Image) {
+	for i := 0; i < pipeline.Height; i++ {
+		for j := 0; j < pipeline.Width; j++ {
+			for c := 0; c < pipeline.Channels; c++ {
+				image[i][j][c], _ = X.GetValue(pipeline.feature(i, j, c), example)
+			}
+		}
+	}
+}
+
+// Copies image into the given column of X
+func (pipeline *Pipeline) flatten(image Image, X matrix.NumberArray, example int) {
+	for i := 0; i < pipeline.Height; i++ {
+		for j := 0; j < pipeline.Width; j++ {
+			for c := 0; c < pipeline.Channels; c++ {
+				X.SetValue(pipeline.feature(i, j, c), example, image[i][j][c])
+			}
+		}
+	}
+}
+
+// Returns the feature holding the value of row i, column j and channel c
+func (pipeline *Pipeline) feature(i, j, c int) int {
+	return i*pipeline.Width*pipeline.Channels + j*pipeline.Channels + c
+}
+
+// HorizontalFlip mirrors the image left to right with the given probability
+type HorizontalFlip struct {
+	Probability float64
+}
+
+// Apply flips the image if it's drawn to be flipped
+func (flip *HorizontalFlip) Apply(image Image, random *rand.Rand) Image {
+	if random.Float64() >= flip.Probability {
+		return image
+	}
+	width := len(image[0])
+	flipped := newImage(len(image), width, len(image[0][0]))
+	for i := range image {
+		for j := range image[i] {
+			copy(flipped[i][width-1-j], image[i][j])
+		}
+	}
+	return flipped
+}
+
+// RandomCrop pads the image with Padding zeros on every side, and crops it
+// back to its original size at a random offset, i.e: shifts the image by up to
+// Padding pixels in each direction
+type RandomCrop struct {
+	Padding int
+}
+
+// Apply shifts the image by a random offset, filling with zeros
+func (crop *RandomCrop) Apply(image Image, random *rand.Rand) Image {
+	height, width, channels := len(image), len(image[0]), len(image[0][0])
+	offsetI := random.Intn(2*crop.Padding+1) - crop.Padding
+	offsetJ := random.Intn(2*crop.Padding+1) - crop.Padding
+	cropped := newImage(height, width, channels)
+	for i := range cropped {
+		for j := range cropped[i] {
+			sourceI, sourceJ := i+offsetI, j+offsetJ
+			if sourceI >= 0 && sourceI < height && sourceJ >= 0 && sourceJ < width {
+				copy(cropped[i][j], image[sourceI][sourceJ])
+			}
+		}
+	}
+	return cropped
+}
+
+// Rotation rotates the image around its center by a random angle between
+// -MaxDegrees and MaxDegrees, filling with zeros the pixels that come from
+// outside of the image
+type Rotation struct {
+	MaxDegrees float64
+}
+
+// Apply rotates the image by a random angle
+func (rotation *Rotation) Apply(image Image, random *rand.Rand) Image {
+	degrees := (2*random.Float64() - 1) * rotation.MaxDegrees
+	return rotate(image, degrees*math.Pi/180)
+}
+
+// Rotates the image clockwise by angle radians, interpolating the
+// values bilinearly
+func rotate(image Image, angle float64) Image {
+	height, width, channels := len(image), len(image[0]), len(image[0][0])
+	rotated := newImage(height, width, channels)
+	centerI, centerJ := float64(height-1)/2, float64(width-1)/2
+	sin, cos := math.Sincos(angle)
+	for i := range rotated {
+		for j := range rotated[i] {
+			// position in the original image of the rotated pixel
+			y, x := float64(i)-centerI, float64(j)-centerJ
+			sourceI := cos*y - sin*x + centerI
+			sourceJ := sin*y + cos*x + centerJ
+			i0, j0 := int(math.Floor(sourceI)), int(math.Floor(sourceJ))
+			di, dj := sourceI-float64(i0), sourceJ-float64(j0)
+			for c := 0; c < channels; c++ {
+				value := 0.0
+				for _, corner := range [4][3]float64{
+					{0, 0, (1 - di) * (1 - dj)}, {0, 1, (1 - di) * dj},
+					{1, 0, di * (1 - dj)}, {1, 1, di * dj},
+				} {
+					ci, cj := i0+int(corner[0]), j0+int(corner[1])
+					if corner[2] != 0 && ci >= 0 && ci < height && cj >= 0 && cj < width {
+						value += corner[2] * image[ci][cj][c]
+					}
+				}
+				rotated[i][j][c] = value
+			}
+		}
+	}
+	return rotated
+}
+
+// BrightnessContrast adds a random brightness between -MaxBrightness and
+// MaxBrightness to every value, and scales the values around their mean by a
+// random contrast factor between 1 - MaxContrast and 1 + MaxContrast
+type BrightnessContrast struct {
+	MaxBrightness float64
+	MaxContrast   float64
+}
+
+// Apply changes the brightness and contrast by random amounts
+func (jitter *BrightnessContrast) Apply(image Image, random *rand.Rand) Image {
+	brightness := (2*random.Float64() - 1) * jitter.MaxBrightness
+	contrast := 1 + (2*random.Float64()-1)*jitter.MaxContrast
+	mean, count := 0.0, 0
+	for i := range image {
+		for j := range image[i] {
+			for _, value := range image[i][j] {
+				mean += value
+				count++
+			}
+		}
+	}
+	mean /= float64(count)
+	jittered := newImage(len(image), len(image[0]), len(image[0][0]))
+	for i := range image {
+		for j := range image[i] {
+			for c, value := range image[i][j] {
+				jittered[i][j][c] = (value-mean)*contrast + mean + brightness
+			}
+		}
+	}
+	return jittered
+}
+
+// GaussianNoise adds noise drawn from a normal distribution with mean 0 and
+// standard deviation Std to every value
+type GaussianNoise struct {
+	Std float64
+}
+
+// Apply adds random noise to the image
+func (noise *GaussianNoise) Apply(image Image, random *rand.Rand) Image {
+	noisy := newImage(len(image), len(image[0]), len(image[0][0]))
+	for i := range image {
+		for j := range image[i] {
+			for c, value := range image[i][j] {
+				noisy[i][j][c] = value + random.NormFloat64()*noise.Std
+			}
+		}
+	}
+	return noisy
+}
@@ -0,0 +1,121 @@
+package augmentation
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// 3x3 image with a single channel whose values are their position
+var image = Image{
+	{{1}, {2}, {3}},
+	{{4}, {5}, {6}},
+	{{7}, {8}, {9}},
+}
+
+// True when both images have the same size and values up to 1e-9
+func equalImages(a, b Image) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		for j := range a[i] {
+			for c := range a[i][j] {
+				if math.Abs(a[i][j][c]-b[i][j][c]) > 1e-9 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func TestAugmentations(t *testing.T) {
+	tables := []struct {
+		name         string
+		augmentation Augmentation
+		expected     Image
+	}{
+		{"no flip", &HorizontalFlip{Probability: 0}, image},
+		{"flip", &HorizontalFlip{Probability: 1}, Image{{{3}, {2}, {1}}, {{6}, {5}, {4}}, {{9}, {8}, {7}}}},
+		{"no crop", &RandomCrop{Padding: 0}, image},
+		{"no rotation", &Rotation{MaxDegrees: 0}, image},
+		{"no jitter", &BrightnessContrast{}, image},
+		{"no noise", &GaussianNoise{Std: 0}, image},
+	}
+	for _, table := range tables {
+		actual := table.augmentation.Apply(image, rand.New(rand.NewSource(1)))
+		if !equalImages(table.expected, actual) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.name, table.expected, actual)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	expected := Image{{{7}, {4}, {1}}, {{8}, {5}, {2}}, {{9}, {6}, {3}}}
+	if actual := rotate(image, math.Pi/2); !equalImages(expected, actual) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, actual)
+	}
+}
+
+func TestRandomCrop(t *testing.T) {
+	crop := &RandomCrop{Padding: 1}
+	random := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		actual := crop.Apply(image, random)
+		// the center pixel always comes from a pixel of the original image
+		if actual[1][1][0] < 1 {
+			t.Errorf("Expected a shift of at most 1 pixel, Actual: %v\n", actual)
+		}
+	}
+}
+
+func TestBrightnessContrast(t *testing.T) {
+	jitter := &BrightnessContrast{MaxBrightness: 2, MaxContrast: 0.5}
+	actual := jitter.Apply(image, rand.New(rand.NewSource(1)))
+	// the values keep their order and their differences are scaled equally
+	scale := actual[0][1][0] - actual[0][0][0]
+	if scale < 0.5 || scale > 1.5 {
+		t.Errorf("Expected a contrast between 0.5 and 1.5, Actual: %v\n", scale)
+	}
+	if math.Abs(actual[2][2][0]-actual[0][0][0]-8*scale) > 1e-9 {
+		t.Errorf("Expected the contrast to scale all differences, Actual: %v\n", actual)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	// two images of 3x3 with a single channel, one per column
+	values := make([][]float64, 9)
+	for f := range values {
+		values[f] = []float64{float64(f + 1), float64(-f - 1)}
+	}
+	X, _ := matrix.NewMatrixFromSlice(values)
+	pipeline := NewPipeline(3, 3, 1, &HorizontalFlip{Probability: 1})
+	actual, err := pipeline.TransformBatch(X, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []float64{3, 2, 1, 6, 5, 4, 9, 8, 7}
+	for f, value := range expected {
+		first, _ := actual.GetValue(f, 0)
+		second, _ := actual.GetValue(f, 1)
+		if first != value || second != -value {
+			t.Errorf("Feature %v Expected: %v and %v, Actual: %v and %v\n", f, value, -value, first, second)
+		}
+	}
+
+	// the same seed draws the same augmentations
+	random := NewPipeline(3, 3, 1, &RandomCrop{Padding: 1}, &Rotation{MaxDegrees: 10}, &GaussianNoise{Std: 0.1})
+	a, _ := random.TransformBatch(X, rand.New(rand.NewSource(7)))
+	b, _ := random.TransformBatch(X, rand.New(rand.NewSource(7)))
+	if !reflect.DeepEqual(matrix.ToSlice(a), matrix.ToSlice(b)) {
+		t.Errorf("Expected: %v, Actual: %v\n", matrix.ToSlice(a), matrix.ToSlice(b))
+	}
+
+	if _, err := NewPipeline(64, 64, 3).TransformBatch(X, rand.New(rand.NewSource(1))); err == nil {
+		t.Errorf("Expected an error augmenting inputs of the wrong size")
+	}
+}
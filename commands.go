@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/dataset"
+	"github.com/chibby0ne/micro_neural_network/grpcserver"
+	"github.com/chibby0ne/micro_neural_network/h5io"
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/monitoring"
+	"github.com/chibby0ne/micro_neural_network/onnx"
+	"github.com/chibby0ne/micro_neural_network/plot"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+	"github.com/chibby0ne/micro_neural_network/server"
+	"google.golang.org/grpc"
+)
+
+// Subcommands of the program, given as its first argument
+var commands = map[string]func(args []string) error{
+	"predict": predict,
+	"inspect": inspect,
+	"serve":   serve,
+	"export":  export,
+	"summary": summary,
+	"plot":    plotCurves,
+	"weights": weights,
+}
+
+// Runs a trained model over an HDF5 dataset and writes its predictions and
+// evaluation to a new group of an HDF5 file, separate from the dataset unless
+// told otherwise
+func predict(args []string) error {
+	flags := flag.NewFlagSet("predict", flag.ExitOnError)
+	modelFile := flags.String("model", "model.json", "model saved with SaveModel")
+	dataFile := flags.String("data", testSetFile, "HDF5 file with the examples")
+	inputs := flags.String("x", inputTest, "dataset with the inputs of the examples")
+	outputs := flags.String("y", outputTestSet, "dataset with the desired outputs of the examples")
+	outputFile := flags.String("output", "predictions.h5", "HDF5 file the predictions are written to")
+	group := flags.String("group", "predictions", "group the predictions are written to, which must not exist yet")
+	threshold := flags.Float64("threshold", 0.5, "probability above which an example is labelled as 1")
+	batchSize := flags.Int("batch-size", 64, "number of examples read at once")
+	modelID := flags.String("id", "", "model ID written with the predictions (default the model file)")
+	flags.Parse(args)
+	if *modelID == "" {
+		*modelID = *modelFile
+	}
+	if h5io.GroupExists(*outputFile, *group) {
+		return fmt.Errorf("Can't write the predictions to group %v of %v, it already exists: pass another one with -group", *group, *outputFile)
+	}
+
+	parameters, pipeline, err := model.LoadModel(*modelFile)
+	if err != nil {
+		return err
+	}
+	reader, err := h5io.OpenBatchReader(*dataFile, *inputs, *outputs, *batchSize)
+	if err != nil {
+		return err
+	}
+	report, err := h5io.Evaluate(parameters, pipeline, reader, *modelID, *threshold)
+	// the data file must be closed before writing to it
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	if err := h5io.WriteReport(*outputFile, *group, report); err != nil {
+		return err
+	}
+
+	fmt.Printf("Accuracy: %v\n", report.Accuracy)
+	for _, class := range report.Classes {
+		fmt.Printf("Class %v: precision %v, recall %v, F1 score %v, support %v\n",
+			class.Class, class.Precision, class.Recall, class.F1Score, class.Support)
+	}
+	fmt.Printf("Predictions written to group %v of %v\n", *group, *outputFile)
+	return nil
+}
+
+// Describes the groups and datasets of the HDF5 files given as arguments
+func inspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: inspect file.h5...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("No HDF5 file given")
+	}
+	for _, filename := range flags.Args() {
+		infos, err := h5io.Inspect(filename)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v\n", filename)
+		if err := h5io.WriteInspection(os.Stdout, infos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Plots the learning curves of a training history and, when a model is given,
+// its ROC and precision-recall curves on an HDF5 dataset
+func plotCurves(args []string) error {
+	flags := flag.NewFlagSet("plot", flag.ExitOnError)
+	historyFile := flags.String("history", "", "history saved as CSV, JSON Lines or HDF5 (.h5)")
+	modelFile := flags.String("model", "", "model saved with SaveModel whose ROC and precision-recall curves are plotted")
+	dataFile := flags.String("data", testSetFile, "HDF5 file with the examples the model is evaluated on")
+	inputs := flags.String("x", inputTest, "dataset with the inputs of the examples")
+	outputs := flags.String("y", outputTestSet, "dataset with the desired outputs of the examples")
+	batchSize := flags.Int("batch-size", 64, "number of examples read at once")
+	outputDirectory := flags.String("output", ".", "directory the images are written to")
+	format := flags.String("format", "svg", "format of the images, svg or png")
+	flags.Parse(args)
+	if *historyFile == "" && *modelFile == "" {
+		flags.Usage()
+		return fmt.Errorf("No history or model given")
+	}
+	extension := "." + *format
+	if extension != plot.SVGExtension && extension != plot.PNGExtension {
+		return fmt.Errorf("Unknown image format: %v", *format)
+	}
+
+	charts := map[string]*plot.Chart{}
+	if *historyFile != "" {
+		var records []model.HistoryRecord
+		var err error
+		if filepath.Ext(*historyFile) == ".h5" {
+			records, err = h5io.ReadHistory(*historyFile)
+		} else {
+			records, err = model.LoadHistory(*historyFile)
+		}
+		if err != nil {
+			return err
+		}
+		if charts, err = plot.LearningCurves(records); err != nil {
+			return err
+		}
+	}
+	if *modelFile != "" {
+		parameters, pipeline, err := model.LoadModel(*modelFile)
+		if err != nil {
+			return err
+		}
+		reader, err := h5io.OpenBatchReader(*dataFile, *inputs, *outputs, *batchSize)
+		if err != nil {
+			return err
+		}
+		report, err := h5io.Evaluate(parameters, pipeline, reader, *modelFile, 0.5)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		probabilities, _ := matrix.NewMatrixFromSlice([][]float64{report.Probabilities})
+		Y, _ := matrix.NewMatrixFromSlice([][]float64{report.Desired})
+		charts["roc"] = plot.ROCChart(model.ROCCurve(probabilities, Y))
+		charts["precision_recall"] = plot.PrecisionRecallChart(model.PrecisionRecallCurve(probabilities, Y))
+	}
+
+	names := make([]string, 0, len(charts))
+	for name := range charts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		filename := filepath.Join(*outputDirectory, name+extension)
+		if err := charts[name].Save(filename); err != nil {
+			return err
+		}
+		fmt.Printf("Chart written to %v\n", filename)
+	}
+	return nil
+}
+
+// Renders the weights of the hidden units of a trained model as image tiles
+// and, for models of 2 features, their decision boundary over a CSV dataset
+func weights(args []string) error {
+	flags := flag.NewFlagSet("weights", flag.ExitOnError)
+	modelFile := flags.String("model", "model.json", "model saved with SaveModel")
+	tilesFile := flags.String("tiles", "", "PNG file the weights of the hidden units are written to")
+	height := flags.Int("height", 64, "height of the images the weights are reshaped to")
+	width := flags.Int("width", 64, "width of the images the weights are reshaped to")
+	channels := flags.Int("channels", 3, "channels of the images the weights are reshaped to, 1 or 3")
+	boundaryFile := flags.String("boundary", "", "PNG file the decision boundary is written to")
+	dataFile := flags.String("data", "", "CSV file with the examples of 2 features drawn over the decision boundary")
+	label := flags.String("label", "label", "column of the CSV file holding the labels")
+	size := flags.Int("size", 400, "width and height of the decision boundary in pixels")
+	flags.Parse(args)
+	if *tilesFile == "" && *boundaryFile == "" {
+		flags.Usage()
+		return fmt.Errorf("No output file given")
+	}
+
+	parameters, pipeline, err := model.LoadModel(*modelFile)
+	if err != nil {
+		return err
+	}
+	if *tilesFile != "" {
+		tiles, err := plot.WeightTiles(parameters.W1, *height, *width, *channels)
+		if err != nil {
+			return err
+		}
+		if err := plot.SavePNG(*tilesFile, tiles); err != nil {
+			return err
+		}
+		fmt.Printf("Weights written to %v\n", *tilesFile)
+	}
+	if *boundaryFile != "" {
+		if *dataFile == "" {
+			return fmt.Errorf("No CSV file given for the decision boundary")
+		}
+		table, err := dataset.LoadCSV(*dataFile, &dataset.CSVOptions{Header: true, Label: *label})
+		if err != nil {
+			return err
+		}
+		boundary, err := plot.DecisionBoundary(parameters, pipeline, table.X, table.Y, *size)
+		if err != nil {
+			return err
+		}
+		if err := plot.SavePNG(*boundaryFile, boundary); err != nil {
+			return err
+		}
+		fmt.Printf("Decision boundary written to %v\n", *boundaryFile)
+	}
+	return nil
+}
+
+// Prints the layers of a trained model, their shapes and number of parameters
+func summary(args []string) error {
+	flags := flag.NewFlagSet("summary", flag.ExitOnError)
+	modelFile := flags.String("model", "model.json", "model saved with SaveModel")
+	flags.Parse(args)
+
+	parameters, pipeline, err := model.LoadModel(*modelFile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v\n", *modelFile)
+	for _, transform := range pipeline {
+		fmt.Printf("Preprocessing: %v\n", preprocessing.TransformName(transform))
+	}
+	return model.NewSummary(parameters).Write(os.Stdout)
+}
+
+// Exports a trained model as an ONNX file. Models with preprocessing are
+// refused, since the exported network would silently predict on inputs that
+// weren't preprocessed
+func export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	modelFile := flags.String("model", "model.json", "model saved with SaveModel")
+	outputFile := flags.String("output", "model.onnx", "ONNX file the model is written to")
+	flags.Parse(args)
+
+	parameters, pipeline, err := model.LoadModel(*modelFile)
+	if err != nil {
+		return err
+	}
+	if len(pipeline) > 0 {
+		return fmt.Errorf("Can't export model %v with %v preprocessing steps, they aren't supported by the ONNX export", *modelFile, len(pipeline))
+	}
+	if err := onnx.Export(*outputFile, parameters); err != nil {
+		return err
+	}
+	fmt.Printf("Model written to %v\n", *outputFile)
+	return nil
+}
+
+// Timeouts of the HTTP server, so that slow or idle clients don't hold its
+// connections forever
+const (
+	serveReadTimeout  = 30 * time.Second
+	serveWriteTimeout = time.Minute
+	serveIdleTimeout  = 2 * time.Minute
+)
+
+// Serves the predictions of a trained model over HTTP and, when an address is
+// given, over gRPC. When a model directory is given, its newest model is
+// served and swapped for any newer one saved to it while serving. Requests of
+// a single example can be batched together, trading latency for throughput.
+// The metrics of the predictions are exposed at /metrics
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	modelFile := flags.String("model", "model.json", "model saved with SaveModel")
+	modelDirectory := flags.String("model-dir", "", "directory watched for new models, replacing -model")
+	poll := flags.Duration("poll", 10*time.Second, "interval between the checks of the model directory")
+	address := flags.String("addr", ":8080", "address the server listens on")
+	grpcAddress := flags.String("grpc-addr", "", "address the gRPC server listens on, none when empty")
+	threshold := flags.Float64("threshold", 0.5, "probability above which an example is labelled as 1")
+	maxBatch := flags.Int("max-batch", 1, "most requests of a single example predicted together, none batched when 1")
+	batchLatency := flags.Duration("batch-latency", 5*time.Millisecond, "longest wait of a request of a single example for others to batch with")
+	flags.Parse(args)
+	if *maxBatch < 1 || *batchLatency < 0 {
+		return fmt.Errorf("Can't batch up to %v requests waiting up to %v", *maxBatch, *batchLatency)
+	}
+
+	httpServer := server.NewServer(nil, *threshold)
+	httpServer.SetBatching(*maxBatch, *batchLatency)
+	metrics := monitoring.NewRegistry()
+	collector := monitoring.NewServingCollector(metrics)
+	httpServer.SetObserver(collector)
+	mux := http.NewServeMux()
+	mux.Handle("/", httpServer)
+	mux.Handle(monitoring.MetricsPath, metrics)
+	source := *modelFile
+	if *modelDirectory != "" {
+		registry, err := server.NewRegistry(*modelDirectory, httpServer)
+		if err != nil {
+			return err
+		}
+		go registry.Watch(context.Background(), *poll)
+		mux.Handle(server.VersionsPath, registry)
+		mux.Handle(server.RollbackPath, registry)
+		source = "of " + *modelDirectory
+	} else {
+		served, err := server.LoadModel(*modelFile)
+		if err != nil {
+			return err
+		}
+		if err := served.Validate(); err != nil {
+			return fmt.Errorf("Can't serve model %v: %v", *modelFile, err)
+		}
+		httpServer.SetModel(served)
+	}
+
+	if *grpcAddress != "" {
+		listener, err := net.Listen("tcp", *grpcAddress)
+		if err != nil {
+			return fmt.Errorf("Can't listen on %v: %v", *grpcAddress, err)
+		}
+		grpcServer := grpc.NewServer()
+		service := grpcserver.NewService(httpServer, *threshold)
+		service.SetObserver(collector)
+		grpcserver.Register(grpcServer, service)
+		log.Printf("Serving model %v over gRPC on %v\n", source, *grpcAddress)
+		go func() {
+			log.Fatal(grpcServer.Serve(listener))
+		}()
+	}
+	log.Printf("Serving model %v on %v\n", source, *address)
+	endpoint := &http.Server{
+		Addr:              *address,
+		Handler:           mux,
+		ReadHeaderTimeout: serveReadTimeout,
+		ReadTimeout:       serveReadTimeout,
+		WriteTimeout:      serveWriteTimeout,
+		IdleTimeout:       serveIdleTimeout,
+	}
+	return endpoint.ListenAndServe()
+}
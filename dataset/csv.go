@@ -0,0 +1,341 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Strategies for imputing the missing values of a numeric column
+const (
+	// Replaced by the mean of the values present in the column
+	ImputeMean string = "mean"
+	// Replaced by the median of the values present in the column
+	ImputeMedian string = "median"
+	// Replaced by the value that appears the most in the column
+	ImputeMostFrequent string = "most_frequent"
+	// Replaced by CSVOptions.FillValue
+	ImputeConstant string = "constant"
+)
+
+// CSVOptions configures how a CSV or TSV file is turned into a dataset. The
+// columns are given by name when the file has a header, or by their index
+// starting at 0 otherwise
+type CSVOptions struct {
+	// Separator of the fields. When 0 it's a tab for files with the .tsv
+	// extension, and a comma otherwise
+	Comma rune
+	// Whether the first row holds the names of the columns
+	Header bool
+	// Columns used as features, all but the label column when empty
+	Features []string
+	// Column holding the labels. Labels that aren't numbers are replaced by
+	// the index of their class
+	Label string
+	// Feature columns holding categories, which are one-hot encoded
+	Categorical []string
+	// Fields that are considered missing, besides the empty ones
+	MissingValues []string
+	// Strategy used to impute the missing values of the numeric columns,
+	// ImputeMean when empty. Missing categories are imputed with the most
+	// frequent category, unless the strategy is ImputeConstant, in which case
+	// none of the one-hot columns is set
+	Imputation string
+	// Value of the missing values with the ImputeConstant strategy
+	FillValue float64
+}
+
+// Table is a dataset loaded from a CSV or TSV file, with one example per
+// column in X and the labels in the single row of Y
+type Table struct {
+	X matrix.NumberArray
+	Y matrix.NumberArray
+	// Names of the rows of X. A categorical column named c with the category
+	// v is encoded in the feature named c=v
+	FeatureNames []string
+	// Names of the classes whose indexes are the labels, when the labels
+	// aren't numbers
+	Classes []string
+}
+
+// LoadCSV reads the dataset in filename
+func LoadCSV(filename string, options *CSVOptions) (*Table, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	withComma := *options
+	if withComma.Comma == 0 && strings.ToLower(filepath.Ext(filename)) == ".tsv" {
+		withComma.Comma = '\t'
+	}
+	return ReadCSV(f, &withComma)
+}
+
+// ReadCSV reads the dataset from r
+func ReadCSV(r io.Reader, options *CSVOptions) (*Table, error) {
+	reader := csv.NewReader(r)
+	if options.Comma != 0 {
+		reader.Comma = options.Comma
+	}
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if options.Header {
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("Can't read the header of an empty file")
+		}
+		names, rows = rows[0], rows[1:]
+	} else if len(rows) > 0 {
+		for i := range rows[0] {
+			names = append(names, strconv.Itoa(i))
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Can't create a dataset without examples")
+	}
+
+	labelColumn, err := findColumn(names, options.Label)
+	if err != nil {
+		return nil, err
+	}
+	var featureColumns []int
+	if len(options.Features) == 0 {
+		for i := range names {
+			if i != labelColumn {
+				featureColumns = append(featureColumns, i)
+			}
+		}
+	} else {
+		for _, feature := range options.Features {
+			column, err := findColumn(names, feature)
+			if err != nil {
+				return nil, err
+			}
+			featureColumns = append(featureColumns, column)
+		}
+	}
+	categorical := make(map[int]bool)
+	for _, name := range options.Categorical {
+		column, err := findColumn(names, name)
+		if err != nil {
+			return nil, err
+		}
+		categorical[column] = true
+	}
+	missing := map[string]bool{"": true}
+	for _, value := range options.MissingValues {
+		missing[value] = true
+	}
+
+	table := new(Table)
+	var features [][]float64
+	for _, column := range featureColumns {
+		fields := columnFields(rows, column)
+		var encoded [][]float64
+		var encodedNames []string
+		if categorical[column] {
+			encoded, encodedNames = oneHot(names[column], fields, missing, options.Imputation == ImputeConstant)
+		} else {
+			values, err := imputeNumbers(names[column], fields, missing, options)
+			if err != nil {
+				return nil, err
+			}
+			encoded, encodedNames = [][]float64{values}, []string{names[column]}
+		}
+		features = append(features, encoded...)
+		table.FeatureNames = append(table.FeatureNames, encodedNames...)
+	}
+	if len(features) == 0 {
+		return nil, fmt.Errorf("Can't create a dataset without features")
+	}
+	labels, classes, err := parseLabels(names[labelColumn], columnFields(rows, labelColumn), missing)
+	if err != nil {
+		return nil, err
+	}
+	table.Classes = classes
+	if table.X, err = matrix.NewMatrixFromSlice(features); err != nil {
+		return nil, err
+	}
+	if table.Y, err = matrix.NewMatrixFromSlice([][]float64{labels}); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// Returns the index of the column with the given name, or with the given
+// index if no column has that name
+func findColumn(names []string, column string) (int, error) {
+	for i, name := range names {
+		if name == column {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(column); err == nil && i >= 0 && i < len(names) {
+		return i, nil
+	}
+	return 0, fmt.Errorf("Can't find the column %q", column)
+}
+
+// Returns the fields of the given column in every row
+func columnFields(rows [][]string, column int) []string {
+	fields := make([]string, len(rows))
+	for i, row := range rows {
+		if column < len(row) {
+			fields[i] = strings.TrimSpace(row[column])
+		}
+	}
+	return fields
+}
+
+// Parses the numbers of a column and replaces the missing ones following the
+// imputation strategy
+func imputeNumbers(name string, fields []string, missing map[string]bool, options *CSVOptions) ([]float64, error) {
+	values := make([]float64, len(fields))
+	var present []float64
+	for i, field := range fields {
+		if missing[field] {
+			values[i] = math.NaN()
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse %q in column %v, it must be"+
+				" declared categorical if it isn't a number", field, name)
+		}
+		values[i] = value
+		present = append(present, value)
+	}
+	if len(present) == len(values) {
+		return values, nil
+	}
+	var fill float64
+	switch options.Imputation {
+	case "", ImputeMean:
+		if len(present) == 0 {
+			return nil, fmt.Errorf("Can't impute column %v without any value", name)
+		}
+		for _, value := range present {
+			fill += value / float64(len(present))
+		}
+	case ImputeMedian:
+		if len(present) == 0 {
+			return nil, fmt.Errorf("Can't impute column %v without any value", name)
+		}
+		sort.Float64s(present)
+		middle := len(present) / 2
+		fill = present[middle]
+		if len(present)%2 == 0 {
+			fill = (present[middle-1] + present[middle]) / 2
+		}
+	case ImputeMostFrequent:
+		if len(present) == 0 {
+			return nil, fmt.Errorf("Can't impute column %v without any value", name)
+		}
+		counts := make(map[float64]int)
+		for _, value := range present {
+			counts[value]++
+		}
+		// the smallest value wins the ties, so that the result is stable
+		sort.Float64s(present)
+		fill = present[0]
+		for _, value := range present {
+			if counts[value] > counts[fill] {
+				fill = value
+			}
+		}
+	case ImputeConstant:
+		fill = options.FillValue
+	default:
+		return nil, fmt.Errorf("Can't impute with the given strategy: %v", options.Imputation)
+	}
+	for i := range values {
+		if math.IsNaN(values[i]) {
+			values[i] = fill
+		}
+	}
+	return values, nil
+}
+
+// Encodes a categorical column into one row per category, sorted by name,
+// which is 1 for the examples of that category and 0 otherwise. Missing
+// categories are replaced by the most frequent one, or left with all the rows
+// at 0 if leaveEmpty is true
+func oneHot(name string, fields []string, missing map[string]bool, leaveEmpty bool) ([][]float64, []string) {
+	counts := make(map[string]int)
+	for _, field := range fields {
+		if !missing[field] {
+			counts[field]++
+		}
+	}
+	categories := make([]string, 0, len(counts))
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	mostFrequent := ""
+	for _, category := range categories {
+		if counts[category] > counts[mostFrequent] {
+			mostFrequent = category
+		}
+	}
+	index := make(map[string]int)
+	encoded := make([][]float64, len(categories))
+	names := make([]string, len(categories))
+	for i, category := range categories {
+		index[category] = i
+		encoded[i] = make([]float64, len(fields))
+		names[i] = name + "=" + category
+	}
+	for j, field := range fields {
+		if missing[field] {
+			if leaveEmpty || mostFrequent == "" {
+				continue
+			}
+			field = mostFrequent
+		}
+		encoded[index[field]][j] = 1
+	}
+	return encoded, names
+}
+
+// Parses the labels as numbers, or as the index of their class sorted by
+// name if any of them isn't a number
+func parseLabels(name string, fields []string, missing map[string]bool) (labels []float64, classes []string, err error) {
+	labels = make([]float64, len(fields))
+	numeric := true
+	for i, field := range fields {
+		if missing[field] {
+			return nil, nil, fmt.Errorf("Can't load example %v without a label in column %v", i, name)
+		}
+		if labels[i], err = strconv.ParseFloat(field, 64); err != nil {
+			numeric = false
+		}
+	}
+	if numeric {
+		return labels, nil, nil
+	}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			classes = append(classes, field)
+		}
+	}
+	sort.Strings(classes)
+	for i, field := range fields {
+		labels[i] = float64(sort.SearchStrings(classes, field))
+	}
+	return labels, classes, nil
+}
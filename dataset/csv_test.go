@@ -0,0 +1,117 @@
+package dataset
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+const weatherCSV = `outlook,temperature,humidity,windy,play
+sunny,85,85,false,no
+sunny,80,?,true,no
+overcast,83,86,false,yes
+,70,96,false,yes
+rainy,68,80,false,yes
+`
+
+func TestReadCSV(t *testing.T) {
+	tables := []struct {
+		name             string
+		options          CSVOptions
+		expectedNames    []string
+		expectedX        [][]float64
+		expectedY        []float64
+		expectedClasses  []string
+		expectedErrorMsg string
+	}{
+		{
+			"categorical and mean",
+			CSVOptions{Header: true, Label: "play", Features: []string{"outlook", "humidity"},
+				Categorical: []string{"outlook"}, MissingValues: []string{"?"}},
+			[]string{"outlook=overcast", "outlook=rainy", "outlook=sunny", "humidity"},
+			[][]float64{{0, 0, 1, 0, 0}, {0, 0, 0, 0, 1}, {1, 1, 0, 1, 0}, {85, 86.75, 86, 96, 80}},
+			[]float64{0, 0, 1, 1, 1},
+			[]string{"no", "yes"},
+			"",
+		},
+		{
+			"constant by index",
+			CSVOptions{Header: true, Label: "4", Features: []string{"0", "2"}, Categorical: []string{"0"},
+				MissingValues: []string{"?"}, Imputation: ImputeConstant, FillValue: -1},
+			[]string{"outlook=overcast", "outlook=rainy", "outlook=sunny", "humidity"},
+			[][]float64{{0, 0, 1, 0, 0}, {0, 0, 0, 0, 1}, {1, 1, 0, 0, 0}, {85, -1, 86, 96, 80}},
+			[]float64{0, 0, 1, 1, 1},
+			[]string{"no", "yes"},
+			"",
+		},
+		{
+			"median",
+			CSVOptions{Header: true, Label: "temperature", Features: []string{"humidity"},
+				MissingValues: []string{"?"}, Imputation: ImputeMedian},
+			[]string{"humidity"},
+			[][]float64{{85, 85.5, 86, 96, 80}},
+			[]float64{85, 80, 83, 70, 68},
+			nil,
+			"",
+		},
+		{
+			"not a number",
+			CSVOptions{Header: true, Label: "play", Features: []string{"windy"}},
+			nil, nil, nil, nil,
+			`Can't parse "false" in column windy, it must be declared categorical if it isn't a number`,
+		},
+		{
+			"unknown column",
+			CSVOptions{Header: true, Label: "rain"},
+			nil, nil, nil, nil,
+			`Can't find the column "rain"`,
+		},
+	}
+	for _, table := range tables {
+		actual, err := ReadCSV(strings.NewReader(weatherCSV), &table.options)
+		if table.expectedErrorMsg != "" {
+			if err == nil || err.Error() != table.expectedErrorMsg {
+				t.Errorf("%v Expected: %v, Actual: %v\n", table.name, table.expectedErrorMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", table.name, err)
+		}
+		if !reflect.DeepEqual(table.expectedNames, actual.FeatureNames) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.name, table.expectedNames, actual.FeatureNames)
+		}
+		if !reflect.DeepEqual(table.expectedX, matrix.ToSlice(actual.X)) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.name, table.expectedX, matrix.ToSlice(actual.X))
+		}
+		if !reflect.DeepEqual([][]float64{table.expectedY}, matrix.ToSlice(actual.Y)) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.name, table.expectedY, matrix.ToSlice(actual.Y))
+		}
+		if !reflect.DeepEqual(table.expectedClasses, actual.Classes) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.name, table.expectedClasses, actual.Classes)
+		}
+	}
+}
+
+func TestLoadTSV(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.tsv")
+	if err := os.WriteFile(filename, []byte("1\t2\t0\n3\tNA\t1\n5\t6\t1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := &CSVOptions{Label: "2", MissingValues: []string{"NA"}, Imputation: ImputeMostFrequent}
+	actual, err := LoadCSV(filename, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]float64{{1, 3, 5}, {2, 2, 6}}
+	if !reflect.DeepEqual(expected, matrix.ToSlice(actual.X)) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, matrix.ToSlice(actual.X))
+	}
+	if expected := []string{"0", "1"}; !reflect.DeepEqual(expected, actual.FeatureNames) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, actual.FeatureNames)
+	}
+}
@@ -0,0 +1,155 @@
+package dataset
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Codes of the types of the values in an IDX file
+const (
+	idxUnsignedByte byte = 0x08
+	idxSignedByte   byte = 0x09
+	idxShort        byte = 0x0B
+	idxInt          byte = 0x0C
+	idxFloat        byte = 0x0D
+	idxDouble       byte = 0x0E
+)
+
+// Largest value of an int
+const maxInt = int(^uint(0) >> 1)
+
+// Magic bytes at the beginning of a gzipped file
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadIDX reads an array in the IDX format used by MNIST, and returns its
+// dimensions and its values in row-major order
+func ReadIDX(r io.Reader) (dims []int, values []float64, err error) {
+	var header [4]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return nil, nil, fmt.Errorf("Can't read the IDX header: %v", err)
+	}
+	if header[0] != 0 || header[1] != 0 {
+		return nil, nil, fmt.Errorf("Can't read IDX with magic number %x", header)
+	}
+	dataType, numDims := header[2], int(header[3])
+	var width int
+	var decode func([]byte) float64
+	switch dataType {
+	case idxUnsignedByte:
+		width, decode = 1, func(b []byte) float64 { return float64(b[0]) }
+	case idxSignedByte:
+		width, decode = 1, func(b []byte) float64 { return float64(int8(b[0])) }
+	case idxShort:
+		width, decode = 2, func(b []byte) float64 { return float64(int16(binary.BigEndian.Uint16(b))) }
+	case idxInt:
+		width, decode = 4, func(b []byte) float64 { return float64(int32(binary.BigEndian.Uint32(b))) }
+	case idxFloat:
+		width, decode = 4, func(b []byte) float64 { return float64(math.Float32frombits(binary.BigEndian.Uint32(b))) }
+	case idxDouble:
+		width, decode = 8, func(b []byte) float64 { return math.Float64frombits(binary.BigEndian.Uint64(b)) }
+	default:
+		return nil, nil, fmt.Errorf("Can't read IDX values of type 0x%02x", dataType)
+	}
+
+	size := 1
+	dims = make([]int, numDims)
+	for i := range dims {
+		var dim uint32
+		if err = binary.Read(r, binary.BigEndian, &dim); err != nil {
+			return nil, nil, fmt.Errorf("Can't read the IDX dimensions: %v", err)
+		}
+		dims[i] = int(dim)
+		// the values must fit in a slice of bytes
+		if dims[i] > 0 && size > maxInt/width/dims[i] {
+			return nil, nil, fmt.Errorf("Can't read IDX with dimensions %v, too many values", dims[:i+1])
+		}
+		size *= dims[i]
+	}
+
+	// the values are read as they come instead of being allocated upfront, so
+	// that dimensions larger than the file fail without allocating for them
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(size*width)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't read the %v IDX values: %v", size, err)
+	}
+	if len(data) < size*width {
+		return nil, nil, fmt.Errorf("Can't read the %v IDX values: %v", size, io.ErrUnexpectedEOF)
+	}
+	values = make([]float64, size)
+	for i := range values {
+		values[i] = decode(data[i*width : (i+1)*width])
+	}
+	return dims, values, nil
+}
+
+// LoadIDX reads the IDX file in filename, which may be gzipped
+func LoadIDX(filename string) (dims []int, values []float64, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if magic, _ := r.(*bufio.Reader).Peek(len(gzipMagic)); string(magic) == string(gzipMagic) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	dims, values, err = ReadIDX(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	return dims, values, nil
+}
+
+// LoadMNIST reads the images and labels of MNIST, or of any dataset with the
+// same format like Fashion-MNIST, from the *-images-idx3-ubyte and
+// *-labels-idx1-ubyte files, gzipped or not. Each image is flattened row by
+// row into a column of X, with its pixels between 0 and 255, and its label is
+// in the same column of the single row of Y
+func LoadMNIST(imagesFile, labelsFile string) (X, Y matrix.NumberArray, err error) {
+	imageDims, pixels, err := LoadIDX(imagesFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	labelDims, labels, err := LoadIDX(labelsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(imageDims) != 3 {
+		return nil, nil, fmt.Errorf("Can't read images with %v dimensions, expected 3", len(imageDims))
+	}
+	if len(labelDims) != 1 {
+		return nil, nil, fmt.Errorf("Can't read labels with %v dimensions, expected 1", len(labelDims))
+	}
+	numImages, imageSize := imageDims[0], imageDims[1]*imageDims[2]
+	if numImages != labelDims[0] {
+		return nil, nil, fmt.Errorf("Can't match %v images with %v labels", numImages, labelDims[0])
+	}
+	inputs, err := matrix.NewMatrix(imageSize, numImages)
+	if err != nil {
+		return nil, nil, err
+	}
+	outputs, err := matrix.NewMatrix(1, numImages)
+	if err != nil {
+		return nil, nil, err
+	}
+	for example := 0; example < numImages; example++ {
+		for f := 0; f < imageSize; f++ {
+			inputs.SetValue(f, example, pixels[example*imageSize+f])
+		}
+		outputs.SetValue(0, example, labels[example])
+	}
+	return inputs, outputs, nil
+}
@@ -0,0 +1,118 @@
+package dataset
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Encodes the values in the IDX format with the given type and dimensions
+func encodeIDX(dataType byte, dims []int, values interface{}) []byte {
+	var buffer bytes.Buffer
+	buffer.Write([]byte{0, 0, dataType, byte(len(dims))})
+	for _, dim := range dims {
+		binary.Write(&buffer, binary.BigEndian, uint32(dim))
+	}
+	binary.Write(&buffer, binary.BigEndian, values)
+	return buffer.Bytes()
+}
+
+// Writes data to a file in dir, gzipped if compress is true
+func writeFile(t *testing.T, dir, name string, data []byte, compress bool) string {
+	if compress {
+		var buffer bytes.Buffer
+		gz := gzip.NewWriter(&buffer)
+		gz.Write(data)
+		gz.Close()
+		data = buffer.Bytes()
+	}
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadIDX(t *testing.T) {
+	tables := []struct {
+		name           string
+		data           []byte
+		expectedDims   []int
+		expectedValues []float64
+	}{
+		{"ubyte", encodeIDX(idxUnsignedByte, []int{2, 2}, []uint8{0, 1, 128, 255}), []int{2, 2}, []float64{0, 1, 128, 255}},
+		{"sbyte", encodeIDX(idxSignedByte, []int{2}, []int8{-1, 5}), []int{2}, []float64{-1, 5}},
+		{"short", encodeIDX(idxShort, []int{1, 3}, []int16{-300, 0, 300}), []int{1, 3}, []float64{-300, 0, 300}},
+		{"int", encodeIDX(idxInt, []int{1}, []int32{-70000}), []int{1}, []float64{-70000}},
+		{"float", encodeIDX(idxFloat, []int{2}, []float32{0.5, -2}), []int{2}, []float64{0.5, -2}},
+		{"double", encodeIDX(idxDouble, []int{1}, []float64{0.1}), []int{1}, []float64{0.1}},
+	}
+	for _, table := range tables {
+		dims, values, err := ReadIDX(bytes.NewReader(table.data))
+		if err != nil {
+			t.Fatalf("%v: %v", table.name, err)
+		}
+		if !reflect.DeepEqual(table.expectedDims, dims) || !reflect.DeepEqual(table.expectedValues, values) {
+			t.Errorf("%v Expected: %v %v, Actual: %v %v\n", table.name, table.expectedDims, table.expectedValues, dims, values)
+		}
+	}
+
+	errors := [][]byte{
+		{1, 0, idxUnsignedByte, 1},
+		{0, 0, 0x42, 0},
+		encodeIDX(idxUnsignedByte, []int{3}, []uint8{1, 2}),
+		// dimensions whose product overflows, or that the data can't hold
+		{0, 0, idxUnsignedByte, 2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0, 0, idxUnsignedByte, 3, 0x80, 0, 0, 0, 0x80, 0, 0, 0, 0x80, 0, 0, 0},
+		{0, 0, idxDouble, 2, 0x7f, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff, 1, 2, 3},
+		{0, 0, idxDouble, 1, 0x7f, 0xff, 0xff, 0xff, 1, 2, 3},
+	}
+	for _, data := range errors {
+		if _, _, err := ReadIDX(bytes.NewReader(data)); err == nil {
+			t.Errorf("Expected an error reading %v", data)
+		}
+	}
+}
+
+func TestLoadMNIST(t *testing.T) {
+	dir := t.TempDir()
+	// 3 images of 2x2
+	pixels := []uint8{
+		0, 1, 2, 3,
+		10, 11, 12, 13,
+		255, 254, 253, 252,
+	}
+	images := encodeIDX(idxUnsignedByte, []int{3, 2, 2}, pixels)
+	labels := encodeIDX(idxUnsignedByte, []int{3}, []uint8{7, 0, 9})
+	for _, compress := range []bool{false, true} {
+		imagesFile := writeFile(t, dir, "train-images-idx3-ubyte", images, compress)
+		labelsFile := writeFile(t, dir, "train-labels-idx1-ubyte", labels, compress)
+		X, Y, err := LoadMNIST(imagesFile, labelsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedX := [][]float64{{0, 10, 255}, {1, 11, 254}, {2, 12, 253}, {3, 13, 252}}
+		if !reflect.DeepEqual(expectedX, matrix.ToSlice(X)) {
+			t.Errorf("Expected: %v, Actual: %v\n", expectedX, matrix.ToSlice(X))
+		}
+		expectedY := [][]float64{{7, 0, 9}}
+		if !reflect.DeepEqual(expectedY, matrix.ToSlice(Y)) {
+			t.Errorf("Expected: %v, Actual: %v\n", expectedY, matrix.ToSlice(Y))
+		}
+	}
+
+	tooFewLabels := writeFile(t, dir, "labels", encodeIDX(idxUnsignedByte, []int{2}, []uint8{1, 2}), false)
+	imagesFile := writeFile(t, dir, "images", images, false)
+	if _, _, err := LoadMNIST(imagesFile, tooFewLabels); err == nil {
+		t.Errorf("Expected an error matching 3 images with 2 labels")
+	}
+	if _, _, err := LoadMNIST(tooFewLabels, tooFewLabels); err == nil {
+		t.Errorf("Expected an error reading labels as images")
+	}
+}
@@ -0,0 +1,182 @@
+package dataset
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	// decoders of the supported image formats
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Each pixel is coded in RGB, therefore contains 3 values
+const colorsChannel int = 3
+
+// Extensions of the image files that are loaded
+var imageExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+}
+
+// LoadImageFolder loads the images under root, where each subfolder of root
+// is a class and contains, at any depth, the images of that class. The images
+// are resized to width x height and returned with one image per column, as
+// expected by model.Model, with its RGB values between 0 and 255 stored row by
+// row, i.e: the feature i*width*3 + j*3 + c is the channel c of the pixel in
+// row i and column j. The labels are a single row with the index of the class
+// of each image in classes, which are the subfolder names sorted
+// alphabetically
+func LoadImageFolder(root string, width, height int) (X, Y matrix.NumberArray, classes []string, err error) {
+	if width < 1 || height < 1 {
+		return nil, nil, nil, fmt.Errorf("Can't resize images to %vx%v", width, height)
+	}
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var files []string
+	var labels []int
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		classFiles, err := imageFiles(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		files = append(files, classFiles...)
+		for range classFiles {
+			labels = append(labels, len(classes))
+		}
+		classes = append(classes, entry.Name())
+	}
+	if len(files) == 0 {
+		return nil, nil, nil, fmt.Errorf("Can't find any image in the class folders of %v", root)
+	}
+
+	inputs, _ := matrix.NewMatrix(width*height*colorsChannel, len(files))
+	outputs, _ := matrix.NewMatrix(1, len(files))
+	for example, filename := range files {
+		img, err := decodeImage(filename)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		setImage(inputs, example, resize(img, width, height))
+		outputs.SetValue(0, example, float64(labels[example]))
+	}
+	return inputs, outputs, classes, nil
+}
+
+// ImageFeatures returns the RGB values, between 0 and 255, of img resized to
+// width x height in the layout of a column of LoadImageFolder, i.e: row by row
+// with the channels of each pixel together
+func ImageFeatures(img image.Image, width, height int) ([]float64, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("Can't resize images to %vx%v", width, height)
+	}
+	pixels := resize(img, width, height)
+	features := make([]float64, 0, width*height*colorsChannel)
+	for i := range pixels {
+		for j := range pixels[i] {
+			features = append(features, pixels[i][j][:]...)
+		}
+	}
+	return features, nil
+}
+
+// Returns the image files under dir, at any depth, sorted by path
+func imageFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Decodes the image in filename with the decoder of its format
+func decodeImage(filename string) (image.Image, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode image %v: %v", filename, err)
+	}
+	return img, nil
+}
+
+// Returns the RGB values of img resized to width x height with bilinear
+// interpolation, indexed by row, column and channel
+func resize(img image.Image, width, height int) [][][colorsChannel]float64 {
+	bounds := img.Bounds()
+	// RGB values of the original image, between 0 and 255
+	source := make([][][colorsChannel]float64, bounds.Dy())
+	for i := range source {
+		source[i] = make([][colorsChannel]float64, bounds.Dx())
+		for j := range source[i] {
+			c := color.RGBAModel.Convert(img.At(bounds.Min.X+j, bounds.Min.Y+i)).(color.RGBA)
+			source[i][j] = [colorsChannel]float64{float64(c.R), float64(c.G), float64(c.B)}
+		}
+	}
+	scaleI := float64(bounds.Dy()) / float64(height)
+	scaleJ := float64(bounds.Dx()) / float64(width)
+	resized := make([][][colorsChannel]float64, height)
+	for i := range resized {
+		resized[i] = make([][colorsChannel]float64, width)
+		// align the centers of the pixels of both images
+		sourceI := clamp((float64(i)+0.5)*scaleI-0.5, 0, float64(bounds.Dy()-1))
+		i0 := int(sourceI)
+		i1 := int(math.Min(float64(i0+1), float64(bounds.Dy()-1)))
+		di := sourceI - float64(i0)
+		for j := range resized[i] {
+			sourceJ := clamp((float64(j)+0.5)*scaleJ-0.5, 0, float64(bounds.Dx()-1))
+			j0 := int(sourceJ)
+			j1 := int(math.Min(float64(j0+1), float64(bounds.Dx()-1)))
+			dj := sourceJ - float64(j0)
+			for c := 0; c < colorsChannel; c++ {
+				top := source[i0][j0][c]*(1-dj) + source[i0][j1][c]*dj
+				bottom := source[i1][j0][c]*(1-dj) + source[i1][j1][c]*dj
+				resized[i][j][c] = top*(1-di) + bottom*di
+			}
+		}
+	}
+	return resized
+}
+
+// Limits val to the range [min, max]
+func clamp(val, min, max float64) float64 {
+	return math.Max(min, math.Min(max, val))
+}
+
+// Stores the pixels in the column of X of the given example, row by row with
+// the channels of each pixel together
+func setImage(X matrix.NumberArray, example int, pixels [][][colorsChannel]float64) {
+	width := len(pixels[0])
+	for i := range pixels {
+		for j := range pixels[i] {
+			for c := 0; c < colorsChannel; c++ {
+				X.SetValue(i*width*colorsChannel+j*colorsChannel+c, example, pixels[i][j][c])
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Creates a 2x2 image with a red, green, blue and white pixel
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	img.Set(0, 1, color.RGBA{0, 0, 255, 255})
+	img.Set(1, 1, color.RGBA{255, 255, 255, 255})
+	return img
+}
+
+// Writes img to path with the encoder given by the extension of path, or
+// writes a file that isn't an image if img is nil
+func writeImage(t *testing.T, path string, img image.Image) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	switch {
+	case img == nil:
+		_, err = f.Write([]byte("not an image"))
+	case filepath.Ext(path) == ".png":
+		err = png.Encode(f, img)
+	case filepath.Ext(path) == ".jpg":
+		err = jpeg.Encode(f, img, nil)
+	default:
+		err = gif.Encode(f, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadImageFolder(t *testing.T) {
+	root := t.TempDir()
+	writeImage(t, filepath.Join(root, "cat", "a.png"), testImage())
+	writeImage(t, filepath.Join(root, "cat", "nested", "b.gif"), testImage())
+	writeImage(t, filepath.Join(root, "non-cat", "c.jpg"), testImage())
+	writeImage(t, filepath.Join(root, "non-cat", "notes.txt"), nil)
+	writeImage(t, filepath.Join(root, "readme.png"), testImage())
+
+	X, Y, classes, err := LoadImageFolder(root, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"cat", "non-cat"}; !reflect.DeepEqual(expected, classes) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, classes)
+	}
+	if X.GetRows() != 12 || X.GetColumns() != 3 || Y.GetRows() != 1 || Y.GetColumns() != 3 {
+		t.Fatalf("Expected: 12x3 and 1x3, Actual: %vx%v and %vx%v\n", X.GetRows(), X.GetColumns(), Y.GetRows(), Y.GetColumns())
+	}
+	for example, expected := range []float64{0, 0, 1} {
+		if actual, _ := Y.GetValue(0, example); expected != actual {
+			t.Errorf("Example %v Expected: %v, Actual: %v\n", example, expected, actual)
+		}
+	}
+	// the PNG is lossless, and its pixels are stored row by row in RGB
+	expected := []float64{255, 0, 0, 0, 255, 0, 0, 0, 255, 255, 255, 255}
+	for f, value := range expected {
+		if actual, _ := X.GetValue(f, 0); value != actual {
+			t.Errorf("Feature %v Expected: %v, Actual: %v\n", f, value, actual)
+		}
+	}
+}
+
+func TestLoadImageFolderResize(t *testing.T) {
+	root := t.TempDir()
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.Gray{uint8(x * 60)})
+		img.Set(x, 1, color.Gray{uint8(x * 60)})
+	}
+	writeImage(t, filepath.Join(root, "gradient", "a.png"), img)
+	X, _, _, err := LoadImageFolder(root, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each resized pixel is the average of two neighbouring pixels
+	expected := []float64{30, 30, 30, 150, 150, 150}
+	for f, value := range expected {
+		if actual, _ := X.GetValue(f, 0); value != actual {
+			t.Errorf("Feature %v Expected: %v, Actual: %v\n", f, value, actual)
+		}
+	}
+}
+
+func TestLoadImageFolderErrors(t *testing.T) {
+	root := t.TempDir()
+	if _, _, _, err := LoadImageFolder(root, 2, 2); err == nil {
+		t.Errorf("Expected an error loading a folder without images")
+	}
+	if _, _, _, err := LoadImageFolder(root, 0, 2); err == nil {
+		t.Errorf("Expected an error resizing to 0x2")
+	}
+	writeImage(t, filepath.Join(root, "cat", "broken.png"), nil)
+	if _, _, _, err := LoadImageFolder(root, 2, 2); err == nil {
+		t.Errorf("Expected an error decoding a broken image")
+	}
+}
+
+func TestImageFeatures(t *testing.T) {
+	tables := []struct {
+		width, height int
+		expected      []float64
+	}{
+		{2, 2, []float64{255, 0, 0, 0, 255, 0, 0, 0, 255, 255, 255, 255}},
+		{1, 1, []float64{127.5, 127.5, 127.5}},
+	}
+	for _, table := range tables {
+		actual, err := ImageFeatures(testImage(), table.width, table.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(table.expected, actual) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expected, actual)
+		}
+	}
+	if _, err := ImageFeatures(testImage(), 0, 1); err == nil {
+		t.Errorf("Expected an error resizing to 0x1")
+	}
+}
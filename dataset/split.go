@@ -0,0 +1,186 @@
+// Package dataset provides utilities for loading datasets and splitting them
+// into the sets used for training and evaluating the neural network.
+//
+// Unless stated otherwise, the arrays hold one example per column, as used by
+// the model package, and the labels are a single row
+package dataset
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Split holds the inputs and labels of the training, validation and test
+// sets. The validation and test arrays are nil when their fraction is 0
+type Split struct {
+	XTrain      matrix.NumberArray
+	YTrain      matrix.NumberArray
+	XValidation matrix.NumberArray
+	YValidation matrix.NumberArray
+	XTest       matrix.NumberArray
+	YTest       matrix.NumberArray
+}
+
+// Fold holds the indexes of the examples used for training and for
+// validation in one fold of a cross-validation
+type Fold struct {
+	Train      []int
+	Validation []int
+}
+
+// Shuffle returns the examples of X and Y in a random order given by random
+func Shuffle(X, Y matrix.NumberArray, random *rand.Rand) (XShuffled, YShuffled matrix.NumberArray, err error) {
+	if err = checkExamples(X, Y); err != nil {
+		return nil, nil, err
+	}
+	permutation := random.Perm(X.GetColumns())
+	return selectExamples(X, Y, permutation)
+}
+
+// Returns an error if X and Y don't have the same number of examples
+func checkExamples(X, Y matrix.NumberArray) error {
+	if X.GetColumns() != Y.GetColumns() {
+		return fmt.Errorf("Can't split %v inputs with %v labels", X.GetColumns(), Y.GetColumns())
+	}
+	return nil
+}
+
+// Returns the examples with the given indexes, or nil arrays if there's none
+func selectExamples(X, Y matrix.NumberArray, indexes []int) (XSelected, YSelected matrix.NumberArray, err error) {
+	if len(indexes) == 0 {
+		return nil, nil, nil
+	}
+	if XSelected, err = matrix.SelectColumns(X, indexes); err != nil {
+		return nil, nil, err
+	}
+	if YSelected, err = matrix.SelectColumns(Y, indexes); err != nil {
+		return nil, nil, err
+	}
+	return XSelected, YSelected, nil
+}
+
+// Groups the indexes of the examples by their label in the first row of Y.
+// The groups are sorted by label so that the result doesn't depend on the
+// order of the map
+func groupByLabel(Y matrix.NumberArray) [][]int {
+	groups := make(map[float64][]int)
+	for j := 0; j < Y.GetColumns(); j++ {
+		label, _ := Y.GetValue(0, j)
+		groups[label] = append(groups[label], j)
+	}
+	labels := make([]float64, 0, len(groups))
+	for label := range groups {
+		labels = append(labels, label)
+	}
+	sort.Float64s(labels)
+	result := make([][]int, len(labels))
+	for i, label := range labels {
+		result[i] = groups[label]
+	}
+	return result
+}
+
+// Returns the indexes of all the examples in a single group, or grouped by
+// label if stratify is true, with each group shuffled
+func shuffledGroups(Y matrix.NumberArray, stratify bool, random *rand.Rand) [][]int {
+	var groups [][]int
+	if stratify {
+		groups = groupByLabel(Y)
+	} else {
+		all := make([]int, Y.GetColumns())
+		for j := range all {
+			all[j] = j
+		}
+		groups = [][]int{all}
+	}
+	for _, group := range groups {
+		random.Shuffle(len(group), func(a, b int) {
+			group[a], group[b] = group[b], group[a]
+		})
+	}
+	return groups
+}
+
+// TrainValidationTestSplit shuffles the examples and splits them into a
+// training, validation and test set, with the given fractions of examples for
+// the last two. When stratify is true, each label keeps the same proportion in
+// every set
+func TrainValidationTestSplit(X, Y matrix.NumberArray, validationFraction, testFraction float64, stratify bool, random *rand.Rand) (*Split, error) {
+	if err := checkExamples(X, Y); err != nil {
+		return nil, err
+	}
+	if validationFraction < 0 || testFraction < 0 || validationFraction+testFraction >= 1 {
+		return nil, fmt.Errorf("Can't split with validation fraction %v and"+
+			" test fraction %v", validationFraction, testFraction)
+	}
+	var train, validation, test []int
+	for _, group := range shuffledGroups(Y, stratify, random) {
+		numTest := int(float64(len(group))*testFraction + 0.5)
+		numValidation := int(float64(len(group))*validationFraction + 0.5)
+		if numTest+numValidation > len(group) {
+			numValidation = len(group) - numTest
+		}
+		test = append(test, group[:numTest]...)
+		validation = append(validation, group[numTest:numTest+numValidation]...)
+		train = append(train, group[numTest+numValidation:]...)
+	}
+	if len(train) == 0 {
+		return nil, fmt.Errorf("Can't split %v examples leaving none for training", X.GetColumns())
+	}
+	split := new(Split)
+	var err error
+	if split.XTrain, split.YTrain, err = selectExamples(X, Y, train); err != nil {
+		return nil, err
+	}
+	if split.XValidation, split.YValidation, err = selectExamples(X, Y, validation); err != nil {
+		return nil, err
+	}
+	if split.XTest, split.YTest, err = selectExamples(X, Y, test); err != nil {
+		return nil, err
+	}
+	return split, nil
+}
+
+// KFold shuffles the examples and splits them into k folds. Each example is
+// used for validation in exactly one of the folds. When stratify is true, each
+// label keeps the same proportion in every fold
+func KFold(Y matrix.NumberArray, k int, stratify bool, random *rand.Rand) ([]Fold, error) {
+	if k < 2 || k > Y.GetColumns() {
+		return nil, fmt.Errorf("Can't split %v examples into %v folds", Y.GetColumns(), k)
+	}
+	// deal the examples of each group to the folds in turns, continuing with
+	// the next fold where the previous group left off
+	assignment := make([][]int, k)
+	next := 0
+	for _, group := range shuffledGroups(Y, stratify, random) {
+		for _, j := range group {
+			assignment[next] = append(assignment[next], j)
+			next = (next + 1) % k
+		}
+	}
+	folds := make([]Fold, k)
+	for i := range folds {
+		folds[i].Validation = assignment[i]
+		for l := range assignment {
+			if l != i {
+				folds[i].Train = append(folds[i].Train, assignment[l]...)
+			}
+		}
+	}
+	return folds, nil
+}
+
+// Examples returns the inputs and labels of the examples with the given
+// indexes
+func Examples(X, Y matrix.NumberArray, indexes []int) (XSelected, YSelected matrix.NumberArray, err error) {
+	if err = checkExamples(X, Y); err != nil {
+		return nil, nil, err
+	}
+	if len(indexes) == 0 {
+		return nil, nil, fmt.Errorf("Can't select 0 examples")
+	}
+	return selectExamples(X, Y, indexes)
+}
@@ -0,0 +1,118 @@
+package dataset
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Creates a dataset with 20 examples of 2 features, where the first feature is
+// the index of the example and the label is 1 for a quarter of them
+func indexedDataset() (X, Y matrix.NumberArray) {
+	XValues := [][]float64{make([]float64, 20), make([]float64, 20)}
+	YValues := [][]float64{make([]float64, 20)}
+	for j := 0; j < 20; j++ {
+		XValues[0][j] = float64(j)
+		XValues[1][j] = float64(-j)
+		if j%4 == 0 {
+			YValues[0][j] = 1
+		}
+	}
+	X, _ = matrix.NewMatrixFromSlice(XValues)
+	Y, _ = matrix.NewMatrixFromSlice(YValues)
+	return X, Y
+}
+
+// Returns the indexes of the examples in X, and the number of positive labels
+func examplesOf(t *testing.T, X, Y matrix.NumberArray) (indexes []int, positives int) {
+	if X == nil {
+		return nil, 0
+	}
+	for j := 0; j < X.GetColumns(); j++ {
+		index, _ := X.GetValue(0, j)
+		label, _ := Y.GetValue(0, j)
+		if label != 0 && int(index)%4 != 0 || label == 0 && int(index)%4 == 0 {
+			t.Errorf("Example %v has the wrong label %v", index, label)
+		}
+		indexes = append(indexes, int(index))
+		positives += int(label)
+	}
+	return indexes, positives
+}
+
+func TestTrainValidationTestSplit(t *testing.T) {
+	X, Y := indexedDataset()
+	tables := []struct {
+		validation, test float64
+		stratify         bool
+		expectedSizes    [3]int
+		expectedPositive [3]int
+	}{
+		{0.2, 0.2, true, [3]int{12, 4, 4}, [3]int{3, 1, 1}},
+		{0.25, 0, false, [3]int{15, 5, 0}, [3]int{-1, -1, 0}},
+	}
+	for _, table := range tables {
+		split, err := TrainValidationTestSplit(X, Y, table.validation, table.test, table.stratify, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var all []int
+		parts := [][2]matrix.NumberArray{
+			{split.XTrain, split.YTrain}, {split.XValidation, split.YValidation}, {split.XTest, split.YTest},
+		}
+		for i, part := range parts {
+			indexes, positives := examplesOf(t, part[0], part[1])
+			if table.expectedSizes[i] != len(indexes) {
+				t.Errorf("Part %v Expected: %v, Actual: %v\n", i, table.expectedSizes[i], len(indexes))
+			}
+			if table.expectedPositive[i] >= 0 && table.expectedPositive[i] != positives {
+				t.Errorf("Part %v Expected: %v, Actual: %v\n", i, table.expectedPositive[i], positives)
+			}
+			all = append(all, indexes...)
+		}
+		// every example is in exactly one of the sets
+		sort.Ints(all)
+		for j, index := range all {
+			if j != index {
+				t.Fatalf("Expected every example once, Actual: %v\n", all)
+			}
+		}
+	}
+	if _, err := TrainValidationTestSplit(X, Y, 0.5, 0.5, false, rand.New(rand.NewSource(1))); err == nil {
+		t.Errorf("Expected an error leaving no examples for training")
+	}
+}
+
+func TestKFold(t *testing.T) {
+	_, Y := indexedDataset()
+	folds, err := KFold(Y, 5, true, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int]int)
+	for i, fold := range folds {
+		if len(fold.Validation) != 4 || len(fold.Train) != 16 {
+			t.Errorf("Fold %v Expected: 4 and 16 examples, Actual: %v and %v\n", i, len(fold.Validation), len(fold.Train))
+		}
+		positives := 0
+		for _, j := range fold.Validation {
+			seen[j]++
+			if j%4 == 0 {
+				positives++
+			}
+		}
+		if positives != 1 {
+			t.Errorf("Fold %v Expected: 1 positive example, Actual: %v\n", i, positives)
+		}
+	}
+	if len(seen) != 20 {
+		t.Errorf("Expected every example validated once, Actual: %v\n", seen)
+	}
+	for _, k := range []int{1, 21} {
+		if _, err := KFold(Y, k, false, rand.New(rand.NewSource(1))); err == nil {
+			t.Errorf("Expected an error splitting into %v folds", k)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package grpcserver
+
+import "fmt"
+
+// The messages of prediction.proto. The protobuf struct tags give the field
+// numbers and wire types used by the protobuf codec of gRPC
+
+// Instance is the feature vector of an example
+type Instance struct {
+	Features []float64 `protobuf:"fixed64,1,rep,packed,name=features,proto3" json:"features,omitempty"`
+}
+
+// Reset clears the message
+func (m *Instance) Reset() { *m = Instance{} }
+
+// String returns the message in a human readable form
+func (m *Instance) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks the type as a protobuf message
+func (*Instance) ProtoMessage() {}
+
+// PredictRequest holds the examples to predict
+type PredictRequest struct {
+	Instances []*Instance `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+}
+
+// Reset clears the message
+func (m *PredictRequest) Reset() { *m = PredictRequest{} }
+
+// String returns the message in a human readable form
+func (m *PredictRequest) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks the type as a protobuf message
+func (*PredictRequest) ProtoMessage() {}
+
+// Prediction of a single example
+type Prediction struct {
+	// Probability of the example belonging to the class 1
+	Probability float64 `protobuf:"fixed64,1,opt,name=probability,proto3" json:"probability,omitempty"`
+	// 1 when the probability is above the threshold, 0 otherwise
+	Label int32 `protobuf:"varint,2,opt,name=label,proto3" json:"label,omitempty"`
+}
+
+// Reset clears the message
+func (m *Prediction) Reset() { *m = Prediction{} }
+
+// String returns the message in a human readable form
+func (m *Prediction) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks the type as a protobuf message
+func (*Prediction) ProtoMessage() {}
+
+// PredictResponse holds the predictions of the instances of a request, in the
+// same order
+type PredictResponse struct {
+	Model       string        `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Predictions []*Prediction `protobuf:"bytes,2,rep,name=predictions,proto3" json:"predictions,omitempty"`
+}
+
+// Reset clears the message
+func (m *PredictResponse) Reset() { *m = PredictResponse{} }
+
+// String returns the message in a human readable form
+func (m *PredictResponse) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks the type as a protobuf message
+func (*PredictResponse) ProtoMessage() {}
+
+// MetadataRequest asks for the Metadata of the model
+type MetadataRequest struct{}
+
+// Reset clears the message
+func (m *MetadataRequest) Reset() { *m = MetadataRequest{} }
+
+// String returns the message in a human readable form
+func (m *MetadataRequest) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks the type as a protobuf message
+func (*MetadataRequest) ProtoMessage() {}
+
+// Metadata describes the model being served
+type Metadata struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Seconds since the Unix epoch at which the model was loaded
+	LoadedAt          int64    `protobuf:"varint,2,opt,name=loaded_at,json=loadedAt,proto3" json:"loaded_at,omitempty"`
+	NumberFeatures    int32    `protobuf:"varint,3,opt,name=number_features,json=numberFeatures,proto3" json:"number_features,omitempty"`
+	NumberHiddenUnits int32    `protobuf:"varint,4,opt,name=number_hidden_units,json=numberHiddenUnits,proto3" json:"number_hidden_units,omitempty"`
+	Threshold         float64  `protobuf:"fixed64,5,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Preprocessing     []string `protobuf:"bytes,6,rep,name=preprocessing,proto3" json:"preprocessing,omitempty"`
+	// Version given by the registry of the model directory, 0 otherwise
+	Version int32 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+// Reset clears the message
+func (m *Metadata) Reset() { *m = Metadata{} }
+
+// String returns the message in a human readable form
+func (m *Metadata) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks the type as a protobuf message
+func (*Metadata) ProtoMessage() {}
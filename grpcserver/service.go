@@ -0,0 +1,277 @@
+// Package grpcserver serves the predictions of a trained model over gRPC, with
+// the Predictor service of prediction.proto
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+	"github.com/chibby0ne/micro_neural_network/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Full name of the service, as declared in prediction.proto
+const serviceName string = "mnn.v1.Predictor"
+
+// Full names of the methods of the service
+const (
+	predictMethod       string = "/" + serviceName + "/Predict"
+	predictStreamMethod string = "/" + serviceName + "/PredictStream"
+	getMetadataMethod   string = "/" + serviceName + "/GetMetadata"
+)
+
+// Number of examples of each forward pass, between which the deadline and
+// cancellation of the request are checked
+const defaultChunkSize int = 256
+
+// PredictorServer is the server API of the Predictor service
+type PredictorServer interface {
+	Predict(ctx context.Context, request *PredictRequest) (*PredictResponse, error)
+	PredictStream(stream grpc.ServerStream) error
+	GetMetadata(ctx context.Context, request *MetadataRequest) (*Metadata, error)
+}
+
+// Description of the service used to register it in a grpc.Server
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*PredictorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Predict", Handler: predictHandler},
+		{MethodName: "GetMetadata", Handler: getMetadataHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "PredictStream", Handler: predictStreamHandler, ServerStreams: true, ClientStreams: true},
+	},
+	Metadata: "prediction.proto",
+}
+
+// Decodes the request of Predict and calls it through the interceptor
+func predictHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	request := new(PredictRequest)
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).Predict(ctx, request)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: predictMethod}
+	handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return srv.(PredictorServer).Predict(ctx, request.(*PredictRequest))
+	}
+	return interceptor(ctx, request, info, handler)
+}
+
+// Decodes the request of GetMetadata and calls it through the interceptor
+func getMetadataHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	request := new(MetadataRequest)
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).GetMetadata(ctx, request)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: getMetadataMethod}
+	handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return srv.(PredictorServer).GetMetadata(ctx, request.(*MetadataRequest))
+	}
+	return interceptor(ctx, request, info, handler)
+}
+
+// Calls PredictStream with the stream
+func predictStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PredictorServer).PredictStream(stream)
+}
+
+// Register registers the Predictor service in the gRPC server
+func Register(grpcServer *grpc.Server, predictor PredictorServer) {
+	grpcServer.RegisterService(&serviceDesc, predictor)
+}
+
+// ModelProvider provides the model being served, e.g. a server.Server, so
+// that the HTTP and gRPC servers serve the same model, batched together
+type ModelProvider interface {
+	// Model returns the model being served, nil if there's none
+	Model() *server.Model
+	// Predict returns the probability of each example of X, with one
+	// example per column, belonging to the class 1 according to the model,
+	// running the forward pass over chunks of chunkSize examples until the
+	// context is done
+	Predict(ctx context.Context, served *server.Model, X matrix.NumberArray, chunkSize int) (matrix.NumberArray, error)
+}
+
+// Service implements the Predictor service with the model of the provider
+type Service struct {
+	models    ModelProvider
+	observer  server.PredictionObserver
+	threshold float64
+	chunkSize int
+}
+
+// NewService creates a Service predicting with the model of the provider,
+// labelling as 1 the examples whose probability is above the threshold
+func NewService(models ModelProvider, threshold float64) *Service {
+	return &Service{models: models, threshold: threshold, chunkSize: defaultChunkSize}
+}
+
+// SetObserver sets the observer notified of the prediction requests, none
+// when nil. It must be set before serving
+func (service *Service) SetObserver(observer server.PredictionObserver) {
+	service.observer = observer
+}
+
+// Returns the model being served, or an Unavailable error if there's none
+func (service *Service) model() (*server.Model, error) {
+	served := service.models.Model()
+	if served == nil {
+		return nil, status.Error(codes.Unavailable, "No model loaded")
+	}
+	return served, nil
+}
+
+// Converts the error of a context to its status, any other error of the
+// prediction being an internal one
+func contextError(err error) error {
+	switch err {
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// Predict predicts the instances of the request. The forward pass stops as
+// soon as the deadline of the request is exceeded or it's cancelled
+func (service *Service) Predict(ctx context.Context, request *PredictRequest) (*PredictResponse, error) {
+	start := time.Now()
+	response, err := service.predict(ctx, request)
+	if service.observer != nil {
+		examples := 0
+		if response != nil {
+			examples = len(response.Predictions)
+		}
+		service.observer.ObservePrediction(server.GRPCAPI, examples, time.Since(start), err)
+	}
+	return response, err
+}
+
+// Answers the request of Predict
+func (service *Service) predict(ctx context.Context, request *PredictRequest) (*PredictResponse, error) {
+	served, err := service.model()
+	if err != nil {
+		return nil, err
+	}
+	numberFeatures := served.NumberFeatures()
+	if len(request.Instances) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Can't predict a request without instances")
+	}
+	examples, err := matrix.NewMatrix(numberFeatures, len(request.Instances))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	X := matrix.NumberArray(examples)
+	for j, instance := range request.Instances {
+		if instance == nil || len(instance.Features) != numberFeatures {
+			return nil, status.Errorf(codes.InvalidArgument, "Can't predict instance %v, the model has %v features", j, numberFeatures)
+		}
+		for i, value := range instance.Features {
+			X.SetValue(i, j, value)
+		}
+	}
+
+	A2, err := service.models.Predict(ctx, served, X, service.chunkSize)
+	if err != nil {
+		return nil, contextError(err)
+	}
+	response := &PredictResponse{Model: served.Name, Predictions: make([]*Prediction, A2.GetColumns())}
+	for j := range response.Predictions {
+		probability, _ := A2.GetValue(0, j)
+		prediction := &Prediction{Probability: probability}
+		if probability > service.threshold {
+			prediction.Label = 1
+		}
+		response.Predictions[j] = prediction
+	}
+	return response, nil
+}
+
+// PredictStream answers each request of the stream with its predictions,
+// until the client closes its side of the stream
+func (service *Service) PredictStream(stream grpc.ServerStream) error {
+	for {
+		request := new(PredictRequest)
+		if err := stream.RecvMsg(request); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		response, err := service.Predict(stream.Context(), request)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(response); err != nil {
+			return err
+		}
+	}
+}
+
+// GetMetadata describes the model being served
+func (service *Service) GetMetadata(ctx context.Context, request *MetadataRequest) (*Metadata, error) {
+	served, err := service.model()
+	if err != nil {
+		return nil, err
+	}
+	metadata := &Metadata{
+		Name:              served.Name,
+		LoadedAt:          served.LoadedAt.Unix(),
+		NumberFeatures:    int32(served.NumberFeatures()),
+		NumberHiddenUnits: int32(served.Parameters.W1.GetRows()),
+		Threshold:         service.threshold,
+		Version:           int32(served.Version),
+	}
+	for _, transform := range served.Pipeline {
+		metadata.Preprocessing = append(metadata.Preprocessing, preprocessing.TransformName(transform))
+	}
+	return metadata, nil
+}
+
+// Client calls the Predictor service through a gRPC connection
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// NewClient creates a Client using the connection
+func NewClient(conn *grpc.ClientConn) *Client {
+	return &Client{conn: conn}
+}
+
+// Predict calls the Predict method of the service
+func (client *Client) Predict(ctx context.Context, request *PredictRequest, opts ...grpc.CallOption) (*PredictResponse, error) {
+	response := new(PredictResponse)
+	if err := client.conn.Invoke(ctx, predictMethod, request, response, opts...); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetMetadata calls the GetMetadata method of the service
+func (client *Client) GetMetadata(ctx context.Context, request *MetadataRequest, opts ...grpc.CallOption) (*Metadata, error) {
+	metadata := new(Metadata)
+	if err := client.conn.Invoke(ctx, getMetadataMethod, request, metadata, opts...); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// PredictStream opens a stream to the PredictStream method of the service.
+// Requests are sent with SendMsg and their responses received with RecvMsg,
+// and CloseSend ends the stream
+func (client *Client) PredictStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return client.conn.NewStream(ctx, &serviceDesc.Streams[0], predictStreamMethod, opts...)
+}
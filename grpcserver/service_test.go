@@ -0,0 +1,208 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+	"github.com/chibby0ne/micro_neural_network/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Model of 12 features that predicts the class 1 when the first feature is
+// high
+func testModel() *server.Model {
+	weights := make([]float64, 12)
+	weights[0] = 0.01
+	W1, _ := matrix.NewMatrixFromSlice([][]float64{weights})
+	B1, _ := matrix.NewMatrixFromSlice([][]float64{{-1}})
+	W2, _ := matrix.NewMatrixFromSlice([][]float64{{10}})
+	B2, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	parameters := &model.Parameters{W1: W1, B1: B1, W2: W2, B2: B2}
+	pipeline := preprocessing.Pipeline{preprocessing.NewRescaler(1)}
+	return server.NewModel("test", parameters, pipeline)
+}
+
+// Returns an instance of the test model whose first feature is first
+func instance(first float64) *Instance {
+	features := make([]float64, 12)
+	features[0] = first
+	return &Instance{Features: features}
+}
+
+// Serves the service on an in-process listener and returns a client
+// connected to it, along with a function stopping both
+func serve(t *testing.T, service *Service) (*Client, func()) {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	Register(grpcServer, service)
+	go grpcServer.Serve(listener)
+	dialer := func(string, time.Duration) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.Dial("bufconn", grpc.WithDialer(dialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(conn), func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+// PredictionObserver recording the number of examples of every request and
+// whether it failed
+type recordingObserver struct {
+	mutex    sync.Mutex
+	examples []int
+	failed   []bool
+}
+
+func (observer *recordingObserver) ObservePrediction(api string, examples int, latency time.Duration, err error) {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	observer.examples = append(observer.examples, examples)
+	observer.failed = append(observer.failed, err != nil || api != server.GRPCAPI)
+}
+
+func TestPredict(t *testing.T) {
+	service := NewService(server.NewServer(testModel(), 0.5), 0.5)
+	observer := &recordingObserver{}
+	service.SetObserver(observer)
+	client, stop := serve(t, service)
+	defer stop()
+	tables := []struct {
+		instances      []*Instance
+		expectedCode   codes.Code
+		expectedLabels []int32
+	}{
+		{[]*Instance{instance(255), instance(0), instance(200)}, codes.OK, []int32{1, 0, 1}},
+		{[]*Instance{{Features: []float64{1, 2}}}, codes.InvalidArgument, nil},
+		{nil, codes.InvalidArgument, nil},
+	}
+	for _, table := range tables {
+		response, err := client.Predict(context.Background(), &PredictRequest{Instances: table.instances})
+		if status.Code(err) != table.expectedCode {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedCode, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if response.Model != "test" || len(response.Predictions) != len(table.expectedLabels) {
+			t.Fatalf("Expected: %v predictions, Actual: %v\n", len(table.expectedLabels), response)
+		}
+		for i, prediction := range response.Predictions {
+			if prediction.Label != table.expectedLabels[i] {
+				t.Errorf("Expected: %v, Actual: %v\n", table.expectedLabels[i], prediction)
+			}
+		}
+	}
+	expectedExamples, expectedFailed := []int{3, 0, 0}, []bool{false, true, true}
+	if !reflect.DeepEqual(expectedExamples, observer.examples) || !reflect.DeepEqual(expectedFailed, observer.failed) {
+		t.Errorf("Expected: %v %v, Actual: %v %v\n", expectedExamples, expectedFailed, observer.examples, observer.failed)
+	}
+}
+
+func TestPredictDeadline(t *testing.T) {
+	service := NewService(server.NewServer(testModel(), 0.5), 0.5)
+	client, stop := serve(t, service)
+	defer stop()
+	instances := make([]*Instance, 100)
+	for i := range instances {
+		instances[i] = instance(float64(i))
+	}
+	tables := []struct {
+		timeout      time.Duration
+		expectedCode codes.Code
+	}{
+		{time.Minute, codes.OK},
+		{-time.Second, codes.DeadlineExceeded},
+	}
+	for _, table := range tables {
+		ctx, cancel := context.WithTimeout(context.Background(), table.timeout)
+		_, err := client.Predict(ctx, &PredictRequest{Instances: instances})
+		cancel()
+		if status.Code(err) != table.expectedCode {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedCode, err)
+		}
+	}
+
+	// the forward pass itself stops once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	service.chunkSize = 10
+	if _, err := service.Predict(ctx, &PredictRequest{Instances: instances}); status.Code(err) != codes.Canceled {
+		t.Errorf("Expected: %v, Actual: %v\n", codes.Canceled, err)
+	}
+}
+
+func TestPredictStream(t *testing.T) {
+	client, stop := serve(t, NewService(server.NewServer(testModel(), 0.5), 0.5))
+	defer stop()
+	stream, err := client.PredictStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := [][]*Instance{
+		{instance(255), instance(0)},
+		{instance(0)},
+		{instance(150), instance(50), instance(255)},
+	}
+	expectedLabels := [][]int32{{1, 0}, {0}, {1, 0, 1}}
+	for i, instances := range requests {
+		if err := stream.SendMsg(&PredictRequest{Instances: instances}); err != nil {
+			t.Fatal(err)
+		}
+		response := new(PredictResponse)
+		if err := stream.RecvMsg(response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Predictions) != len(expectedLabels[i]) {
+			t.Fatalf("Expected: %v predictions, Actual: %v\n", len(expectedLabels[i]), response)
+		}
+		for j, prediction := range response.Predictions {
+			if prediction.Label != expectedLabels[i][j] {
+				t.Errorf("Expected: %v, Actual: %v\n", expectedLabels[i][j], prediction)
+			}
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.RecvMsg(new(PredictResponse)); err != io.EOF {
+		t.Errorf("Expected: %v, Actual: %v\n", io.EOF, err)
+	}
+}
+
+func TestGetMetadata(t *testing.T) {
+	models := server.NewServer(nil, 0.7)
+	client, stop := serve(t, NewService(models, 0.7))
+	defer stop()
+	if _, err := client.GetMetadata(context.Background(), &MetadataRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected: %v, Actual: %v\n", codes.Unavailable, err)
+	}
+	served := testModel()
+	served.Version = 3
+	models.SetModel(served)
+	metadata, err := client.GetMetadata(context.Background(), &MetadataRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Name != "test" || metadata.NumberFeatures != 12 || metadata.NumberHiddenUnits != 1 ||
+		metadata.Threshold != 0.7 || metadata.LoadedAt == 0 || strings.Join(metadata.Preprocessing, ",") != "rescale" ||
+		metadata.Version != 3 {
+		t.Errorf("Unexpected metadata: %+v\n", metadata)
+	}
+}
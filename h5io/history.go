@@ -0,0 +1,328 @@
+package h5io
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/model"
+	"gonum.org/v1/hdf5"
+)
+
+// Names of the group and packet tables of the training log
+const (
+	// group holding the training log
+	historyGroup string = "history"
+	// packet table with one recordPacket per record
+	recordsTable string = "records"
+	// group holding one packet table of metricPackets per metric
+	metricsGroup string = "metrics"
+	// number of packets per chunk of the tables
+	packetsPerChunk int = 64
+	// the tables aren't compressed
+	noCompression int = -1
+)
+
+// Packet of the records table. The names of the fields are the names of the
+// members of the compound type of the packets
+type recordPacket struct {
+	Iteration    int64
+	Epoch        int64
+	Batch        int64
+	Cost         float64
+	LearningRate float64
+	WallTime     float64
+}
+
+// Packet of the table of a metric. Since the metrics can change from one
+// training to another, each one has its own table, joined to the records by
+// the iteration
+type metricPacket struct {
+	Iteration int64
+	Value     float64
+}
+
+// TableLogger is a Callback that appends the cost, metrics, learning rate and
+// wall time of the training to packet tables of an HDF5 file as the training
+// proceeds, either once per epoch or once per mini-batch. The file, which may
+// be the one the model is saved to, is flushed at the end of every epoch so
+// that the log is kept if the training is interrupted.
+//
+// Callbacks can't return errors, therefore the first error is kept, nothing
+// else is logged after it, and it's returned by Err and Close
+type TableLogger struct {
+	model.BaseCallback
+	file     *hdf5.File
+	group    *hdf5.Group
+	metrics  *hdf5.Group
+	records  *hdf5.Table
+	tables   map[string]*hdf5.Table
+	perBatch bool
+	start    time.Time
+	// Last iteration already in the log when the training is resumed
+	lastIteration int64
+	err           error
+}
+
+// Opens the group of the location, creating it when it doesn't exist
+func openOrCreateGroup(location *hdf5.CommonFG, name string) (*hdf5.Group, error) {
+	if group, err := location.OpenGroup(name); err == nil {
+		return group, nil
+	}
+	group, err := location.CreateGroup(name)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create group %v: %v", name, err)
+	}
+	return group, nil
+}
+
+// Opens the packet table of the group, creating it with the type of packet
+// when it doesn't exist
+func openOrCreateTable(group *hdf5.Group, name string, packet interface{}) (*hdf5.Table, error) {
+	if table, err := group.OpenTable(name); err == nil {
+		return table, nil
+	}
+	table, err := group.CreateTableFrom(name, packet, packetsPerChunk, noCompression)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create packet table %v: %v", name, err)
+	}
+	return table, nil
+}
+
+// NewTableLogger creates a TableLogger that writes to the history group of
+// the HDF5 file, which is created when it doesn't exist. It records every
+// mini-batch when perBatch is true, or every epoch otherwise. When the file
+// already has a log, the records are appended to it, as done when the
+// training is resumed from a checkpoint
+func NewTableLogger(filename string, perBatch bool) (*TableLogger, error) {
+	file, err := openForWriting(filename)
+	if err != nil {
+		return nil, err
+	}
+	logger := &TableLogger{file: file, tables: make(map[string]*hdf5.Table), perBatch: perBatch}
+	if logger.group, err = openOrCreateGroup(&file.CommonFG, historyGroup); err != nil {
+		logger.Close()
+		return nil, err
+	}
+	if logger.metrics, err = openOrCreateGroup(&logger.group.CommonFG, metricsGroup); err != nil {
+		logger.Close()
+		return nil, err
+	}
+	if logger.records, err = openOrCreateTable(logger.group, recordsTable, recordPacket{}); err != nil {
+		logger.Close()
+		return nil, err
+	}
+	return logger, nil
+}
+
+// OnTrainBegin starts measuring the wall time. When the training is resumed
+// the wall time continues from the last record of the log, while a new
+// training can't be logged to a file that already has a log. The log may go
+// past the checkpoint the training is resumed from, when it was interrupted
+// some epochs after saving it, so the iterations that are already in the log
+// aren't appended again. Packet tables can't be truncated, and the repeated
+// iterations have the same values as the logged ones
+func (logger *TableLogger) OnTrainBegin(logs *model.Logs) {
+	logger.start = time.Now()
+	logger.lastIteration = 0
+	if logger.err != nil {
+		return
+	}
+	numberPackets, err := logger.records.NumPackets()
+	if err != nil {
+		logger.err = fmt.Errorf("Can't get the number of records: %v", err)
+		return
+	}
+	if numberPackets == 0 {
+		return
+	}
+	if logs.Iteration == 0 {
+		logger.err = fmt.Errorf("Can't log a new training to %v, it already has a log", logger.file.FileName())
+		return
+	}
+	last := make([]recordPacket, 1)
+	if err := logger.records.ReadPackets(numberPackets-1, 1, &last); err != nil {
+		logger.err = fmt.Errorf("Can't read the last record: %v", err)
+		return
+	}
+	logger.start = logger.start.Add(-time.Duration(last[0].WallTime * float64(time.Second)))
+	logger.lastIteration = last[0].Iteration
+}
+
+// OnBatchEnd appends the batch if logging per mini-batch
+func (logger *TableLogger) OnBatchEnd(batch int, logs *model.Logs) {
+	if logger.perBatch {
+		logger.append(logs)
+	}
+}
+
+// OnEpochEnd appends the epoch if logging per epoch, and flushes the file
+func (logger *TableLogger) OnEpochEnd(epoch int, logs *model.Logs) {
+	if !logger.perBatch {
+		logger.append(logs)
+	}
+	if logger.err == nil {
+		if err := logger.file.Flush(hdf5.F_SCOPE_LOCAL); err != nil {
+			logger.err = fmt.Errorf("Can't flush the log: %v", err)
+		}
+	}
+}
+
+// Appends the current values of the logs to the tables
+func (logger *TableLogger) append(logs *model.Logs) {
+	if logger.err != nil {
+		return
+	}
+	iteration := int64(logs.Iteration)
+	if iteration <= logger.lastIteration {
+		return
+	}
+	record := recordPacket{
+		Iteration:    iteration,
+		Epoch:        int64(logs.Epoch),
+		Batch:        int64(logs.Batch),
+		Cost:         logs.Cost,
+		LearningRate: logs.LearningRate,
+		WallTime:     time.Since(logger.start).Seconds(),
+	}
+	if err := logger.records.Append(record); err != nil {
+		logger.err = fmt.Errorf("Can't append the record of iteration %v: %v", iteration, err)
+		return
+	}
+	for name, value := range logs.Metrics {
+		table, ok := logger.tables[name]
+		if !ok {
+			var err error
+			if table, err = openOrCreateTable(logger.metrics, name, metricPacket{}); err != nil {
+				logger.err = err
+				return
+			}
+			logger.tables[name] = table
+		}
+		if err := table.Append(metricPacket{Iteration: iteration, Value: value}); err != nil {
+			logger.err = fmt.Errorf("Can't append metric %v of iteration %v: %v", name, iteration, err)
+			return
+		}
+	}
+}
+
+// Err returns the first error that happened while logging
+func (logger *TableLogger) Err() error {
+	return logger.err
+}
+
+// Close closes the tables and the file, returning the first error that
+// happened while logging
+func (logger *TableLogger) Close() error {
+	for _, table := range logger.tables {
+		table.Close()
+	}
+	if logger.records != nil {
+		logger.records.Close()
+	}
+	if logger.metrics != nil {
+		logger.metrics.Close()
+	}
+	if logger.group != nil {
+		logger.group.Close()
+	}
+	if err := logger.file.Close(); logger.err == nil {
+		logger.err = err
+	}
+	return logger.err
+}
+
+// Reads all the packets of the table of the metric
+func readMetric(metrics *hdf5.Group, name string) ([]metricPacket, error) {
+	table, err := metrics.OpenTable(name)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open packet table %v: %v", name, err)
+	}
+	defer table.Close()
+	numberPackets, err := table.NumPackets()
+	if err != nil {
+		return nil, fmt.Errorf("Can't get the number of values of metric %v: %v", name, err)
+	}
+	values := make([]metricPacket, numberPackets)
+	if numberPackets > 0 {
+		if err := table.ReadPackets(0, numberPackets, &values); err != nil {
+			return nil, fmt.Errorf("Can't read packet table %v: %v", name, err)
+		}
+	}
+	return values, nil
+}
+
+// ReadHistory reads the training log written by a TableLogger to the HDF5
+// file, e.g. for plotting it
+func ReadHistory(filename string) ([]model.HistoryRecord, error) {
+	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open file %v: %v", filename, err)
+	}
+	defer file.Close()
+	group, err := file.OpenGroup(historyGroup)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open the training log of %v: %v", filename, err)
+	}
+	defer group.Close()
+
+	table, err := group.OpenTable(recordsTable)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open packet table %v: %v", recordsTable, err)
+	}
+	defer table.Close()
+	numberPackets, err := table.NumPackets()
+	if err != nil {
+		return nil, fmt.Errorf("Can't get the number of records: %v", err)
+	}
+	packets := make([]recordPacket, numberPackets)
+	if numberPackets > 0 {
+		if err := table.ReadPackets(0, numberPackets, &packets); err != nil {
+			return nil, fmt.Errorf("Can't read packet table %v: %v", recordsTable, err)
+		}
+	}
+	records := make([]model.HistoryRecord, len(packets))
+	// index of the record of each iteration
+	indexes := make(map[int64]int, len(packets))
+	for i, packet := range packets {
+		records[i] = model.HistoryRecord{
+			Iteration:    int(packet.Iteration),
+			Epoch:        int(packet.Epoch),
+			Batch:        int(packet.Batch),
+			Cost:         packet.Cost,
+			LearningRate: packet.LearningRate,
+			WallTime:     packet.WallTime,
+		}
+		indexes[packet.Iteration] = i
+	}
+
+	metrics, err := group.OpenGroup(metricsGroup)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open group %v: %v", metricsGroup, err)
+	}
+	defer metrics.Close()
+	numberMetrics, err := metrics.NumObjects()
+	if err != nil {
+		return nil, fmt.Errorf("Can't get the number of metrics: %v", err)
+	}
+	for i := uint(0); i < numberMetrics; i++ {
+		name, err := metrics.ObjectNameByIndex(i)
+		if err != nil {
+			return nil, fmt.Errorf("Can't get the name of metric %v: %v", i, err)
+		}
+		values, err := readMetric(metrics, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			index, ok := indexes[value.Iteration]
+			if !ok {
+				continue
+			}
+			if records[index].Metrics == nil {
+				records[index].Metrics = make(map[string]float64)
+			}
+			records[index].Metrics[name] = value.Value
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,135 @@
+package h5io
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+// Returns the records without their wall time, which differs between
+// callbacks
+func withoutWallTime(records []model.HistoryRecord) []model.HistoryRecord {
+	result := append([]model.HistoryRecord(nil), records...)
+	for i := range result {
+		result[i].WallTime = 0
+	}
+	return result
+}
+
+func TestTableLogger(t *testing.T) {
+	X, _ := matrix.NewMatrixFromSlice([][]float64{
+		{-2, -1, 1, 2},
+		{1, -1, 1, -1},
+	})
+	Y, _ := matrix.NewMatrixFromSlice([][]float64{{0, 0, 1, 1}})
+	tables := []struct {
+		perBatch        bool
+		expectedRecords int
+	}{
+		{false, 5},
+		{true, 10},
+	}
+	for _, table := range tables {
+		filename := filepath.Join(t.TempDir(), "model.h5")
+		logger, err := NewTableLogger(filename, table.perBatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		history := model.NewHistory(table.perBatch)
+		hyperparameters := model.NewHyperparameters(5, 0.5, 1, 3)
+		hyperparameters.SetBatchSize(2)
+		model.Model(X, Y, hyperparameters, 4, 2, history, logger)
+		if err := logger.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := ReadHistory(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != table.expectedRecords {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedRecords, len(records))
+		}
+		if !reflect.DeepEqual(withoutWallTime(history.Records), withoutWallTime(records)) {
+			t.Errorf("Expected: %v, Actual: %v\n", history.Records, records)
+		}
+
+		// a new training can't be appended to the log
+		logger, err = NewTableLogger(filename, table.perBatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		model.Model(X, Y, hyperparameters, 4, 2, logger)
+		if err := logger.Close(); err == nil {
+			t.Errorf("Expected an error logging a new training to an existing log\n")
+		}
+	}
+}
+
+// Callback that stops the training at the end of the given epoch
+type stopAt struct {
+	model.BaseCallback
+	epoch int
+}
+
+func (callback *stopAt) OnEpochEnd(epoch int, logs *model.Logs) {
+	logs.StopTraining = epoch == callback.epoch
+}
+
+func TestTableLoggerResume(t *testing.T) {
+	X, _ := matrix.NewMatrixFromSlice([][]float64{
+		{-2, -1, 1, 2},
+		{1, -1, 1, -1},
+	})
+	Y, _ := matrix.NewMatrixFromSlice([][]float64{{0, 0, 1, 1}})
+	newHyperparameters := func(checkpoint string) *model.Hyperparameters {
+		hyperparameters := model.NewHyperparameters(6, 0.5, 1, 3)
+		hyperparameters.SetBatchSize(2)
+		hyperparameters.SetCheckpointing(checkpoint, 2)
+		return hyperparameters
+	}
+	history := model.NewHistory(false)
+	model.Model(X, Y, newHyperparameters(""), 4, 2, history)
+
+	// interrupted an epoch after the checkpoint of the 2nd epoch, with that
+	// epoch already logged
+	directory := t.TempDir()
+	filename := filepath.Join(directory, "model.h5")
+	checkpointFile := filepath.Join(directory, "checkpoint.json")
+	logger, err := NewTableLogger(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.Model(X, Y, newHyperparameters(checkpointFile), 4, 2, logger, &stopAt{epoch: 2})
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := model.LoadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Epoch != 2 {
+		t.Fatalf("Expected: checkpoint of epoch %v, Actual: %v\n", 2, checkpoint.Epoch)
+	}
+
+	logger, err = NewTableLogger(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.Resume(checkpoint, X, Y, newHyperparameters(""), logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(withoutWallTime(history.Records), withoutWallTime(records)) {
+		t.Errorf("Expected: %v, Actual: %v\n", history.Records, records)
+	}
+}
@@ -0,0 +1,255 @@
+package h5io
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Number of entries of the first axis of a dataset that are read at once to
+// compute its statistics
+const inspectionRows int = 1024
+
+// Names of the classes of the datatypes that can't be read
+var typeClassNames = map[hdf5.TypeClass]string{
+	hdf5.T_INTEGER:   "integer",
+	hdf5.T_FLOAT:     "float",
+	hdf5.T_TIME:      "time",
+	hdf5.T_STRING:    "string",
+	hdf5.T_BITFIELD:  "bitfield",
+	hdf5.T_OPAQUE:    "opaque",
+	hdf5.T_COMPOUND:  "compound",
+	hdf5.T_REFERENCE: "reference",
+	hdf5.T_ENUM:      "enum",
+	hdf5.T_VLEN:      "variable length",
+	hdf5.T_ARRAY:     "array",
+}
+
+// Attributes looked for in every dataset with the type of their value. The
+// vendored hdf5 package can't iterate over the attributes of a dataset, so
+// only the ones written by this package are found
+var knownAttributes = []struct {
+	name     string
+	isString bool
+}{
+	{thresholdAttribute, false},
+	{accuracyAttribute, false},
+	{modelIDAttribute, true},
+	{timestampAttribute, true},
+}
+
+// Statistics of the values of a dataset
+type Statistics struct {
+	Min  float64
+	Max  float64
+	Mean float64
+	Std  float64
+}
+
+// DatasetInfo describes a dataset of an HDF5 file
+type DatasetInfo struct {
+	// Path of the dataset inside the file, e.g. /predictions/labels
+	Path string
+	// Dimensions of the dataset, empty for a scalar
+	Dims []uint
+	// Name of the type of the values, e.g. uint8, or of its class when
+	// it can't be read, e.g. compound for packet tables
+	Datatype string
+	// Values of the attributes found, either float64 or string
+	Attributes map[string]interface{}
+	// Statistics of the values, nil when they can't be read
+	Statistics *Statistics
+	// Number of examples of each label, for one dimensional integer
+	// datasets, i.e. datasets of labels
+	Labels map[int64]int
+}
+
+// Inspect walks all the groups of the HDF5 file and describes each of their
+// datasets, sorted by path. The values of the datasets are read in blocks, so
+// the file may be larger than the memory
+func Inspect(filename string) ([]DatasetInfo, error) {
+	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open file %v: %v", filename, err)
+	}
+	defer file.Close()
+	var infos []DatasetInfo
+	if err := inspectGroup(&file.CommonFG, "/", &infos); err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos, nil
+}
+
+// Appends the description of every dataset of the group and its subgroups to
+// infos
+func inspectGroup(group *hdf5.CommonFG, groupPath string, infos *[]DatasetInfo) error {
+	numberObjects, err := group.NumObjects()
+	if err != nil {
+		return fmt.Errorf("Can't get the number of objects of group %v: %v", groupPath, err)
+	}
+	for i := uint(0); i < numberObjects; i++ {
+		name, err := group.ObjectNameByIndex(i)
+		if err != nil {
+			return fmt.Errorf("Can't get the name of object %v of group %v: %v", i, groupPath, err)
+		}
+		objectPath := path.Join(groupPath, name)
+		if dataset, err := group.OpenDataset(name); err == nil {
+			info, err := inspectDataset(dataset, objectPath)
+			dataset.Close()
+			if err != nil {
+				return err
+			}
+			*infos = append(*infos, info)
+			continue
+		}
+		subgroup, err := group.OpenGroup(name)
+		if err != nil {
+			// neither a dataset nor a group, e.g. a named datatype
+			continue
+		}
+		err = inspectGroup(&subgroup.CommonFG, objectPath, infos)
+		subgroup.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Describes the dataset
+func inspectDataset(dataset *hdf5.Dataset, datasetPath string) (DatasetInfo, error) {
+	info := DatasetInfo{Path: datasetPath, Attributes: make(map[string]interface{})}
+	var err error
+	if info.Dims, err = datasetDims(dataset); err != nil {
+		return info, err
+	}
+	index, err := datatypeIndex(dataset)
+	if err != nil {
+		return info, err
+	}
+	if index < 0 {
+		datatype, err := dataset.Datatype()
+		if err != nil {
+			return info, fmt.Errorf("Can't get the datatype of dataset %v: %v", datasetPath, err)
+		}
+		info.Datatype = typeClassNames[datatype.Class()]
+		datatype.Close()
+	} else {
+		info.Datatype = datatypes[index].name
+	}
+
+	for _, attribute := range knownAttributes {
+		var value interface{}
+		if attribute.isString {
+			value, err = readStringAttribute(dataset, attribute.name)
+		} else {
+			value, err = readFloatAttribute(dataset, attribute.name)
+		}
+		if err == nil {
+			info.Attributes[attribute.name] = value
+		}
+	}
+
+	if index < 0 || len(info.Dims) == 0 || product(info.Dims) == 0 {
+		return info, nil
+	}
+	isLabels := strings.Contains(datatypes[index].name, "int") &&
+		(len(info.Dims) == 1 || (len(info.Dims) == 2 && info.Dims[0] == 1))
+	if isLabels {
+		info.Labels = make(map[int64]int)
+	}
+	if info.Statistics, err = datasetStatistics(dataset, info.Dims, info.Labels); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// Computes the statistics of the dataset reading it in blocks of rows. When
+// labels isn't nil the number of occurrences of each value is counted in it
+func datasetStatistics(dataset *hdf5.Dataset, dims []uint, labels map[int64]int) (*Statistics, error) {
+	statistics := &Statistics{Min: math.Inf(1), Max: math.Inf(-1)}
+	sum, sumSquares := 0.0, 0.0
+	rows := int(dims[0])
+	for from := 0; from < rows; from += inspectionRows {
+		count := inspectionRows
+		if from+count > rows {
+			count = rows - from
+		}
+		values, err := readHyperslab(dataset, dims, 0, from, count)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			statistics.Min = math.Min(statistics.Min, value)
+			statistics.Max = math.Max(statistics.Max, value)
+			sum += value
+			sumSquares += value * value
+			if labels != nil {
+				labels[int64(value)]++
+			}
+		}
+	}
+	n := float64(product(dims))
+	statistics.Mean = sum / n
+	statistics.Std = math.Sqrt(math.Max(sumSquares/n-statistics.Mean*statistics.Mean, 0))
+	return statistics, nil
+}
+
+// WriteInspection writes the descriptions of the datasets in a human readable
+// form
+func WriteInspection(w io.Writer, infos []DatasetInfo) error {
+	for _, info := range infos {
+		dims := make([]string, len(info.Dims))
+		for i, dim := range info.Dims {
+			dims[i] = fmt.Sprint(dim)
+		}
+		shape := "scalar"
+		if len(dims) > 0 {
+			shape = "(" + strings.Join(dims, ", ") + ")"
+		}
+		if _, err := fmt.Fprintf(w, "%v: %v %v\n", info.Path, shape, info.Datatype); err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(info.Attributes))
+		for name := range info.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, err := fmt.Fprintf(w, "  @%v = %v\n", name, info.Attributes[name]); err != nil {
+				return err
+			}
+		}
+
+		if statistics := info.Statistics; statistics != nil {
+			if _, err := fmt.Fprintf(w, "  min %v, max %v, mean %v, std %v\n",
+				statistics.Min, statistics.Max, statistics.Mean, statistics.Std); err != nil {
+				return err
+			}
+		}
+
+		labels := make([]int64, 0, len(info.Labels))
+		for label := range info.Labels {
+			labels = append(labels, label)
+		}
+		sort.Slice(labels, func(i, j int) bool { return labels[i] < labels[j] })
+		total := 0
+		for _, count := range info.Labels {
+			total += count
+		}
+		for _, label := range labels {
+			count := info.Labels[label]
+			if _, err := fmt.Fprintf(w, "  label %v: %v (%.1f%%)\n", label, count, 100*float64(count)/float64(total)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package h5io
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	filename := writeTrainingSet(t)
+	report := &Report{
+		ModelID:       "model.json",
+		Threshold:     0.5,
+		Timestamp:     time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC),
+		Probabilities: []float64{0.25, 0.75},
+		Labels:        []int64{0, 1},
+	}
+	if err := WriteReport(filename, "predictions", report); err != nil {
+		t.Fatal(err)
+	}
+	infos, err := Inspect(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []struct {
+		path     string
+		dims     []uint
+		datatype string
+		mean     float64
+		labels   map[int64]int
+	}{
+		{"/predictions/labels", []uint{2}, "int64", 0.5, map[int64]int{0: 1, 1: 1}},
+		{"/predictions/probabilities", []uint{2}, "float64", 0.5, nil},
+		{"/x", []uint{5, 2, 2, 2}, "uint8", 3.75, nil},
+		{"/y", []uint{1, 5}, "int64", 0.6, map[int64]int{0: 2, 1: 3}},
+	}
+	found := make(map[string]DatasetInfo)
+	for _, info := range infos {
+		found[info.Path] = info
+	}
+	for _, table := range tables {
+		info, ok := found[table.path]
+		if !ok {
+			t.Errorf("Expected dataset %v in %v\n", table.path, infos)
+			continue
+		}
+		if !reflect.DeepEqual(table.dims, info.Dims) || table.datatype != info.Datatype {
+			t.Errorf("Expected: %v %v, Actual: %v %v\n", table.dims, table.datatype, info.Dims, info.Datatype)
+		}
+		if info.Statistics == nil || info.Statistics.Mean != table.mean {
+			t.Errorf("Expected: mean %v, Actual: %v\n", table.mean, info.Statistics)
+		}
+		if !reflect.DeepEqual(table.labels, info.Labels) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.labels, info.Labels)
+		}
+	}
+	if actual := found["/predictions/labels"].Attributes[modelIDAttribute]; actual != "model.json" {
+		t.Errorf("Expected: %v, Actual: %v\n", "model.json", actual)
+	}
+
+	var output bytes.Buffer
+	if err := WriteInspection(&output, infos); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"/x: (5, 2, 2, 2) uint8", "@timestamp = 2018-05-01T00:00:00Z", "label 1: 3 (60.0%)"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Expected: %q in %v\n", expected, output.String())
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package h5io reads training sets from and writes results to HDF5 files
+package h5io
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"gonum.org/v1/hdf5"
+)
+
+// Types of the datasets that can be read, with the Go type used as buffer.
+// Since the data is read using the type of the dataset in the file, only
+// little endian types are supported
+var datatypes = []struct {
+	name     string
+	datatype *hdf5.Datatype
+	goType   reflect.Type
+}{
+	{"uint8", hdf5.T_STD_U8LE, reflect.TypeOf(uint8(0))},
+	{"int8", hdf5.T_STD_I8LE, reflect.TypeOf(int8(0))},
+	{"uint16", hdf5.T_STD_U16LE, reflect.TypeOf(uint16(0))},
+	{"int16", hdf5.T_STD_I16LE, reflect.TypeOf(int16(0))},
+	{"uint32", hdf5.T_STD_U32LE, reflect.TypeOf(uint32(0))},
+	{"int32", hdf5.T_STD_I32LE, reflect.TypeOf(int32(0))},
+	{"uint64", hdf5.T_STD_U64LE, reflect.TypeOf(uint64(0))},
+	{"int64", hdf5.T_STD_I64LE, reflect.TypeOf(int64(0))},
+	{"float32", hdf5.T_IEEE_F32LE, reflect.TypeOf(float32(0))},
+	{"float64", hdf5.T_IEEE_F64LE, reflect.TypeOf(float64(0))},
+}
+
+// Returns the index in datatypes of the type of the dataset, or -1 when it
+// isn't supported
+func datatypeIndex(dataset *hdf5.Dataset) (int, error) {
+	datatype, err := dataset.Datatype()
+	if err != nil {
+		return -1, fmt.Errorf("Can't get the datatype of dataset %v: %v", dataset.Name(), err)
+	}
+	defer datatype.Close()
+	for i, supported := range datatypes {
+		if datatype.Equal(supported.datatype) {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// Returns the dimensions of the dataset
+func datasetDims(dataset *hdf5.Dataset) ([]uint, error) {
+	space := dataset.Space()
+	if space == nil {
+		return nil, fmt.Errorf("Can't get the dataspace of dataset %v", dataset.Name())
+	}
+	defer space.Close()
+	if space.SimpleExtentNDims() == 0 {
+		return []uint{}, nil
+	}
+	dims, _, err := space.SimpleExtentDims()
+	if err != nil {
+		return nil, fmt.Errorf("Can't get the dimensions of dataset %v: %v", dataset.Name(), err)
+	}
+	return dims, nil
+}
+
+// Returns the product of the dimensions
+func product(dims []uint) int {
+	size := 1
+	for _, dim := range dims {
+		size *= int(dim)
+	}
+	return size
+}
+
+// Reads count entries starting at from along the given axis of the dataset
+// with dimensions dims, keeping the whole extent of every other axis. The
+// values are returned in row-major order
+func readHyperslab(dataset *hdf5.Dataset, dims []uint, axis, from, count int) ([]float64, error) {
+	index, err := datatypeIndex(dataset)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("Can't read dataset %v: unsupported datatype", dataset.Name())
+	}
+
+	filespace := dataset.Space()
+	if filespace == nil {
+		return nil, fmt.Errorf("Can't get the dataspace of dataset %v", dataset.Name())
+	}
+	defer filespace.Close()
+	offset := make([]uint, len(dims))
+	counts := append([]uint(nil), dims...)
+	offset[axis] = uint(from)
+	counts[axis] = uint(count)
+	if err := filespace.SelectHyperslab(offset, nil, counts, nil); err != nil {
+		return nil, fmt.Errorf("Can't select examples %v to %v of dataset %v: %v", from, from+count, dataset.Name(), err)
+	}
+
+	size := product(counts)
+	memspace, err := hdf5.CreateSimpleDataspace([]uint{uint(size)}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create the memory dataspace: %v", err)
+	}
+	defer memspace.Close()
+
+	// the buffer is passed as a pointer to a slice of the type of the dataset
+	buffer := reflect.New(reflect.SliceOf(datatypes[index].goType))
+	buffer.Elem().Set(reflect.MakeSlice(buffer.Elem().Type(), size, size))
+	if err := dataset.ReadSubset(buffer.Interface(), memspace, filespace); err != nil {
+		return nil, fmt.Errorf("Can't read examples %v to %v of dataset %v: %v", from, from+count, dataset.Name(), err)
+	}
+	values := make([]float64, size)
+	for i := range values {
+		value := buffer.Elem().Index(i)
+		switch value.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			values[i] = float64(value.Uint())
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			values[i] = float64(value.Int())
+		default:
+			values[i] = value.Float()
+		}
+	}
+	return values, nil
+}
+
+// BatchReader reads mini-batches of examples from the input and output
+// datasets of an HDF5 file using hyperslab selections, so that only the
+// examples of a batch are held in memory and files larger than the memory can
+// be used for training.
+//
+// The first dimension of the input dataset indexes the examples and the rest
+// are flattened in row-major order into the features of each example, which
+// for the images of the cat datasets is the same layout used by
+// InputArray.ToNumberArray. The output dataset holds one label per example,
+// either with dimensions m or 1 x m.
+type BatchReader struct {
+	file           *hdf5.File
+	inputs         *hdf5.Dataset
+	outputs        *hdf5.Dataset
+	inputDims      []uint
+	outputDims     []uint
+	outputAxis     int
+	numberExamples int
+	numberFeatures int
+	batchSize      int
+	next           int
+}
+
+// OpenBatchReader opens the input and output datasets of the HDF5 file for
+// reading batches of batchSize examples
+func OpenBatchReader(filename, inputDataset, outputDataset string, batchSize int) (*BatchReader, error) {
+	if batchSize < 1 {
+		return nil, fmt.Errorf("Can't read batches of %v examples", batchSize)
+	}
+	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open file %v: %v", filename, err)
+	}
+	reader := &BatchReader{file: file, batchSize: batchSize}
+	if err := reader.open(inputDataset, outputDataset); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// Opens the datasets and checks that their dimensions match
+func (reader *BatchReader) open(inputDataset, outputDataset string) error {
+	var err error
+	if reader.inputs, err = reader.file.OpenDataset(inputDataset); err != nil {
+		return fmt.Errorf("Can't open dataset %v: %v", inputDataset, err)
+	}
+	if reader.outputs, err = reader.file.OpenDataset(outputDataset); err != nil {
+		return fmt.Errorf("Can't open dataset %v: %v", outputDataset, err)
+	}
+	if reader.inputDims, err = datasetDims(reader.inputs); err != nil {
+		return err
+	}
+	if reader.outputDims, err = datasetDims(reader.outputs); err != nil {
+		return err
+	}
+	if len(reader.inputDims) == 0 {
+		return fmt.Errorf("Can't read examples from scalar dataset %v", inputDataset)
+	}
+	reader.numberExamples = int(reader.inputDims[0])
+	reader.numberFeatures = product(reader.inputDims[1:])
+
+	switch {
+	case len(reader.outputDims) == 1 && int(reader.outputDims[0]) == reader.numberExamples:
+		reader.outputAxis = 0
+	case len(reader.outputDims) == 2 && reader.outputDims[0] == 1 && int(reader.outputDims[1]) == reader.numberExamples:
+		reader.outputAxis = 1
+	default:
+		return fmt.Errorf("Can't use dataset %v of dimensions %v as labels of %v examples", outputDataset, reader.outputDims, reader.numberExamples)
+	}
+	return nil
+}
+
+// NumberExamples returns the number of examples in the datasets
+func (reader *BatchReader) NumberExamples() int {
+	return reader.numberExamples
+}
+
+// NumberFeatures returns the number of features of each example
+func (reader *BatchReader) NumberFeatures() int {
+	return reader.numberFeatures
+}
+
+// Batch reads the examples from index from up to but not including to. X
+// holds one example per column and Y the corresponding labels
+func (reader *BatchReader) Batch(from, to int) (X, Y matrix.NumberArray, err error) {
+	if from < 0 || to > reader.numberExamples || from >= to {
+		return nil, nil, fmt.Errorf("Can't read examples %v to %v out of %v", from, to, reader.numberExamples)
+	}
+	count := to - from
+	inputs, err := readHyperslab(reader.inputs, reader.inputDims, 0, from, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	outputs, err := readHyperslab(reader.outputs, reader.outputDims, reader.outputAxis, from, count)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	XBatch, err := matrix.NewMatrix(reader.numberFeatures, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	YBatch, err := matrix.NewMatrix(1, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	for j := 0; j < count; j++ {
+		for i := 0; i < reader.numberFeatures; i++ {
+			XBatch.SetValue(i, j, inputs[j*reader.numberFeatures+i])
+		}
+		YBatch.SetValue(0, j, outputs[j])
+	}
+	return XBatch, YBatch, nil
+}
+
+// Next reads the next batch of examples. The last batch holds the remaining
+// examples, and io.EOF is returned once all of them have been read
+func (reader *BatchReader) Next() (X, Y matrix.NumberArray, err error) {
+	if reader.next >= reader.numberExamples {
+		return nil, nil, io.EOF
+	}
+	to := reader.next + reader.batchSize
+	if to > reader.numberExamples {
+		to = reader.numberExamples
+	}
+	X, Y, err = reader.Batch(reader.next, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader.next = to
+	return X, Y, nil
+}
+
+// Reset starts reading the batches from the first example again
+func (reader *BatchReader) Reset() {
+	reader.next = 0
+}
+
+// Close closes the datasets and the file
+func (reader *BatchReader) Close() error {
+	if reader.inputs != nil {
+		reader.inputs.Close()
+	}
+	if reader.outputs != nil {
+		reader.outputs.Close()
+	}
+	return reader.file.Close()
+}
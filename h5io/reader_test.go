@@ -0,0 +1,98 @@
+package h5io
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"gonum.org/v1/hdf5"
+)
+
+// Writes the values to a new dataset of the file with the given dimensions
+func writeDataset(t *testing.T, file *hdf5.File, name string, datatype *hdf5.Datatype, dims []uint, values interface{}) {
+	space, err := hdf5.CreateSimpleDataspace(dims, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer space.Close()
+	dataset, err := file.CreateDataset(name, datatype, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataset.Close()
+	if err := dataset.Write(values); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Creates a file with 5 examples of 2 x 2 pixels with 2 channels, whose values
+// are the index of the example, and their labels
+func writeTrainingSet(t *testing.T) string {
+	filename := filepath.Join(t.TempDir(), "train.h5")
+	file, err := hdf5.CreateFile(filename, hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var inputs [5][2][2][2]uint8
+	for i := range inputs {
+		for j := range inputs[i] {
+			for k := range inputs[i][j] {
+				inputs[i][j][k] = [2]uint8{uint8(i), uint8(10*j + k)}
+			}
+		}
+	}
+	outputs := [1][5]int64{{0, 1, 1, 0, 1}}
+	writeDataset(t, file, "x", hdf5.T_STD_U8LE, []uint{5, 2, 2, 2}, &inputs)
+	writeDataset(t, file, "y", hdf5.T_STD_I64LE, []uint{1, 5}, &outputs)
+	return filename
+}
+
+func TestBatchReader(t *testing.T) {
+	reader, err := OpenBatchReader(writeTrainingSet(t), "x", "y", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reader.NumberExamples() != 5 || reader.NumberFeatures() != 8 {
+		t.Errorf("Expected: 5 examples of 8 features, Actual: %v of %v\n", reader.NumberExamples(), reader.NumberFeatures())
+	}
+
+	tables := []struct {
+		expectedExamples []float64
+		expectedLabels   []float64
+	}{
+		{[]float64{0, 1}, []float64{0, 1}},
+		{[]float64{2, 3}, []float64{1, 0}},
+		{[]float64{4}, []float64{1}},
+	}
+	for _, table := range tables {
+		X, Y, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := matrix.ToSlice(X)
+		// one example per column with the channels of each pixel together
+		if !reflect.DeepEqual(values[0], table.expectedExamples) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedExamples, values[0])
+		}
+		if actual := []float64{values[1][0], values[3][0], values[5][0]}; !reflect.DeepEqual(actual, []float64{0, 1, 10}) {
+			t.Errorf("Expected: %v, Actual: %v\n", []float64{0, 1, 10}, actual)
+		}
+		if actual := matrix.ToSlice(Y)[0]; !reflect.DeepEqual(actual, table.expectedLabels) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedLabels, actual)
+		}
+	}
+	if _, _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected: %v, Actual: %v\n", io.EOF, err)
+	}
+	reader.Reset()
+	if _, Y, err := reader.Next(); err != nil || Y.GetColumns() != 2 {
+		t.Errorf("Expected: a batch of %v examples, Actual: %v, %v\n", 2, Y, err)
+	}
+	if _, _, err := reader.Batch(4, 6); err == nil {
+		t.Errorf("Expected an error reading beyond the last example\n")
+	}
+}
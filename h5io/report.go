@@ -0,0 +1,346 @@
+package h5io
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+	"gonum.org/v1/hdf5"
+)
+
+// Names of the datasets and attributes written to the group of a report
+const (
+	// dataset with the probability of each example belonging to the class 1
+	probabilitiesDataset string = "probabilities"
+	// dataset with the predicted class of each example
+	labelsDataset string = "labels"
+	// dataset with the classes the metrics are about
+	classesDataset string = "classes"
+	// datasets with the metrics of each class
+	precisionDataset string = "precision"
+	recallDataset    string = "recall"
+	f1ScoreDataset   string = "f1_score"
+	supportDataset   string = "support"
+	// attributes of the probabilities and labels datasets
+	thresholdAttribute string = "threshold"
+	modelIDAttribute   string = "model_id"
+	timestampAttribute string = "timestamp"
+	accuracyAttribute  string = "accuracy"
+)
+
+// Report holds the predictions of a model over a dataset along with the
+// evaluation of the predictions against the desired outputs
+type Report struct {
+	// Identifies the model that made the predictions, e.g. its filename
+	ModelID string
+	// Examples whose probability is above the threshold are labelled as 1
+	Threshold float64
+	// Time at which the predictions were made
+	Timestamp time.Time
+	// Probability of each example belonging to the class 1
+	Probabilities []float64
+	// Predicted class of each example
+	Labels []int64
+	// Desired output of each example
+	Desired []float64
+	// Fraction of examples correctly labelled
+	Accuracy float64
+	// Metrics of each class
+	Classes []model.ClassMetrics
+}
+
+// Evaluate runs the model over all the examples of the reader, one batch at a
+// time, and returns its predictions and evaluation. The pipeline is applied to
+// every batch before predicting, and may be nil
+func Evaluate(parameters *model.Parameters, pipeline preprocessing.Pipeline, reader *BatchReader, modelID string, threshold float64) (*Report, error) {
+	report := &Report{ModelID: modelID, Threshold: threshold, Timestamp: time.Now()}
+	report.Desired = make([]float64, 0, reader.NumberExamples())
+	correct := 0
+	reader.Reset()
+	for {
+		X, Y, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pipeline != nil {
+			if X, err = pipeline.Transform(X); err != nil {
+				return nil, err
+			}
+		}
+		A2 := model.PredictProbabilities(parameters, X)
+		for j := 0; j < A2.GetColumns(); j++ {
+			probability, _ := A2.GetValue(0, j)
+			y, _ := Y.GetValue(0, j)
+			label := int64(0)
+			if probability > threshold {
+				label = 1
+			}
+			report.Probabilities = append(report.Probabilities, probability)
+			report.Labels = append(report.Labels, label)
+			report.Desired = append(report.Desired, y)
+			if (label == 1) == (y > 0.5) {
+				correct++
+			}
+		}
+	}
+	if len(report.Desired) == 0 {
+		return nil, fmt.Errorf("Can't evaluate the model on a dataset without examples")
+	}
+
+	probabilities, err := matrix.NewMatrixFromSlice([][]float64{report.Probabilities})
+	if err != nil {
+		return nil, err
+	}
+	Y, err := matrix.NewMatrixFromSlice([][]float64{report.Desired})
+	if err != nil {
+		return nil, err
+	}
+	report.Classes = model.ClassificationReport(probabilities, Y, threshold)
+	report.Accuracy = float64(correct) / float64(len(report.Desired))
+	return report, nil
+}
+
+// Opens the file for writing, creating it when it doesn't exist yet
+func openForWriting(filename string) (*hdf5.File, error) {
+	var file *hdf5.File
+	var err error
+	if hdf5.IsHDF5(filename) {
+		file, err = hdf5.OpenFile(filename, hdf5.F_ACC_RDWR)
+	} else {
+		file, err = hdf5.CreateFile(filename, hdf5.F_ACC_EXCL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Can't open file %v for writing: %v", filename, err)
+	}
+	return file, nil
+}
+
+// Writes the values as a new one dimensional dataset of the group
+func writeVector(group *hdf5.Group, name string, datatype *hdf5.Datatype, length int, values interface{}) (*hdf5.Dataset, error) {
+	space, err := hdf5.CreateSimpleDataspace([]uint{uint(length)}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create the dataspace of dataset %v: %v", name, err)
+	}
+	defer space.Close()
+	dataset, err := group.CreateDataset(name, datatype, space)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create dataset %v: %v", name, err)
+	}
+	if err := dataset.Write(values); err != nil {
+		dataset.Close()
+		return nil, fmt.Errorf("Can't write dataset %v: %v", name, err)
+	}
+	return dataset, nil
+}
+
+// Returns a fixed length string datatype of the given size
+func stringDatatype(size int) (*hdf5.Datatype, error) {
+	datatype, err := hdf5.T_C_S1.Copy()
+	if err != nil {
+		return nil, err
+	}
+	if err := datatype.SetSize(size); err != nil {
+		datatype.Close()
+		return nil, err
+	}
+	return datatype, nil
+}
+
+// Writes the value, either a float64 or a string, as a new scalar attribute
+// of the dataset. Strings are written with a fixed length, as variable length
+// strings can't be read back
+func writeAttribute(dataset *hdf5.Dataset, name string, value interface{}) error {
+	var datatype *hdf5.Datatype
+	var data interface{}
+	switch value := value.(type) {
+	case float64:
+		datatype, data = hdf5.T_NATIVE_DOUBLE, &value
+	case string:
+		// a pointer to an array of the bytes of the string
+		size := len(value)
+		if size == 0 {
+			size = 1
+		}
+		bytes := reflect.New(reflect.ArrayOf(size, reflect.TypeOf(byte(0))))
+		reflect.Copy(bytes.Elem(), reflect.ValueOf([]byte(value)))
+		var err error
+		if datatype, err = stringDatatype(size); err != nil {
+			return fmt.Errorf("Can't create the datatype of attribute %v: %v", name, err)
+		}
+		defer datatype.Close()
+		data = bytes.Interface()
+	default:
+		return fmt.Errorf("Can't write attribute %v of type %T", name, value)
+	}
+	space, err := hdf5.CreateDataspace(hdf5.S_SCALAR)
+	if err != nil {
+		return fmt.Errorf("Can't create the dataspace of attribute %v: %v", name, err)
+	}
+	defer space.Close()
+	attribute, err := dataset.CreateAttribute(name, datatype, space)
+	if err != nil {
+		return fmt.Errorf("Can't create attribute %v: %v", name, err)
+	}
+	defer attribute.Close()
+	if err := attribute.Write(data, datatype); err != nil {
+		return fmt.Errorf("Can't write attribute %v: %v", name, err)
+	}
+	return nil
+}
+
+// Reads the scalar float64 attribute of the dataset
+func readFloatAttribute(dataset *hdf5.Dataset, name string) (float64, error) {
+	attribute, err := dataset.OpenAttribute(name)
+	if err != nil {
+		return 0, fmt.Errorf("Can't open attribute %v: %v", name, err)
+	}
+	defer attribute.Close()
+	var value float64
+	if err := attribute.Read(&value, hdf5.T_NATIVE_DOUBLE); err != nil {
+		return 0, fmt.Errorf("Can't read attribute %v: %v", name, err)
+	}
+	return value, nil
+}
+
+// Reads the fixed length string attribute of the dataset, with as many bytes
+// as its stored datatype holds
+func readStringAttribute(dataset *hdf5.Dataset, name string) (string, error) {
+	attribute, err := dataset.OpenAttribute(name)
+	if err != nil {
+		return "", fmt.Errorf("Can't open attribute %v: %v", name, err)
+	}
+	defer attribute.Close()
+	stored := &hdf5.Datatype{Identifier: attribute.GetType()}
+	defer stored.Close()
+	if stored.Class() != hdf5.T_STRING {
+		return "", fmt.Errorf("Can't read attribute %v of class %v as a string", name, stored.Class())
+	}
+	size := int(stored.Size())
+	if size < 1 {
+		return "", fmt.Errorf("Can't read attribute %v of %v bytes", name, size)
+	}
+	datatype, err := stringDatatype(size)
+	if err != nil {
+		return "", fmt.Errorf("Can't create the datatype of attribute %v: %v", name, err)
+	}
+	defer datatype.Close()
+	value := make([]byte, size)
+	if err := attribute.Read(&value[0], datatype); err != nil {
+		return "", fmt.Errorf("Can't read attribute %v: %v", name, err)
+	}
+	length := 0
+	for length < len(value) && value[length] != 0 {
+		length++
+	}
+	return string(value[:length]), nil
+}
+
+// GroupExists returns whether filename is an HDF5 file with a group of the
+// given name
+func GroupExists(filename, groupName string) bool {
+	if !hdf5.IsHDF5(filename) {
+		return false
+	}
+	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	group, err := file.OpenGroup(groupName)
+	if err != nil {
+		return false
+	}
+	group.Close()
+	return true
+}
+
+// WriteReport writes the report to a new group of the HDF5 file. The file is
+// created when it doesn't exist, but the group must not exist yet, so that a
+// report is never written over another one. The group holds the probabilities
+// and labels of the examples, both with the threshold, model ID, timestamp and
+// accuracy as attributes, and the metrics of each class as datasets indexed by
+// the classes dataset
+func WriteReport(filename, groupName string, report *Report) error {
+	file, err := openForWriting(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if existing, err := file.OpenGroup(groupName); err == nil {
+		existing.Close()
+		return fmt.Errorf("Can't write the report to group %v of file %v, the group already exists", groupName, filename)
+	}
+	group, err := file.CreateGroup(groupName)
+	if err != nil {
+		return fmt.Errorf("Can't create group %v in file %v: %v", groupName, filename, err)
+	}
+	defer group.Close()
+
+	m := len(report.Probabilities)
+	probabilities, err := writeVector(group, probabilitiesDataset, hdf5.T_NATIVE_DOUBLE, m, &report.Probabilities)
+	if err != nil {
+		return err
+	}
+	defer probabilities.Close()
+	labels, err := writeVector(group, labelsDataset, hdf5.T_NATIVE_INT64, m, &report.Labels)
+	if err != nil {
+		return err
+	}
+	defer labels.Close()
+	attributes := []struct {
+		name  string
+		value interface{}
+	}{
+		{thresholdAttribute, report.Threshold},
+		{accuracyAttribute, report.Accuracy},
+		{modelIDAttribute, report.ModelID},
+		{timestampAttribute, report.Timestamp.UTC().Format(time.RFC3339)},
+	}
+	for _, dataset := range []*hdf5.Dataset{probabilities, labels} {
+		for _, attribute := range attributes {
+			if err := writeAttribute(dataset, attribute.name, attribute.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	numberClasses := len(report.Classes)
+	classes := make([]int64, numberClasses)
+	support := make([]int64, numberClasses)
+	precision := make([]float64, numberClasses)
+	recall := make([]float64, numberClasses)
+	f1Score := make([]float64, numberClasses)
+	for i, class := range report.Classes {
+		classes[i] = int64(class.Class)
+		support[i] = int64(class.Support)
+		precision[i] = class.Precision
+		recall[i] = class.Recall
+		f1Score[i] = class.F1Score
+	}
+	vectors := []struct {
+		name     string
+		datatype *hdf5.Datatype
+		values   interface{}
+	}{
+		{classesDataset, hdf5.T_NATIVE_INT64, &classes},
+		{precisionDataset, hdf5.T_NATIVE_DOUBLE, &precision},
+		{recallDataset, hdf5.T_NATIVE_DOUBLE, &recall},
+		{f1ScoreDataset, hdf5.T_NATIVE_DOUBLE, &f1Score},
+		{supportDataset, hdf5.T_NATIVE_INT64, &support},
+	}
+	for _, vector := range vectors {
+		dataset, err := writeVector(group, vector.name, vector.datatype, numberClasses, vector.values)
+		if err != nil {
+			return err
+		}
+		dataset.Close()
+	}
+	return nil
+}
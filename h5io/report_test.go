@@ -0,0 +1,89 @@
+package h5io
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"gonum.org/v1/hdf5"
+)
+
+// Parameters that predict the class 1 for the examples of the training set
+// whose first feature, i.e. the index of the example, is 2 or more
+func thresholdParameters() *model.Parameters {
+	W1, _ := matrix.NewMatrixFromSlice([][]float64{{1, 0, 0, 0, 0, 0, 0, 0}})
+	B1, _ := matrix.NewMatrixFromSlice([][]float64{{-1.5}})
+	W2, _ := matrix.NewMatrixFromSlice([][]float64{{10}})
+	B2, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	return &model.Parameters{W1: W1, B1: B1, W2: W2, B2: B2}
+}
+
+func TestEvaluateAndWriteReport(t *testing.T) {
+	reader, err := OpenBatchReader(writeTrainingSet(t), "x", "y", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	// a model ID longer than any fixed buffer, which is read back whole
+	modelID := strings.Repeat("models/", 50) + "model.json"
+	report, err := Evaluate(thresholdParameters(), nil, reader, modelID, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int64{0, 0, 1, 1, 1}; !reflect.DeepEqual(expected, report.Labels) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, report.Labels)
+	}
+	if report.Accuracy != 0.6 {
+		t.Errorf("Expected: %v, Actual: %v\n", 0.6, report.Accuracy)
+	}
+	if actual := report.Classes[1]; actual.Support != 3 || math.Abs(actual.Recall-2.0/3) > 1e-9 {
+		t.Errorf("Expected: support %v and recall %v, Actual: %v\n", 3, 2.0/3, actual)
+	}
+
+	filename := filepath.Join(t.TempDir(), "predictions.h5")
+	if err := WriteReport(filename, "test", report); err != nil {
+		t.Fatal(err)
+	}
+	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	labels, err := file.OpenDataset("test/labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer labels.Close()
+	actualLabels := make([]int64, 5)
+	if err := labels.Read(&actualLabels); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Labels, actualLabels) {
+		t.Errorf("Expected: %v, Actual: %v\n", report.Labels, actualLabels)
+	}
+	if threshold, err := readFloatAttribute(labels, "threshold"); err != nil || threshold != 0.5 {
+		t.Errorf("Expected: %v, Actual: %v, %v\n", 0.5, threshold, err)
+	}
+	if actual, err := readStringAttribute(labels, "model_id"); err != nil || actual != modelID {
+		t.Errorf("Expected: %v, Actual: %v, %v\n", modelID, actual, err)
+	}
+
+	// the group can't be written twice, but another group of the same file can
+	if !GroupExists(filename, "test") || GroupExists(filename, "other") {
+		t.Errorf("Expected only the group test in %v\n", filename)
+	}
+	expectedError := "Can't write the report to group test of file " + filename + ", the group already exists"
+	if err := WriteReport(filename, "test", report); err == nil || err.Error() != expectedError {
+		t.Errorf("Expected: %v, Actual: %v\n", expectedError, err)
+	}
+	if err := WriteReport(filename, "other", report); err != nil {
+		t.Errorf("Expected no error writing another group, Actual: %v\n", err)
+	}
+	if GroupExists(filepath.Join(t.TempDir(), "missing.h5"), "test") {
+		t.Errorf("Expected no group in a missing file\n")
+	}
+}
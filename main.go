@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/chibby0ne/micro_neural_network/matrix"
 	"gonum.org/v1/hdf5"
@@ -29,14 +30,31 @@ const (
 )
 
 // InputArray type is used to contain the input to the neuron.
-// Bounds and types are taken by opening and examining the datasets in jupyter notebooks
+// Bounds and types are those printed by the inspect command for the dataset
 type InputArray [m][pixelsPerDimension][pixelsPerDimension][colorsChannel]uint8
 
 // OutputArray type is used to contain the input to the neuron.
-// Bounds and types are taken by opening and examining the datasets in jupyter notebooks
+// Bounds and types are those printed by the inspect command for the dataset
 type OutputArray [1][m]int64
 
 func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Printf("Unknown command: %v\n", os.Args[1])
+			os.Exit(2)
+		}
+		if err := command(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	demo()
+}
+
+// Loads the training set and prints some of its values
+func demo() {
 	// Create dummy matrix
 	matrix, err := matrix.NewMatrix(3, 3)
 	if err != nil {
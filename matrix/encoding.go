@@ -0,0 +1,86 @@
+package matrix
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Size in bytes of the header of the binary encoding, i.e. the number of rows
+// and columns
+const binaryHeaderSize int = 8
+
+// Registers the matrix type so that NumberArray values can be encoded with
+// gob
+func init() {
+	gob.Register(new(matrix))
+}
+
+// MarshalJSON writes the matrix as a JSON array of its rows
+func (m *matrix) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.matrix)
+}
+
+// UnmarshalJSON reads a matrix written by MarshalJSON
+func (m *matrix) UnmarshalJSON(data []byte) error {
+	var values [][]float64
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	decoded, err := NewMatrixFromSlice(values)
+	if err != nil {
+		return err
+	}
+	*m = *decoded
+	return nil
+}
+
+// MarshalBinary writes the number of rows and columns of the matrix as
+// little endian uint32, followed by the float64 bits of its values in
+// row-major order
+func (m *matrix) MarshalBinary() ([]byte, error) {
+	data := make([]byte, binaryHeaderSize+8*m.rows*m.cols)
+	binary.LittleEndian.PutUint32(data, uint32(m.rows))
+	binary.LittleEndian.PutUint32(data[4:], uint32(m.cols))
+	offset := binaryHeaderSize
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			binary.LittleEndian.PutUint64(data[offset:], math.Float64bits(m.matrix[i][j]))
+			offset += 8
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary reads a matrix written by MarshalBinary
+func (m *matrix) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeaderSize {
+		return fmt.Errorf("Can't decode a matrix from %v bytes", len(data))
+	}
+	rows := int(binary.LittleEndian.Uint32(data))
+	cols := int(binary.LittleEndian.Uint32(data[4:]))
+	if ok, err := checkPositiveBounds(rows, cols); !ok {
+		return err
+	}
+	// the division keeps corrupted dimensions from overflowing the size of the
+	// values before it's compared with the data
+	values := len(data) - binaryHeaderSize
+	if cols > values/8/rows || values != 8*rows*cols {
+		return fmt.Errorf("Can't decode a matrix of %vx%v from %v bytes", rows, cols, len(data))
+	}
+	decoded, err := NewMatrix(rows, cols)
+	if err != nil {
+		return err
+	}
+	offset := binaryHeaderSize
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			decoded.matrix[i][j] = math.Float64frombits(binary.LittleEndian.Uint64(data[offset:]))
+			offset += 8
+		}
+	}
+	*m = *decoded
+	return nil
+}
@@ -0,0 +1,98 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestMatrixJSON(t *testing.T) {
+	a := &matrix{matrix: [][]float64{{1, -2.5, 3}, {4, 5, 1e-300}}, rows: 2, cols: 3}
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[[1,-2.5,3],[4,5,1e-300]]"; string(data) != expected {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, string(data))
+	}
+	decoded := new(matrix)
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !equalMatrices(a, decoded) {
+		t.Errorf("Expected: %v, Actual: %v\n", a, decoded)
+	}
+
+	tables := []struct {
+		data          string
+		expectedError error
+	}{
+		{"[]", fmt.Errorf("Can't create a matrix with 0 rows")},
+		{"[[1, 2], [3]]", fmt.Errorf("Can't create a matrix from row 1 with 1 cols, expected 2 cols")},
+	}
+	for _, table := range tables {
+		if err := json.Unmarshal([]byte(table.data), new(matrix)); !equalErrors(table.expectedError, err) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+	}
+}
+
+func TestMatrixBinary(t *testing.T) {
+	a := &matrix{matrix: [][]float64{{1, math.Inf(-1)}, {math.MaxFloat64, -0.125}, {0, 7}}, rows: 3, cols: 2}
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := binaryHeaderSize + 8*6; len(data) != expected {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, len(data))
+	}
+	decoded := new(matrix)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !equalMatrices(a, decoded) {
+		t.Errorf("Expected: %v, Actual: %v\n", a, decoded)
+	}
+
+	tables := []struct {
+		data          []byte
+		expectedError error
+	}{
+		{data[:4], fmt.Errorf("Can't decode a matrix from 4 bytes")},
+		{data[:len(data)-1], fmt.Errorf("Can't decode a matrix of 3x2 from 55 bytes")},
+		{make([]byte, binaryHeaderSize), fmt.Errorf("Can't create a matrix with 0 rows")},
+		// dimensions whose size overflows to the length of the data
+		{[]byte{0, 0, 0, 0x80, 0, 0, 0, 0x80}, fmt.Errorf("Can't decode a matrix of 2147483648x2147483648 from 8 bytes")},
+	}
+	for _, table := range tables {
+		if err := new(matrix).UnmarshalBinary(table.data); !equalErrors(table.expectedError, err) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+	}
+}
+
+func TestMatrixGob(t *testing.T) {
+	// NumberArray values are encoded with the binary encoding of the matrix
+	type arrays struct {
+		A NumberArray
+		B NumberArray
+	}
+	a := &matrix{matrix: [][]float64{{1, 2}, {3, 4}}, rows: 2, cols: 2}
+	b := &matrix{matrix: [][]float64{{-1}}, rows: 1, cols: 1}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(arrays{A: a, B: b}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded arrays
+	if err := gob.NewDecoder(&buffer).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	decodedA, _ := decoded.A.(*matrix)
+	decodedB, _ := decoded.B.(*matrix)
+	if !equalMatrices(a, decodedA) || !equalMatrices(b, decodedB) {
+		t.Errorf("Expected: %v %v, Actual: %v %v\n", a, b, decoded.A, decoded.B)
+	}
+}
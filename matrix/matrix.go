@@ -78,6 +78,16 @@ func NewInitializedMatrix(rows, cols int, val float64) (m *matrix, err error) {
 // is non-linear and at big enough values the function turns almost completely
 // flat.
 func NewRandomMatrix(rows, cols int, scaler float64) (m *matrix, err error) {
+	return newRandomMatrix(rows, cols, scaler, rand.Float64)
+}
+
+// Creates a random matrix as NewRandomMatrix, drawing the values from the
+// given random number generator instead of the global one
+func NewRandomMatrixFrom(random *rand.Rand, rows, cols int, scaler float64) (m *matrix, err error) {
+	return newRandomMatrix(rows, cols, scaler, random.Float64)
+}
+
+func newRandomMatrix(rows, cols int, scaler float64, float func() float64) (m *matrix, err error) {
 	if ok, err := checkPositiveBounds(rows, cols); !ok {
 		return m, err
 	}
@@ -86,7 +96,7 @@ func NewRandomMatrix(rows, cols int, scaler float64) (m *matrix, err error) {
 	for i := 0; i < rows; i++ {
 		m.matrix[i] = make([]float64, cols)
 		for j := 0; j < cols; j++ {
-			m.matrix[i][j] = rand.Float64() * scaler
+			m.matrix[i][j] = float() * scaler
 		}
 	}
 	m.rows = rows
@@ -94,6 +104,38 @@ func NewRandomMatrix(rows, cols int, scaler float64) (m *matrix, err error) {
 	return m, err
 }
 
+// Creates a matrix with the values of the given rows, which must all have the
+// same number of elements. The values are copied.
+func NewMatrixFromSlice(values [][]float64) (m *matrix, err error) {
+	if len(values) == 0 {
+		return m, fmt.Errorf("Can't create a matrix with 0 rows")
+	}
+	m, err = NewMatrix(len(values), len(values[0]))
+	if err != nil {
+		return m, err
+	}
+	for i := 0; i < m.rows; i++ {
+		if len(values[i]) != m.cols {
+			return nil, fmt.Errorf("Can't create a matrix from row %v with %v"+
+				" cols, expected %v cols", i, len(values[i]), m.cols)
+		}
+		copy(m.matrix[i], values[i])
+	}
+	return m, err
+}
+
+// Returns a copy of the values of the NumberArray as a slice of rows
+func ToSlice(a NumberArray) [][]float64 {
+	values := make([][]float64, a.GetRows())
+	for i := 0; i < a.GetRows(); i++ {
+		values[i] = make([]float64, a.GetColumns())
+		for j := 0; j < a.GetColumns(); j++ {
+			values[i][j], _ = a.GetValue(i, j)
+		}
+	}
+	return values
+}
+
 // Creates a new ColumnVector which is nothing more than a one-column matrix
 func NewColumnVector(rows int) (*matrix, error) {
 	return NewMatrix(rows, 1)
@@ -181,6 +223,61 @@ func EqualDimensions(a, b NumberArray) bool {
 	return a.GetRows() == b.GetRows() && a.GetColumns() == b.GetColumns()
 }
 
+// Creates a new matrix with the same dimensions and values as a. Useful when
+// an operation like Transpose would otherwise modify a NumberArray that is
+// still needed afterwards.
+func Copy(a NumberArray) NumberArray {
+	resultingMatrix, _ := NewMatrix(a.GetRows(), a.GetColumns())
+	for i := 0; i < a.GetRows(); i++ {
+		for j := 0; j < a.GetColumns(); j++ {
+			operandA, _ := a.GetValue(i, j)
+			resultingMatrix.SetValue(i, j, operandA)
+		}
+	}
+	return resultingMatrix
+}
+
+// Creates a new matrix with the columns of a from index from up to, but not
+// including, index to. Useful for splitting the training set, where each
+// column is a training example, into mini-batches.
+func SliceColumns(a NumberArray, from, to int) (resultingMatrix NumberArray, err error) {
+	if from < 0 || to > a.GetColumns() || from >= to {
+		return resultingMatrix, fmt.Errorf("Can't slice columns from %v to %v"+
+			" of a matrix with %v cols", from, to, a.GetColumns())
+	}
+	resultingMatrix, _ = NewMatrix(a.GetRows(), to-from)
+	for i := 0; i < a.GetRows(); i++ {
+		for j := from; j < to; j++ {
+			operandA, _ := a.GetValue(i, j)
+			resultingMatrix.SetValue(i, j-from, operandA)
+		}
+	}
+	return resultingMatrix, err
+}
+
+// Creates a new matrix with the columns of a in the order given by indexes,
+// which may repeat columns. Useful for shuffling or splitting the training
+// set, where each column is a training example.
+func SelectColumns(a NumberArray, indexes []int) (resultingMatrix NumberArray, err error) {
+	if len(indexes) == 0 {
+		return resultingMatrix, fmt.Errorf("Can't select 0 cols")
+	}
+	for _, j := range indexes {
+		if j < 0 || j >= a.GetColumns() {
+			return resultingMatrix, fmt.Errorf("Index %v is out of bounds for"+
+				" cols with size %v", j, a.GetColumns())
+		}
+	}
+	resultingMatrix, _ = NewMatrix(a.GetRows(), len(indexes))
+	for i := 0; i < a.GetRows(); i++ {
+		for k, j := range indexes {
+			operandA, _ := a.GetValue(i, j)
+			resultingMatrix.SetValue(i, k, operandA)
+		}
+	}
+	return resultingMatrix, err
+}
+
 // Type used for handling binary math functions in binaryOperation function
 type binaryMathFunc func(a, b float64) float64
 
@@ -247,6 +344,25 @@ func MultiplyElementwise(a, b NumberArray) (resultingMatrix NumberArray, err err
 	return binaryOperation("MultiplyElementwise", a, b)
 }
 
+// Adds the column vector v to every column of a, and returns the result in a
+// new NumberArray. Emulates numpy's broadcasting of a (n, 1) array in a + v.
+func AddColumnVector(a, v NumberArray) (resultingMatrix NumberArray, err error) {
+	if v.GetColumns() != 1 || v.GetRows() != a.GetRows() {
+		return resultingMatrix, fmt.Errorf("Can't broadcast a vector of "+
+			"dimensions %vx%v to a matrix of dimensions %vx%v", v.GetRows(),
+			v.GetColumns(), a.GetRows(), a.GetColumns())
+	}
+	resultingMatrix, _ = NewMatrix(a.GetRows(), a.GetColumns())
+	for i := 0; i < a.GetRows(); i++ {
+		operandB, _ := v.GetValue(i, 0)
+		for j := 0; j < a.GetColumns(); j++ {
+			operandA, _ := a.GetValue(i, j)
+			resultingMatrix.SetValue(i, j, operandA+operandB)
+		}
+	}
+	return resultingMatrix, err
+}
+
 // Returns true if the columns' size of array a matches the rows' size of array b
 func canBeMultiplied(a, b NumberArray) (ok bool, err error) {
 	if a.GetColumns() != b.GetRows() {
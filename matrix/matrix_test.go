@@ -145,6 +145,17 @@ func TestNewRandomMatrix(t *testing.T) {
 			t.Errorf("ExpectedError: %v, ActualError: %v", table.expectedError, err)
 		}
 	}
+	// The same values are drawn from a generator with the same seed
+	random := rand.New(rand.NewSource(1))
+	for _, table := range tables {
+		m, err := NewRandomMatrixFrom(random, table.rows, table.cols, 1)
+		if !equalMatrices(table.expectedMatrix, m) {
+			t.Errorf("ExpectedMatrix: %v, ActualMatrix: %v\n", table.expectedMatrix, m)
+		}
+		if !equalErrors(table.expectedError, err) {
+			t.Errorf("ExpectedError: %v, ActualError: %v", table.expectedError, err)
+		}
+	}
 }
 
 func TestNewColumnVector(t *testing.T) {
@@ -715,3 +726,145 @@ func TestSumByColumns(t *testing.T) {
 		}
 	}
 }
+
+func TestCopy(t *testing.T) {
+	tables := []struct {
+		a              *matrix
+		expectedMatrix *matrix
+	}{
+		{
+			&matrix{matrix: [][]float64{{1, 2, 3, 4}}, rows: 1, cols: 4},
+			&matrix{matrix: [][]float64{{1, 2, 3, 4}}, rows: 1, cols: 4},
+		},
+		{
+			&matrix{matrix: [][]float64{{-2.3, 3.3}, {1.2, -4.0}}, rows: 2, cols: 2},
+			&matrix{matrix: [][]float64{{-2.3, 3.3}, {1.2, -4.0}}, rows: 2, cols: 2},
+		},
+	}
+	for _, table := range tables {
+		actual := Copy(table.a)
+		v, _ := actual.(*matrix)
+		if !equalMatrices(table.expectedMatrix, v) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedMatrix, v)
+		}
+		// modifying the copy must leave the original untouched
+		v.Transpose()
+		if !equalMatrices(table.expectedMatrix, table.a) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedMatrix, table.a)
+		}
+	}
+}
+
+func TestAddColumnVector(t *testing.T) {
+	tables := []struct {
+		a              *matrix
+		v              *matrix
+		expectedMatrix *matrix
+		expectedError  error
+	}{
+		{
+			&matrix{matrix: [][]float64{{1, 2}, {3, 4}, {5, 6}}, rows: 3, cols: 2},
+			&matrix{matrix: [][]float64{{1}, {-1}, {0.5}}, rows: 3, cols: 1},
+			&matrix{matrix: [][]float64{{2, 3}, {2, 3}, {5.5, 6.5}}, rows: 3, cols: 2},
+			nil,
+		},
+		{
+			&matrix{matrix: [][]float64{{1, 2}, {3, 4}, {5, 6}}, rows: 3, cols: 2},
+			&matrix{matrix: [][]float64{{1}, {-1}}, rows: 2, cols: 1},
+			nil,
+			fmt.Errorf("Can't broadcast a vector of dimensions 2x1 to a matrix of dimensions 3x2"),
+		},
+		{
+			&matrix{matrix: [][]float64{{1, 2}, {3, 4}}, rows: 2, cols: 2},
+			&matrix{matrix: [][]float64{{1, 2}, {3, 4}}, rows: 2, cols: 2},
+			nil,
+			fmt.Errorf("Can't broadcast a vector of dimensions 2x2 to a matrix of dimensions 2x2"),
+		},
+	}
+	for _, table := range tables {
+		resultMatrix, err := AddColumnVector(table.a, table.v)
+		v, _ := resultMatrix.(*matrix)
+		if !equalMatrices(table.expectedMatrix, v) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedMatrix, v)
+		}
+		if !equalErrors(table.expectedError, err) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+	}
+}
+
+func TestSliceColumns(t *testing.T) {
+	a := &matrix{matrix: [][]float64{{1, 2, 3, 4}, {5, 6, 7, 8}}, rows: 2, cols: 4}
+	tables := []struct {
+		from           int
+		to             int
+		expectedMatrix *matrix
+		expectedError  error
+	}{
+		{0, 4, &matrix{matrix: [][]float64{{1, 2, 3, 4}, {5, 6, 7, 8}}, rows: 2, cols: 4}, nil},
+		{1, 3, &matrix{matrix: [][]float64{{2, 3}, {6, 7}}, rows: 2, cols: 2}, nil},
+		{3, 4, &matrix{matrix: [][]float64{{4}, {8}}, rows: 2, cols: 1}, nil},
+		{-1, 2, nil, fmt.Errorf("Can't slice columns from -1 to 2 of a matrix with 4 cols")},
+		{2, 5, nil, fmt.Errorf("Can't slice columns from 2 to 5 of a matrix with 4 cols")},
+		{2, 2, nil, fmt.Errorf("Can't slice columns from 2 to 2 of a matrix with 4 cols")},
+	}
+	for _, table := range tables {
+		resultMatrix, err := SliceColumns(a, table.from, table.to)
+		v, _ := resultMatrix.(*matrix)
+		if !equalMatrices(table.expectedMatrix, v) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedMatrix, v)
+		}
+		if !equalErrors(table.expectedError, err) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+	}
+}
+
+func TestNewMatrixFromSlice(t *testing.T) {
+	tables := []struct {
+		values         [][]float64
+		expectedMatrix *matrix
+		expectedError  error
+	}{
+		{[][]float64{}, nil, fmt.Errorf("Can't create a matrix with 0 rows")},
+		{[][]float64{{}}, nil, fmt.Errorf("Can't create a matrix with 0 cols")},
+		{[][]float64{{1, 2}, {3}}, nil, fmt.Errorf("Can't create a matrix from row 1 with 1 cols, expected 2 cols")},
+		{[][]float64{{1, 2}, {3, 4}, {5, 6}}, &matrix{matrix: [][]float64{{1, 2}, {3, 4}, {5, 6}}, rows: 3, cols: 2}, nil},
+	}
+	for _, table := range tables {
+		m, err := NewMatrixFromSlice(table.values)
+		if !equalMatrices(table.expectedMatrix, m) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedMatrix, m)
+		}
+		if !equalErrors(table.expectedError, err) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+		if m != nil && !equalSlices(table.values, ToSlice(m)) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.values, ToSlice(m))
+		}
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	a := &matrix{matrix: [][]float64{{1, 2, 3}, {4, 5, 6}}, rows: 2, cols: 3}
+	tables := []struct {
+		indexes        []int
+		expectedMatrix *matrix
+		expectedError  error
+	}{
+		{[]int{2, 0, 1}, &matrix{matrix: [][]float64{{3, 1, 2}, {6, 4, 5}}, rows: 2, cols: 3}, nil},
+		{[]int{1, 1}, &matrix{matrix: [][]float64{{2, 2}, {5, 5}}, rows: 2, cols: 2}, nil},
+		{[]int{}, nil, fmt.Errorf("Can't select 0 cols")},
+		{[]int{0, 3}, nil, fmt.Errorf("Index 3 is out of bounds for cols with size 3")},
+	}
+	for _, table := range tables {
+		resultMatrix, err := SelectColumns(a, table.indexes)
+		v, _ := resultMatrix.(*matrix)
+		if !equalMatrices(table.expectedMatrix, v) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedMatrix, v)
+		}
+		if !equalErrors(table.expectedError, err) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+	}
+}
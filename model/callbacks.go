@@ -0,0 +1,130 @@
+package model
+
+import (
+	"fmt"
+	"io"
+)
+
+// Logs are the values of the training that are passed to the callbacks
+type Logs struct {
+	// Number of parameter updates done so far
+	Iteration int
+	// Current epoch, i.e: pass through the entire training set
+	Epoch int
+	// Current mini-batch inside the epoch
+	Batch int
+	// Cost of the last batch on OnBatchEnd, or the average cost of the epoch
+	// on OnEpochEnd and OnTrainEnd
+	Cost float64
+	// Values of the metrics, e.g: "accuracy" for the training set and
+	// "val_cost" for the validation set when there's early stopping
+	Metrics map[string]float64
+	// Learning rate used for the last update of the parameters
+	LearningRate float64
+	// Euclidean norm of the gradient of each parameter, i.e. "W1", "B1",
+	// "W2" and "B2", for the last mini-batch
+	GradientNorms map[string]float64
+	// Current parameters of the model
+	Parameters *Parameters
+	// Set to true by a callback to stop the training at the end of the
+	// current epoch
+	StopTraining bool
+
+	state           *trainingState
+	hyperparameters *Hyperparameters
+	callbacks       callbackList
+}
+
+// Callback is the interface used to plug logging, checkpointing, progress
+// reporting or custom stopping logic into the training loop of Model
+type Callback interface {
+	OnTrainBegin(logs *Logs)
+	OnTrainEnd(logs *Logs)
+	OnEpochBegin(epoch int, logs *Logs)
+	OnEpochEnd(epoch int, logs *Logs)
+	OnBatchEnd(batch int, logs *Logs)
+}
+
+// BaseCallback implements all the methods of Callback doing nothing. It's
+// meant to be embedded in callbacks that only need some of the methods
+type BaseCallback struct{}
+
+// OnTrainBegin does nothing
+func (BaseCallback) OnTrainBegin(logs *Logs) {}
+
+// OnTrainEnd does nothing
+func (BaseCallback) OnTrainEnd(logs *Logs) {}
+
+// OnEpochBegin does nothing
+func (BaseCallback) OnEpochBegin(epoch int, logs *Logs) {}
+
+// OnEpochEnd does nothing
+func (BaseCallback) OnEpochEnd(epoch int, logs *Logs) {}
+
+// OnBatchEnd does nothing
+func (BaseCallback) OnBatchEnd(batch int, logs *Logs) {}
+
+// CostPrinter is a Callback that writes the cost every number of epochs. It's
+// the callback used by Model when none is given
+type CostPrinter struct {
+	BaseCallback
+	writer    io.Writer
+	frequency int
+}
+
+// NewCostPrinter creates a CostPrinter that writes the cost to writer every
+// frequency epochs
+func NewCostPrinter(writer io.Writer, frequency int) *CostPrinter {
+	printer := new(CostPrinter)
+	printer.writer = writer
+	printer.frequency = frequency
+	return printer
+}
+
+// OnEpochEnd writes the cost of the epoch if it's scheduled
+func (printer *CostPrinter) OnEpochEnd(epoch int, logs *Logs) {
+	if printer.frequency > 0 && epoch%printer.frequency == 0 {
+		fmt.Fprintf(printer.writer, "Cost after %v iterations: %v\n", epoch, logs.Cost)
+	}
+}
+
+// OnTrainEnd writes the epoch at which the training was stopped if it was
+// stopped early
+func (printer *CostPrinter) OnTrainEnd(logs *Logs) {
+	if logs.StopTraining {
+		fmt.Fprintf(printer.writer, "Training stopped after %v iterations\n", logs.Epoch+1)
+	}
+}
+
+// Callbacks called in the same order as they were given
+type callbackList []Callback
+
+func (callbacks callbackList) onTrainBegin(logs *Logs) {
+	for _, callback := range callbacks {
+		callback.OnTrainBegin(logs)
+	}
+}
+
+func (callbacks callbackList) onTrainEnd(logs *Logs) {
+	for _, callback := range callbacks {
+		callback.OnTrainEnd(logs)
+	}
+}
+
+func (callbacks callbackList) onEpochBegin(epoch int, logs *Logs) {
+	for _, callback := range callbacks {
+		callback.OnEpochBegin(epoch, logs)
+	}
+}
+
+func (callbacks callbackList) onEpochEnd(epoch int, logs *Logs) {
+	for _, callback := range callbacks {
+		callback.OnEpochEnd(epoch, logs)
+	}
+}
+
+func (callbacks callbackList) onBatchEnd(batch int, logs *Logs) {
+	for _, callback := range callbacks {
+		callback.OnBatchEnd(batch, logs)
+	}
+}
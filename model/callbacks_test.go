@@ -0,0 +1,92 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Callback that records the name of every call it receives
+type recordingCallback struct {
+	calls     []string
+	stopEpoch int
+}
+
+func (callback *recordingCallback) OnTrainBegin(logs *Logs) {
+	callback.calls = append(callback.calls, "train_begin")
+}
+
+func (callback *recordingCallback) OnTrainEnd(logs *Logs) {
+	callback.calls = append(callback.calls, "train_end")
+}
+
+func (callback *recordingCallback) OnEpochBegin(epoch int, logs *Logs) {
+	callback.calls = append(callback.calls, fmt.Sprintf("epoch_begin %v", epoch))
+}
+
+func (callback *recordingCallback) OnEpochEnd(epoch int, logs *Logs) {
+	callback.calls = append(callback.calls, fmt.Sprintf("epoch_end %v", epoch))
+	if epoch == callback.stopEpoch {
+		logs.StopTraining = true
+	}
+}
+
+func (callback *recordingCallback) OnBatchEnd(batch int, logs *Logs) {
+	callback.calls = append(callback.calls, fmt.Sprintf("batch_end %v %v", batch, logs.Iteration))
+}
+
+func TestCallbacks(t *testing.T) {
+	X, Y := separableDataset()
+	tables := []struct {
+		numIterations int
+		batchSize     int
+		stopEpoch     int
+		expectedCalls []string
+	}{
+		{
+			2, 0, -1,
+			[]string{"train_begin", "epoch_begin 0", "batch_end 0 1", "epoch_end 0",
+				"epoch_begin 1", "batch_end 0 2", "epoch_end 1", "train_end"},
+		},
+		{
+			2, 3, -1,
+			[]string{"train_begin", "epoch_begin 0", "batch_end 0 1", "batch_end 1 2",
+				"batch_end 2 3", "epoch_end 0", "epoch_begin 1", "batch_end 0 4",
+				"batch_end 1 5", "batch_end 2 6", "epoch_end 1", "train_end"},
+		},
+		{
+			10, 4, 1,
+			[]string{"train_begin", "epoch_begin 0", "batch_end 0 1", "batch_end 1 2",
+				"epoch_end 0", "epoch_begin 1", "batch_end 0 3", "batch_end 1 4",
+				"epoch_end 1", "train_end"},
+		},
+	}
+	for _, table := range tables {
+		callback := &recordingCallback{stopEpoch: table.stopEpoch}
+		hyperparameters := NewHyperparameters(table.numIterations, 1.0, 1, 4)
+		hyperparameters.SetBatchSize(table.batchSize)
+		Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), callback)
+		if !reflect.DeepEqual(table.expectedCalls, callback.calls) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedCalls, callback.calls)
+		}
+	}
+}
+
+func TestCostPrinter(t *testing.T) {
+	X, Y := separableDataset()
+	var buffer bytes.Buffer
+	hyperparameters := NewHyperparameters(5, 1.0, 1, 4)
+	Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), NewCostPrinter(&buffer, 2))
+	expected := 3
+	if actual := bytes.Count(buffer.Bytes(), []byte("Cost after")); expected != actual {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, actual)
+	}
+
+	// stopped at the end of the third epoch
+	buffer.Reset()
+	Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), &recordingCallback{stopEpoch: 2}, NewCostPrinter(&buffer, 2))
+	if expected := "Training stopped after 3 iterations\n"; !bytes.HasSuffix(buffer.Bytes(), []byte(expected)) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, buffer.String())
+	}
+}
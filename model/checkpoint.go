@@ -0,0 +1,287 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Source of random numbers that counts the numbers drawn, so that its state
+// can be saved and restored by drawing the same amount of numbers again
+type countingSource struct {
+	source rand.Source64
+	seed   int64
+	draws  uint64
+}
+
+// Creates a countingSource seeded with seed
+func newCountingSource(seed int64) *countingSource {
+	source := new(countingSource)
+	source.Seed(seed)
+	return source
+}
+
+// Seed resets the source to the state given by seed
+func (source *countingSource) Seed(seed int64) {
+	source.source = rand.NewSource(seed).(rand.Source64)
+	source.seed = seed
+	source.draws = 0
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer
+func (source *countingSource) Int63() int64 {
+	source.draws++
+	return source.source.Int63()
+}
+
+// Uint64 returns a pseudo-random 64-bit integer
+func (source *countingSource) Uint64() uint64 {
+	source.draws++
+	return source.source.Uint64()
+}
+
+// RNGState is the state of the random generator used in the training
+type RNGState struct {
+	Seed  int64  `json:"seed"`
+	Draws uint64 `json:"draws"`
+}
+
+// Creates a countingSource in the given state
+func restoreCountingSource(state RNGState) *countingSource {
+	source := newCountingSource(state.Seed)
+	for source.draws < state.Draws {
+		source.Int63()
+	}
+	return source
+}
+
+// State of a training that is needed to resume it
+type trainingState struct {
+	// First epoch to train
+	epoch int
+	// Number of parameter updates already done
+	iteration int
+	source    *countingSource
+	random    *rand.Rand
+}
+
+// Creates the state of a training that starts from the beginning
+func newTrainingState(seed int64) *trainingState {
+	return restoreTrainingState(0, 0, RNGState{Seed: seed})
+}
+
+// Creates the state of a training that starts at the given epoch
+func restoreTrainingState(epoch, iteration int, rngState RNGState) *trainingState {
+	state := new(trainingState)
+	state.epoch = epoch
+	state.iteration = iteration
+	state.source = restoreCountingSource(rngState)
+	state.random = rand.New(state.source)
+	return state
+}
+
+// EarlyStoppingState is the state of the EarlyStopping of a training
+type EarlyStoppingState struct {
+	Best           float64     `json:"best"`
+	BestIteration  int         `json:"best_iteration"`
+	BestParameters *Parameters `json:"best_parameters"`
+	Wait           int         `json:"wait"`
+}
+
+// MarshalJSON writes the state as a JSON object, with a non-finite best value
+// as a string
+func (state *EarlyStoppingState) MarshalJSON() ([]byte, error) {
+	type plain EarlyStoppingState
+	return json.Marshal(struct {
+		*plain
+		Best jsonFloat `json:"best"`
+	}{(*plain)(state), jsonFloat(state.Best)})
+}
+
+// UnmarshalJSON reads the state written by MarshalJSON
+func (state *EarlyStoppingState) UnmarshalJSON(data []byte) error {
+	type plain EarlyStoppingState
+	decoded := struct {
+		*plain
+		Best jsonFloat `json:"best"`
+	}{plain: (*plain)(state)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	state.Best = float64(decoded.Best)
+	return nil
+}
+
+// Checkpoint holds everything needed to resume a training from the end of an
+// epoch and get the same results as if it had never been interrupted
+type Checkpoint struct {
+	// Number of epochs already trained
+	Epoch int `json:"epoch"`
+	// Number of parameter updates already done
+	Iteration  int         `json:"iteration"`
+	Parameters *Parameters `json:"parameters"`
+	// State of the learning rate schedule, if any
+	Schedule json.RawMessage `json:"schedule,omitempty"`
+	// State of the random generator used for shuffling
+	RNG RNGState `json:"rng"`
+	// State of the early stopping, if any has evaluated the validation set
+	EarlyStopping *EarlyStoppingState `json:"early_stopping,omitempty"`
+	// Records of the History callback, if any
+	History []HistoryRecord `json:"history,omitempty"`
+}
+
+// Checkpoint returns the current state of the training, or nil if the logs
+// aren't the ones of a training. It's meant to be called at OnEpochEnd, so
+// that callbacks can save their own checkpoints
+func (logs *Logs) Checkpoint() *Checkpoint {
+	if logs.state == nil {
+		return nil
+	}
+	checkpoint := new(Checkpoint)
+	checkpoint.Epoch = logs.Epoch + 1
+	checkpoint.Iteration = logs.Iteration
+	checkpoint.Parameters = copyParameters(logs.Parameters)
+	checkpoint.RNG = RNGState{Seed: logs.state.source.seed, Draws: logs.state.source.draws}
+	if schedule := logs.hyperparameters.schedule; schedule != nil {
+		state, err := json.Marshal(schedule)
+		handleError(err)
+		checkpoint.Schedule = state
+	}
+	if earlyStopping := logs.hyperparameters.earlyStopping; earlyStopping != nil && earlyStopping.bestParameters != nil {
+		checkpoint.EarlyStopping = &EarlyStoppingState{
+			Best:           earlyStopping.best,
+			BestIteration:  earlyStopping.bestIteration,
+			BestParameters: copyParameters(earlyStopping.bestParameters),
+			Wait:           earlyStopping.wait,
+		}
+	}
+	for _, callback := range logs.callbacks {
+		if history, ok := callback.(*History); ok {
+			checkpoint.History = append([]HistoryRecord(nil), history.Records...)
+			break
+		}
+	}
+	return checkpoint
+}
+
+// SaveCheckpoint writes the checkpoint to filename as JSON. The file is
+// replaced atomically, so that an interruption while saving doesn't corrupt
+// the previous checkpoint
+func SaveCheckpoint(filename string, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(filename, data, 0644)
+}
+
+// LoadCheckpoint reads a checkpoint written by SaveCheckpoint
+func LoadCheckpoint(filename string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(Checkpoint)
+	if err = json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("Can't read checkpoint %v: %v", filename, err)
+	}
+	if checkpoint.Parameters == nil {
+		return nil, fmt.Errorf("Can't read checkpoint %v: missing parameters", filename)
+	}
+	return checkpoint, nil
+}
+
+// Resume continues the training saved in checkpoint until the number of
+// iterations of the hyperparameters. The dataset, hyperparameters and
+// callbacks must be the same as the ones of the interrupted training for the
+// results to be the same. The state of the schedule, early stopping and
+// History callback are restored from the checkpoint
+func Resume(checkpoint *Checkpoint, X, Y matrix.NumberArray, hyperparameters *Hyperparameters, callbacks ...Callback) (*Parameters, error) {
+	if err := hyperparameters.check(checkpoint.Parameters.W1.GetColumns()); err != nil {
+		return nil, err
+	}
+	if adaptive, ok := hyperparameters.schedule.(AdaptiveSchedule); ok {
+		adaptive.Reset()
+	}
+	if schedule := hyperparameters.schedule; schedule != nil && checkpoint.Schedule != nil {
+		if err := json.Unmarshal(checkpoint.Schedule, schedule); err != nil {
+			return nil, fmt.Errorf("Can't restore the learning rate schedule: %v", err)
+		}
+	}
+	if earlyStopping := hyperparameters.earlyStopping; earlyStopping != nil {
+		earlyStopping.reset()
+		if state := checkpoint.EarlyStopping; state != nil {
+			earlyStopping.best = state.Best
+			earlyStopping.bestIteration = state.BestIteration
+			earlyStopping.bestParameters = copyParameters(state.BestParameters)
+			earlyStopping.wait = state.Wait
+		}
+	}
+	for _, callback := range callbacks {
+		if history, ok := callback.(*History); ok {
+			history.Records = append([]HistoryRecord(nil), checkpoint.History...)
+		}
+	}
+	state := restoreTrainingState(checkpoint.Epoch, checkpoint.Iteration, checkpoint.RNG)
+	return train(&arraySource{X: X, Y: Y}, hyperparameters, copyParameters(checkpoint.Parameters), state, callbacks), nil
+}
+
+// Values of the parameters as they are written in JSON, with the non-finite
+// values as strings
+type parametersJSON struct {
+	W1 [][]jsonFloat `json:"W1"`
+	B1 [][]jsonFloat `json:"B1"`
+	W2 [][]jsonFloat `json:"W2"`
+	B2 [][]jsonFloat `json:"B2"`
+}
+
+// Returns the rows of the array as JSON values
+func toJSONRows(array matrix.NumberArray) [][]jsonFloat {
+	rows := matrix.ToSlice(array)
+	encoded := make([][]jsonFloat, len(rows))
+	for i, row := range rows {
+		encoded[i] = make([]jsonFloat, len(row))
+		for j, value := range row {
+			encoded[i][j] = jsonFloat(value)
+		}
+	}
+	return encoded
+}
+
+// MarshalJSON writes the parameters as a JSON object with the rows of each of
+// the arrays
+func (parameters *Parameters) MarshalJSON() ([]byte, error) {
+	return json.Marshal(parametersJSON{
+		W1: toJSONRows(parameters.W1),
+		B1: toJSONRows(parameters.B1),
+		W2: toJSONRows(parameters.W2),
+		B2: toJSONRows(parameters.B2),
+	})
+}
+
+// UnmarshalJSON reads the parameters written by MarshalJSON
+func (parameters *Parameters) UnmarshalJSON(data []byte) error {
+	var values parametersJSON
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	arrays := []*matrix.NumberArray{&parameters.W1, &parameters.B1, &parameters.W2, &parameters.B2}
+	for i, encoded := range [][][]jsonFloat{values.W1, values.B1, values.W2, values.B2} {
+		rows := make([][]float64, len(encoded))
+		for j, row := range encoded {
+			rows[j] = make([]float64, len(row))
+			for k, value := range row {
+				rows[j][k] = float64(value)
+			}
+		}
+		m, err := matrix.NewMatrixFromSlice(rows)
+		if err != nil {
+			return err
+		}
+		*arrays[i] = m
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Callback that stops the training at the end of the given epoch
+type stopAt struct {
+	BaseCallback
+	epoch int
+}
+
+func (callback *stopAt) OnEpochEnd(epoch int, logs *Logs) {
+	if epoch == callback.epoch {
+		logs.StopTraining = true
+	}
+}
+
+// Creates the hyperparameters of a training with every stateful option set
+func checkpointHyperparameters(filename string) *Hyperparameters {
+	X, Y := separableDataset()
+	hyperparameters := NewHyperparameters(8, 1.0, 1, 4)
+	hyperparameters.SetBatchSize(3)
+	hyperparameters.SetShuffle(42)
+	hyperparameters.SetLearningRateSchedule(NewReduceOnPlateau(0.5, 1))
+	earlyStopping := NewEarlyStopping(X, Y, MetricCost, 100)
+	earlyStopping.RestoreBestParameters = false
+	hyperparameters.SetEarlyStopping(earlyStopping)
+	if filename != "" {
+		hyperparameters.SetCheckpointing(filename, 2)
+	}
+	return hyperparameters
+}
+
+// Removes the wall time of the records, which differs between trainings
+func withoutWallTime(records []HistoryRecord) []HistoryRecord {
+	result := append([]HistoryRecord(nil), records...)
+	for i := range result {
+		result[i].WallTime = 0
+	}
+	return result
+}
+
+func TestResume(t *testing.T) {
+	X, Y := separableDataset()
+
+	// uninterrupted training
+	history := NewHistory(false)
+	expected := Model(X, Y, checkpointHyperparameters(""), X.GetColumns(), X.GetRows(), history)
+
+	// training interrupted after the checkpoint of the 4th epoch
+	filename := filepath.Join(t.TempDir(), "checkpoint.json")
+	interruptedHistory := NewHistory(false)
+	Model(X, Y, checkpointHyperparameters(filename), X.GetColumns(), X.GetRows(), interruptedHistory, &stopAt{epoch: 3})
+
+	checkpoint, err := LoadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Epoch != 4 || len(checkpoint.History) != 4 || checkpoint.RNG.Draws == 0 {
+		t.Fatalf("Expected checkpoint of epoch 4 with history and random draws, Actual: %+v\n", checkpoint)
+	}
+	resumedHistory := NewHistory(false)
+	actual, err := Resume(checkpoint, X, Y, checkpointHyperparameters(""), resumedHistory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arrays := []struct {
+		name             string
+		expected, actual matrix.NumberArray
+	}{
+		{"W1", expected.W1, actual.W1},
+		{"B1", expected.B1, actual.B1},
+		{"W2", expected.W2, actual.W2},
+		{"B2", expected.B2, actual.B2},
+	}
+	for _, array := range arrays {
+		if !reflect.DeepEqual(matrix.ToSlice(array.expected), matrix.ToSlice(array.actual)) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", array.name, array.expected, array.actual)
+		}
+	}
+	if !reflect.DeepEqual(withoutWallTime(history.Records), withoutWallTime(resumedHistory.Records)) {
+		t.Errorf("Expected: %+v, Actual: %+v\n", history.Records, resumedHistory.Records)
+	}
+}
+
+func TestLoadCheckpointErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadCheckpoint(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("Expected an error loading a missing checkpoint")
+	}
+	filename := filepath.Join(dir, "empty.json")
+	if err := SaveCheckpoint(filename, &Checkpoint{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(filename); err == nil {
+		t.Errorf("Expected an error loading a checkpoint without parameters")
+	}
+}
+
+func TestCheckpointNonFinite(t *testing.T) {
+	W1, _ := matrix.NewMatrixFromSlice([][]float64{{math.NaN(), 1}})
+	B1, _ := matrix.NewMatrixFromSlice([][]float64{{math.Inf(1)}})
+	W2, _ := matrix.NewMatrixFromSlice([][]float64{{math.Inf(-1)}})
+	B2, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	parameters := &Parameters{W1: W1, B1: B1, W2: W2, B2: B2}
+	schedule := NewReduceOnPlateau(0.5, 2)
+	schedule.Started = true
+	schedule.Best = math.NaN()
+	encoded, err := json.Marshal(schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint := &Checkpoint{
+		Epoch:         1,
+		Parameters:    parameters,
+		Schedule:      encoded,
+		EarlyStopping: &EarlyStoppingState{Best: math.Inf(1), BestParameters: parameters},
+		History:       []HistoryRecord{{Cost: math.NaN()}},
+	}
+	filename := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := SaveCheckpoint(filename, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := matrix.ToSlice(loaded.Parameters.W1)
+	if !math.IsNaN(values[0][0]) || values[0][1] != 1 {
+		t.Errorf("Expected: [[NaN 1]], Actual: %v\n", values)
+	}
+	if value, _ := loaded.Parameters.W2.GetValue(0, 0); !math.IsInf(value, -1) {
+		t.Errorf("Expected: -Inf, Actual: %v\n", value)
+	}
+	if !math.IsInf(loaded.EarlyStopping.Best, 1) || !math.IsNaN(loaded.History[0].Cost) {
+		t.Errorf("Expected: +Inf and NaN, Actual: %v and %v\n", loaded.EarlyStopping.Best, loaded.History[0].Cost)
+	}
+	restored := NewReduceOnPlateau(0.5, 2)
+	if err := json.Unmarshal(loaded.Schedule, restored); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Started || !math.IsNaN(restored.Best) || restored.Patience != 2 {
+		t.Errorf("Expected: %+v, Actual: %+v\n", schedule, restored)
+	}
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+
+	"github.com/chibby0ne/micro_neural_network/dataset"
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// CrossValidationResult holds the metrics on the validation examples of each
+// fold, and their mean and standard deviation across the folds
+type CrossValidationResult struct {
+	Folds []map[string]float64
+	Mean  map[string]float64
+	Std   map[string]float64
+}
+
+// CrossValidate trains the model with the given hyperparameters on each of k
+// folds of the examples, stratified by label, and evaluates the cost and
+// accuracy on the examples left out of each fold. The folds are drawn with a
+// random generator seeded with seed. The callbacks are passed to every
+// training.
+// Each fold trains with its own copy of the hyperparameters: the early
+// stopping, if any, monitors the examples left out of the fold instead of its
+// own validation set, and the checkpoints, if any, are saved to a file per
+// fold, e.g. checkpoint.fold1.json for checkpoint.json
+func CrossValidate(X, Y matrix.NumberArray, hyperparameters *Hyperparameters, k int, seed int64, callbacks ...Callback) (*CrossValidationResult, error) {
+	folds, err := dataset.KFold(Y, k, true, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return nil, err
+	}
+	result := new(CrossValidationResult)
+	for i, fold := range folds {
+		XTrain, YTrain, err := dataset.Examples(X, Y, fold.Train)
+		if err != nil {
+			return nil, err
+		}
+		XValidation, YValidation, err := dataset.Examples(X, Y, fold.Validation)
+		if err != nil {
+			return nil, err
+		}
+		foldHyperparameters := hyperparameters.forFold(i, XValidation, YValidation)
+		if err := foldHyperparameters.check(XTrain.GetRows()); err != nil {
+			return nil, err
+		}
+		parameters := Model(XTrain, YTrain, foldHyperparameters, XTrain.GetColumns(), XTrain.GetRows(), callbacks...)
+		metrics := make(map[string]float64)
+		for _, metric := range []string{MetricCost, MetricAccuracy} {
+			if metrics[metric], err = evaluateMetric(metric, parameters, XValidation, YValidation); err != nil {
+				return nil, err
+			}
+		}
+		result.Folds = append(result.Folds, metrics)
+	}
+	result.Mean, result.Std = aggregateMetrics(result.Folds)
+	return result, nil
+}
+
+// Returns a copy of the hyperparameters for the training of the given fold,
+// which doesn't share any state with the ones of the other folds
+func (hyperparameters *Hyperparameters) forFold(fold int, XValidation, YValidation matrix.NumberArray) *Hyperparameters {
+	copied := *hyperparameters
+	if hyperparameters.earlyStopping != nil {
+		earlyStopping := *hyperparameters.earlyStopping
+		earlyStopping.X = XValidation
+		earlyStopping.Y = YValidation
+		copied.earlyStopping = &earlyStopping
+	}
+	// the schedules keep their state in the struct they point to
+	if schedule := reflect.ValueOf(hyperparameters.schedule); schedule.Kind() == reflect.Ptr && !schedule.IsNil() {
+		scheduleCopy := reflect.New(schedule.Elem().Type())
+		scheduleCopy.Elem().Set(schedule.Elem())
+		copied.schedule = scheduleCopy.Interface().(LearningRateSchedule)
+	}
+	if filename := hyperparameters.checkpointFile; filename != "" {
+		extension := filepath.Ext(filename)
+		copied.checkpointFile = fmt.Sprintf("%v.fold%v%v", filename[:len(filename)-len(extension)], fold+1, extension)
+	}
+	return &copied
+}
+
+// Returns the mean and the standard deviation of each metric across the folds
+func aggregateMetrics(folds []map[string]float64) (mean, std map[string]float64) {
+	mean = make(map[string]float64)
+	std = make(map[string]float64)
+	for _, metrics := range folds {
+		for name, value := range metrics {
+			mean[name] += value / float64(len(folds))
+		}
+	}
+	for _, metrics := range folds {
+		for name, value := range metrics {
+			std[name] += math.Pow(value-mean[name], 2) / float64(len(folds))
+		}
+	}
+	for name := range std {
+		std[name] = math.Sqrt(std[name])
+	}
+	return mean, std
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"math"
+	"sort"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// CurvePoint is a point of a ROC or precision-recall curve, obtained by
+// labelling as 1 the examples whose probability is at least Threshold
+type CurvePoint struct {
+	X         float64
+	Y         float64
+	Threshold float64
+}
+
+// Counts of the examples labelled as 1 at a threshold
+type thresholdCounts struct {
+	threshold      float64
+	truePositives  int
+	falsePositives int
+}
+
+// Returns the counts of true and false positives at each distinct probability,
+// from the highest to the lowest, along with the number of examples of the
+// class 1 and of the class 0
+func countsByThreshold(probabilities, Y matrix.NumberArray) (counts []thresholdCounts, positives, negatives int) {
+	m := probabilities.GetColumns()
+	order := make([]int, m)
+	for j := range order {
+		order[j] = j
+	}
+	values := matrix.ToSlice(probabilities)[0]
+	desired := matrix.ToSlice(Y)[0]
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] > values[order[b]] })
+	current := thresholdCounts{}
+	for i, j := range order {
+		if desired[j] > 0.5 {
+			current.truePositives++
+			positives++
+		} else {
+			current.falsePositives++
+			negatives++
+		}
+		// examples with the same probability are labelled together
+		if i == m-1 || values[order[i+1]] != values[j] {
+			current.threshold = values[j]
+			counts = append(counts, current)
+		}
+	}
+	return counts, positives, negatives
+}
+
+// Returns numerator / denominator, or 0 when the denominator is 0
+func ratio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
+
+// ROCCurve returns the receiver operating characteristic of the predicted
+// probabilities against the desired outputs Y: the false positive rate as X
+// and the true positive rate as Y, at every distinct threshold. The curve
+// starts at (0, 0) and ends at (1, 1)
+func ROCCurve(probabilities, Y matrix.NumberArray) []CurvePoint {
+	counts, positives, negatives := countsByThreshold(probabilities, Y)
+	points := []CurvePoint{{X: 0, Y: 0, Threshold: math.Inf(1)}}
+	for _, count := range counts {
+		points = append(points, CurvePoint{
+			X:         ratio(count.falsePositives, negatives),
+			Y:         ratio(count.truePositives, positives),
+			Threshold: count.threshold,
+		})
+	}
+	return points
+}
+
+// PrecisionRecallCurve returns the recall as X and the precision as Y of the
+// predicted probabilities against the desired outputs Y, at every distinct
+// threshold. The curve starts at a recall of 0 with a precision of 1
+func PrecisionRecallCurve(probabilities, Y matrix.NumberArray) []CurvePoint {
+	counts, positives, _ := countsByThreshold(probabilities, Y)
+	points := []CurvePoint{{X: 0, Y: 1, Threshold: math.Inf(1)}}
+	for _, count := range counts {
+		points = append(points, CurvePoint{
+			X:         ratio(count.truePositives, positives),
+			Y:         ratio(count.truePositives, count.truePositives+count.falsePositives),
+			Threshold: count.threshold,
+		})
+	}
+	return points
+}
+
+// AreaUnderCurve returns the area under the points of a curve with the
+// trapezoidal rule
+func AreaUnderCurve(points []CurvePoint) float64 {
+	area := 0.0
+	for i := 1; i < len(points); i++ {
+		area += (points[i].X - points[i-1].X) * (points[i].Y + points[i-1].Y) / 2
+	}
+	return area
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// True when the points are equal, up to rounding errors
+func equalCurves(a, b []CurvePoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i].X-b[i].X) > 1e-12 || math.Abs(a[i].Y-b[i].Y) > 1e-12 || a[i].Threshold != b[i].Threshold {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCurves(t *testing.T) {
+	inf := math.Inf(1)
+	tables := []struct {
+		probabilities []float64
+		desired       []float64
+		expectedROC   []CurvePoint
+		expectedPR    []CurvePoint
+		expectedAUC   float64
+	}{
+		{
+			[]float64{0.4, 0.9, 0.7, 0.6, 0.8, 0.55},
+			[]float64{0, 1, 0, 1, 1, 0},
+			[]CurvePoint{{0, 0, inf}, {0, 1.0 / 3, 0.9}, {0, 2.0 / 3, 0.8}, {1.0 / 3, 2.0 / 3, 0.7}, {1.0 / 3, 1, 0.6}, {2.0 / 3, 1, 0.55}, {1, 1, 0.4}},
+			[]CurvePoint{{0, 1, inf}, {1.0 / 3, 1, 0.9}, {2.0 / 3, 1, 0.8}, {2.0 / 3, 2.0 / 3, 0.7}, {1, 0.75, 0.6}, {1, 0.6, 0.55}, {1, 0.5, 0.4}},
+			8.0 / 9,
+		},
+		{
+			// examples with the same probability can't be told apart
+			[]float64{0.5, 0.5},
+			[]float64{1, 0},
+			[]CurvePoint{{0, 0, inf}, {1, 1, 0.5}},
+			[]CurvePoint{{0, 1, inf}, {1, 0.5, 0.5}},
+			0.5,
+		},
+	}
+	for _, table := range tables {
+		probabilities, _ := matrix.NewMatrixFromSlice([][]float64{table.probabilities})
+		Y, _ := matrix.NewMatrixFromSlice([][]float64{table.desired})
+		roc := ROCCurve(probabilities, Y)
+		if !equalCurves(table.expectedROC, roc) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedROC, roc)
+		}
+		if pr := PrecisionRecallCurve(probabilities, Y); !equalCurves(table.expectedPR, pr) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedPR, pr)
+		}
+		if auc := AreaUnderCurve(roc); math.Abs(auc-table.expectedAUC) > 1e-12 {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedAUC, auc)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// EarlyStopping monitors a metric on a validation set while training, and
+// stops the training once the metric hasn't improved for Patience evaluations
+type EarlyStopping struct {
+	// Inputs and desired outputs of the validation set
+	X matrix.NumberArray
+	Y matrix.NumberArray
+	// Name of the monitored metric, i.e: MetricCost or MetricAccuracy
+	Metric string
+	// Number of epochs between each evaluation of the metric
+	Frequency int
+	// Number of evaluations without improvement before stopping
+	Patience int
+	// Minimum change of the metric that is considered an improvement
+	MinDelta float64
+	// Whether the parameters with the best metric are returned by Model
+	// instead of the ones from the last iteration
+	RestoreBestParameters bool
+
+	best             float64
+	bestIteration    int
+	bestParameters   *Parameters
+	wait             int
+	stoppedIteration int
+}
+
+// NewEarlyStopping creates an EarlyStopping that evaluates the metric on the
+// validation set X, Y every iteration, and restores the best parameters
+func NewEarlyStopping(X, Y matrix.NumberArray, metric string, patience int) *EarlyStopping {
+	earlyStopping := new(EarlyStopping)
+	earlyStopping.X = X
+	earlyStopping.Y = Y
+	earlyStopping.Metric = metric
+	earlyStopping.Frequency = 1
+	earlyStopping.Patience = patience
+	earlyStopping.RestoreBestParameters = true
+	return earlyStopping
+}
+
+// Best returns the best value of the metric seen and the epoch it was seen at
+func (earlyStopping *EarlyStopping) Best() (float64, int) {
+	return earlyStopping.best, earlyStopping.bestIteration
+}
+
+// StoppedIteration returns the epoch at which the training was stopped, or -1
+// if the training ran for all its iterations
+func (earlyStopping *EarlyStopping) StoppedIteration() int {
+	return earlyStopping.stoppedIteration
+}
+
+// Returns an error if the early stopping can't monitor a network with the
+// given number of input features, i.e. if its metric is unknown or its
+// validation set doesn't have those features and a desired output per example
+func (earlyStopping *EarlyStopping) check(numberFeatures int) error {
+	if err := checkMetric(earlyStopping.Metric); err != nil {
+		return err
+	}
+	X, Y := earlyStopping.X, earlyStopping.Y
+	if X == nil || Y == nil {
+		return fmt.Errorf("Can't monitor a training without a validation set")
+	}
+	if X.GetRows() != numberFeatures {
+		return fmt.Errorf("Can't monitor a network of %v features with a validation set of %v features", numberFeatures, X.GetRows())
+	}
+	if Y.GetRows() != 1 || Y.GetColumns() != X.GetColumns() {
+		return fmt.Errorf("Can't monitor a validation set of %v examples with desired outputs of shape %vx%v", X.GetColumns(), Y.GetRows(), Y.GetColumns())
+	}
+	return nil
+}
+
+// Clears the state left from a previous training
+func (earlyStopping *EarlyStopping) reset() {
+	earlyStopping.best = math.Inf(1)
+	if higherIsBetter(earlyStopping.Metric) {
+		earlyStopping.best = math.Inf(-1)
+	}
+	earlyStopping.bestIteration = -1
+	earlyStopping.bestParameters = nil
+	earlyStopping.wait = 0
+	earlyStopping.stoppedIteration = -1
+}
+
+// True when value is better than the best value seen by more than MinDelta
+func (earlyStopping *EarlyStopping) improves(value float64) bool {
+	if higherIsBetter(earlyStopping.Metric) {
+		return value > earlyStopping.best+earlyStopping.MinDelta
+	}
+	return value < earlyStopping.best-earlyStopping.MinDelta
+}
+
+// Evaluates the metric with the parameters of the given epoch, if it's
+// scheduled, and returns its value, whether it was evaluated and whether the
+// training should stop
+func (earlyStopping *EarlyStopping) update(epoch int, parameters *Parameters) (value float64, evaluated, stop bool) {
+	frequency := earlyStopping.Frequency
+	if frequency < 1 {
+		frequency = 1
+	}
+	if (epoch+1)%frequency != 0 {
+		return value, false, false
+	}
+	value, err := evaluateMetric(earlyStopping.Metric, parameters, earlyStopping.X, earlyStopping.Y)
+	handleError(err)
+	if earlyStopping.improves(value) {
+		earlyStopping.best = value
+		earlyStopping.bestIteration = epoch
+		earlyStopping.bestParameters = copyParameters(parameters)
+		earlyStopping.wait = 0
+		return value, true, false
+	}
+	earlyStopping.wait++
+	if earlyStopping.wait >= earlyStopping.Patience {
+		earlyStopping.stoppedIteration = epoch
+		return value, true, true
+	}
+	return value, true, false
+}
@@ -0,0 +1,359 @@
+package model
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns of the CSV history that precede the metrics' columns
+var historyColumns = []string{"iteration", "epoch", "batch", "cost", "learning_rate", "wall_time"}
+
+// HistoryRecord holds the values of the training at the end of an epoch, or
+// of a mini-batch
+type HistoryRecord struct {
+	Iteration    int                `json:"iteration"`
+	Epoch        int                `json:"epoch"`
+	Batch        int                `json:"batch"`
+	Cost         float64            `json:"cost"`
+	Metrics      map[string]float64 `json:"metrics,omitempty"`
+	LearningRate float64            `json:"learning_rate"`
+	// Seconds elapsed since the beginning of the training
+	WallTime float64 `json:"wall_time"`
+}
+
+// Float written in JSON as a number, or as the strings NaN, +Inf and -Inf
+// that JSON numbers can't represent, e.g. the cost of a diverging training
+type jsonFloat float64
+
+// MarshalJSON writes the value as a number when it's finite, and as a string
+// otherwise
+func (value jsonFloat) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+		return json.Marshal(formatFloat(float64(value)))
+	}
+	return json.Marshal(float64(value))
+}
+
+// UnmarshalJSON reads the values written by MarshalJSON
+func (value *jsonFloat) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*value = jsonFloat(number)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("Can't read the number %v", text)
+	}
+	*value = jsonFloat(number)
+	return nil
+}
+
+// A record as it's written in JSON
+type historyRecordJSON struct {
+	Iteration    int                  `json:"iteration"`
+	Epoch        int                  `json:"epoch"`
+	Batch        int                  `json:"batch"`
+	Cost         jsonFloat            `json:"cost"`
+	Metrics      map[string]jsonFloat `json:"metrics,omitempty"`
+	LearningRate jsonFloat            `json:"learning_rate"`
+	WallTime     jsonFloat            `json:"wall_time"`
+}
+
+// MarshalJSON writes the record as a JSON object, with the non-finite values
+// as strings
+func (record HistoryRecord) MarshalJSON() ([]byte, error) {
+	encoded := historyRecordJSON{
+		Iteration:    record.Iteration,
+		Epoch:        record.Epoch,
+		Batch:        record.Batch,
+		Cost:         jsonFloat(record.Cost),
+		LearningRate: jsonFloat(record.LearningRate),
+		WallTime:     jsonFloat(record.WallTime),
+	}
+	if record.Metrics != nil {
+		encoded.Metrics = make(map[string]jsonFloat, len(record.Metrics))
+		for name, value := range record.Metrics {
+			encoded.Metrics[name] = jsonFloat(value)
+		}
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON reads the records written by MarshalJSON
+func (record *HistoryRecord) UnmarshalJSON(data []byte) error {
+	var decoded historyRecordJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*record = HistoryRecord{
+		Iteration:    decoded.Iteration,
+		Epoch:        decoded.Epoch,
+		Batch:        decoded.Batch,
+		Cost:         float64(decoded.Cost),
+		LearningRate: float64(decoded.LearningRate),
+		WallTime:     float64(decoded.WallTime),
+	}
+	if decoded.Metrics != nil {
+		record.Metrics = make(map[string]float64, len(decoded.Metrics))
+		for name, value := range decoded.Metrics {
+			record.Metrics[name] = float64(value)
+		}
+	}
+	return nil
+}
+
+// History is a Callback that records the cost, metrics, learning rate and
+// wall time of the training, either once per epoch or once per mini-batch
+type History struct {
+	BaseCallback
+	Records  []HistoryRecord
+	perBatch bool
+	start    time.Time
+}
+
+// NewHistory creates a History that records every mini-batch when perBatch is
+// true, or every epoch otherwise
+func NewHistory(perBatch bool) *History {
+	history := new(History)
+	history.perBatch = perBatch
+	return history
+}
+
+// OnTrainBegin clears the records and starts measuring the wall time. When
+// the training is resumed from a checkpoint the records are kept, and the wall
+// time continues from the last record
+func (history *History) OnTrainBegin(logs *Logs) {
+	history.start = time.Now()
+	if logs.Iteration == 0 {
+		history.Records = nil
+	} else if len(history.Records) > 0 {
+		elapsed := history.Records[len(history.Records)-1].WallTime
+		history.start = history.start.Add(-time.Duration(elapsed * float64(time.Second)))
+	}
+}
+
+// OnBatchEnd records the batch if recording per mini-batch
+func (history *History) OnBatchEnd(batch int, logs *Logs) {
+	if history.perBatch {
+		history.record(logs)
+	}
+}
+
+// OnEpochEnd records the epoch if recording per epoch
+func (history *History) OnEpochEnd(epoch int, logs *Logs) {
+	if !history.perBatch {
+		history.record(logs)
+	}
+}
+
+// Appends the current values of the logs to the records
+func (history *History) record(logs *Logs) {
+	record := HistoryRecord{
+		Iteration:    logs.Iteration,
+		Epoch:        logs.Epoch,
+		Batch:        logs.Batch,
+		Cost:         logs.Cost,
+		LearningRate: logs.LearningRate,
+		WallTime:     time.Since(history.start).Seconds(),
+	}
+	if len(logs.Metrics) > 0 {
+		record.Metrics = make(map[string]float64, len(logs.Metrics))
+		for name, value := range logs.Metrics {
+			record.Metrics[name] = value
+		}
+	}
+	history.Records = append(history.Records, record)
+}
+
+// Save writes the records to filename as CSV if its extension is .csv, or as
+// JSON Lines otherwise
+func (history *History) Save(filename string) (err error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if isCSV(filename) {
+		return WriteHistoryCSV(f, history.Records)
+	}
+	return WriteHistoryJSONLines(f, history.Records)
+}
+
+// LoadHistory reads the records from filename, as CSV if its extension is
+// .csv, or as JSON Lines otherwise
+func LoadHistory(filename string) ([]HistoryRecord, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if isCSV(filename) {
+		return ReadHistoryCSV(f)
+	}
+	return ReadHistoryJSONLines(f)
+}
+
+// True when the file extension is the one of a CSV file
+func isCSV(filename string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".csv"
+}
+
+// WriteHistoryJSONLines writes each record as a JSON object in its own line
+func WriteHistoryJSONLines(w io.Writer, records []HistoryRecord) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadHistoryJSONLines reads the records written by WriteHistoryJSONLines
+func ReadHistoryJSONLines(r io.Reader) ([]HistoryRecord, error) {
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("Can't read history record in line %v: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Returns the sorted names of all the metrics present in the records
+func metricNames(records []HistoryRecord) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, record := range records {
+		for name := range record.Metrics {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Formats a float so that it's read back with the same value
+func formatFloat(val float64) string {
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+// WriteHistoryCSV writes the records as CSV with a header row, with one column
+// per metric that is left empty in the records that don't have it
+func WriteHistoryCSV(w io.Writer, records []HistoryRecord) error {
+	metrics := metricNames(records)
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, historyColumns...), metrics...)); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := []string{
+			strconv.Itoa(record.Iteration),
+			strconv.Itoa(record.Epoch),
+			strconv.Itoa(record.Batch),
+			formatFloat(record.Cost),
+			formatFloat(record.LearningRate),
+			formatFloat(record.WallTime),
+		}
+		for _, name := range metrics {
+			value, ok := record.Metrics[name]
+			if ok {
+				row = append(row, formatFloat(value))
+			} else {
+				row = append(row, "")
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadHistoryCSV reads the records written by WriteHistoryCSV
+func ReadHistoryCSV(r io.Reader) ([]HistoryRecord, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Can't read history from an empty CSV")
+	}
+	header := rows[0]
+	if len(header) < len(historyColumns) {
+		return nil, fmt.Errorf("Can't read history with header %v", header)
+	}
+	for i, column := range historyColumns {
+		if header[i] != column {
+			return nil, fmt.Errorf("Expected column %v in the history header but got %v", column, header[i])
+		}
+	}
+	records := make([]HistoryRecord, 0, len(rows)-1)
+	for line, row := range rows[1:] {
+		record, err := parseHistoryRow(header, row)
+		if err != nil {
+			return nil, fmt.Errorf("Can't read history record in line %v: %v", line+2, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Parses a CSV row into a HistoryRecord given the header of the file
+func parseHistoryRow(header, row []string) (record HistoryRecord, err error) {
+	ints := []*int{&record.Iteration, &record.Epoch, &record.Batch}
+	for i, field := range ints {
+		if *field, err = strconv.Atoi(row[i]); err != nil {
+			return record, err
+		}
+	}
+	floats := []*float64{&record.Cost, &record.LearningRate, &record.WallTime}
+	for i, field := range floats {
+		if *field, err = strconv.ParseFloat(row[len(ints)+i], 64); err != nil {
+			return record, err
+		}
+	}
+	for i := len(historyColumns); i < len(header); i++ {
+		if row[i] == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(row[i], 64)
+		if err != nil {
+			return record, err
+		}
+		if record.Metrics == nil {
+			record.Metrics = make(map[string]float64)
+		}
+		record.Metrics[header[i]] = value
+	}
+	return record, nil
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	X, Y := separableDataset()
+	tables := []struct {
+		perBatch        bool
+		batchSize       int
+		expectedRecords int
+	}{
+		{false, 0, 4},
+		{false, 3, 4},
+		{true, 3, 12},
+	}
+	for _, table := range tables {
+		history := NewHistory(table.perBatch)
+		hyperparameters := NewHyperparameters(4, 0.5, 1, 4)
+		hyperparameters.SetBatchSize(table.batchSize)
+		Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), history)
+		if actual := len(history.Records); table.expectedRecords != actual {
+			t.Fatalf("Expected: %v, Actual: %v\n", table.expectedRecords, actual)
+		}
+		last := history.Records[len(history.Records)-1]
+		if last.Epoch != 3 || last.LearningRate != 0.5 {
+			t.Errorf("Expected epoch 3 with learning rate 0.5, Actual: %+v\n", last)
+		}
+		if _, ok := last.Metrics[MetricAccuracy]; !ok {
+			t.Errorf("Expected metric %v in %+v\n", MetricAccuracy, last)
+		}
+	}
+}
+
+// Records with and without metrics, the latter are written as empty CSV cells
+var historyRecords = []HistoryRecord{
+	{Iteration: 1, Epoch: 0, Batch: 0, Cost: 0.6931, LearningRate: 0.1, WallTime: 0.002,
+		Metrics: map[string]float64{"accuracy": 0.5}},
+	{Iteration: 2, Epoch: 1, Batch: 0, Cost: 0.12345678901234, LearningRate: 0.05, WallTime: 0.004,
+		Metrics: map[string]float64{"accuracy": 0.75, "val_cost": 0.9}},
+	{Iteration: 3, Epoch: 2, Batch: 0, Cost: 0.1, LearningRate: 0.05, WallTime: 0.006},
+}
+
+func TestHistoryFormats(t *testing.T) {
+	tables := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		read  func(*bytes.Buffer) ([]HistoryRecord, error)
+	}{
+		{
+			"jsonl",
+			func(b *bytes.Buffer) error { return WriteHistoryJSONLines(b, historyRecords) },
+			func(b *bytes.Buffer) ([]HistoryRecord, error) { return ReadHistoryJSONLines(b) },
+		},
+		{
+			"csv",
+			func(b *bytes.Buffer) error { return WriteHistoryCSV(b, historyRecords) },
+			func(b *bytes.Buffer) ([]HistoryRecord, error) { return ReadHistoryCSV(b) },
+		},
+	}
+	for _, table := range tables {
+		var buffer bytes.Buffer
+		if err := table.write(&buffer); err != nil {
+			t.Fatalf("%v: %v", table.name, err)
+		}
+		actual, err := table.read(&buffer)
+		if err != nil {
+			t.Fatalf("%v: %v", table.name, err)
+		}
+		if !reflect.DeepEqual(historyRecords, actual) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.name, historyRecords, actual)
+		}
+	}
+}
+
+func TestHistoryNonFinite(t *testing.T) {
+	// the values of a diverging training
+	records := []HistoryRecord{
+		{Iteration: 1, Cost: math.NaN(), LearningRate: 0.1, Metrics: map[string]float64{"val_cost": math.Inf(1), "accuracy": 0.5}},
+		{Iteration: 2, Cost: math.Inf(-1), LearningRate: 0.1},
+	}
+	var buffer bytes.Buffer
+	if err := WriteHistoryJSONLines(&buffer, records); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := ReadHistoryJSONLines(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 2 || !math.IsNaN(actual[0].Cost) || !math.IsInf(actual[0].Metrics["val_cost"], 1) ||
+		actual[0].Metrics["accuracy"] != 0.5 || !math.IsInf(actual[1].Cost, -1) || actual[1].LearningRate != 0.1 {
+		t.Errorf("Expected: %v, Actual: %v\n", records, actual)
+	}
+}
+
+func TestSaveLoadHistory(t *testing.T) {
+	dir := t.TempDir()
+	for _, filename := range []string{"history.jsonl", "history.csv"} {
+		history := &History{Records: historyRecords}
+		path := filepath.Join(dir, filename)
+		if err := history.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		actual, err := LoadHistory(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(historyRecords, actual) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", filename, historyRecords, actual)
+		}
+	}
+}
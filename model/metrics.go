@@ -0,0 +1,160 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Names of the metrics that can be evaluated on a dataset
+const (
+	// Cross-entropy loss, lower is better
+	MetricCost string = "cost"
+	// Fraction of examples correctly classified, higher is better
+	MetricAccuracy string = "accuracy"
+)
+
+// Accuracy returns the fraction of examples in X whose prediction matches the
+// desired output in Y
+func Accuracy(parameters *Parameters, X, Y matrix.NumberArray) float64 {
+	A2, _ := forwardPropagation(parameters, X)
+	return accuracy(A2, Y)
+}
+
+// PredictProbabilities returns the output of the network for every example of
+// X, i.e. the probability of each example belonging to the class 1
+func PredictProbabilities(parameters *Parameters, X matrix.NumberArray) matrix.NumberArray {
+	A2, _ := forwardPropagation(parameters, X)
+	return A2
+}
+
+// PredictProbabilitiesContext returns the output of the network like
+// PredictProbabilities, running the forward pass over chunks of chunkSize
+// examples and stopping with the error of the context as soon as it's done,
+// e.g. when its deadline is exceeded or it's cancelled
+func PredictProbabilitiesContext(ctx context.Context, parameters *Parameters, X matrix.NumberArray, chunkSize int) (matrix.NumberArray, error) {
+	m := X.GetColumns()
+	if chunkSize < 1 {
+		chunkSize = m
+	}
+	probabilities, err := matrix.NewMatrix(1, m)
+	if err != nil {
+		return nil, err
+	}
+	for from := 0; from < m; from += chunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		to := from + chunkSize
+		if to > m {
+			to = m
+		}
+		chunk, err := matrix.SliceColumns(X, from, to)
+		if err != nil {
+			return nil, err
+		}
+		A2, _ := forwardPropagation(parameters, chunk)
+		for j := from; j < to; j++ {
+			value, _ := A2.GetValue(0, j-from)
+			probabilities.SetValue(0, j, value)
+		}
+	}
+	return probabilities, nil
+}
+
+// ClassMetrics are the metrics of the predictions of a single class
+type ClassMetrics struct {
+	// Class the metrics are about, either 0 or 1
+	Class int `json:"class"`
+	// Fraction of the examples predicted as the class that belong to it
+	Precision float64 `json:"precision"`
+	// Fraction of the examples of the class that are predicted as such
+	Recall float64 `json:"recall"`
+	// Harmonic mean of the precision and the recall
+	F1Score float64 `json:"f1_score"`
+	// Number of examples of the class
+	Support int `json:"support"`
+}
+
+// ClassificationReport returns the metrics of the classes 0 and 1, given the
+// predicted probabilities and the desired outputs Y, where an example is
+// predicted as the class 1 when its probability is above the threshold. The
+// metrics whose denominator is zero are zero
+func ClassificationReport(probabilities, Y matrix.NumberArray, threshold float64) []ClassMetrics {
+	// confusion[desired][predicted]
+	var confusion [2][2]int
+	for j := 0; j < Y.GetColumns(); j++ {
+		yHat, _ := probabilities.GetValue(0, j)
+		y, _ := Y.GetValue(0, j)
+		desired, predicted := 0, 0
+		if y > 0.5 {
+			desired = 1
+		}
+		if yHat > threshold {
+			predicted = 1
+		}
+		confusion[desired][predicted]++
+	}
+	report := make([]ClassMetrics, 2)
+	for class := range report {
+		truePositives := confusion[class][class]
+		predicted := confusion[0][class] + confusion[1][class]
+		support := confusion[class][0] + confusion[class][1]
+		metrics := ClassMetrics{Class: class, Support: support}
+		if predicted > 0 {
+			metrics.Precision = float64(truePositives) / float64(predicted)
+		}
+		if support > 0 {
+			metrics.Recall = float64(truePositives) / float64(support)
+		}
+		if metrics.Precision+metrics.Recall > 0 {
+			metrics.F1Score = 2 * metrics.Precision * metrics.Recall / (metrics.Precision + metrics.Recall)
+		}
+		report[class] = metrics
+	}
+	return report
+}
+
+// Calculates the fraction of outputs that are on the same side of the 0.5
+// threshold as the desired outputs
+func accuracy(A2, Y matrix.NumberArray) float64 {
+	m := Y.GetColumns()
+	correct := 0
+	for j := 0; j < m; j++ {
+		yHat, _ := A2.GetValue(0, j)
+		y, _ := Y.GetValue(0, j)
+		if (yHat > 0.5) == (y > 0.5) {
+			correct++
+		}
+	}
+	return float64(correct) / float64(m)
+}
+
+// Evaluates the metric with the given name using the parameters on the
+// dataset X, Y
+func evaluateMetric(metric string, parameters *Parameters, X, Y matrix.NumberArray) (float64, error) {
+	if err := checkMetric(metric); err != nil {
+		return 0, err
+	}
+	A2, _ := forwardPropagation(parameters, X)
+	if metric == MetricAccuracy {
+		return accuracy(A2, Y), nil
+	}
+	return computeCost(A2, Y), nil
+}
+
+// Returns an error if there's no metric with the given name
+func checkMetric(metric string) error {
+	switch metric {
+	case MetricCost, MetricAccuracy:
+		return nil
+	default:
+		return fmt.Errorf("Can't evaluate the given metric: %v", metric)
+	}
+}
+
+// True when a bigger value of the metric means a better model
+func higherIsBetter(metric string) bool {
+	return metric == MetricAccuracy
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Rounds away the floating point error of the divisions of the metrics
+func roundMetrics(metrics *ClassMetrics) {
+	for _, value := range []*float64{&metrics.Precision, &metrics.Recall, &metrics.F1Score} {
+		*value = math.Round(*value*1e9) / 1e9
+	}
+}
+
+func TestClassificationReport(t *testing.T) {
+	probabilities := newArray([][]float64{{0.9, 0.8, 0.3, 0.6, 0.1, 0.2}})
+	Y := newArray([][]float64{{1, 1, 1, 0, 0, 0}})
+	tables := []struct {
+		threshold float64
+		expected  []ClassMetrics
+	}{
+		{0.5, []ClassMetrics{
+			{Class: 0, Precision: 2.0 / 3, Recall: 2.0 / 3, F1Score: 2.0 / 3, Support: 3},
+			{Class: 1, Precision: 2.0 / 3, Recall: 2.0 / 3, F1Score: 2.0 / 3, Support: 3},
+		}},
+		{0.95, []ClassMetrics{
+			{Class: 0, Precision: 0.5, Recall: 1, F1Score: 2.0 / 3, Support: 3},
+			{Class: 1, Precision: 0, Recall: 0, F1Score: 0, Support: 3},
+		}},
+	}
+	for _, table := range tables {
+		actual := ClassificationReport(probabilities, Y, table.threshold)
+		for i := range actual {
+			roundMetrics(&actual[i])
+			roundMetrics(&table.expected[i])
+		}
+		if !reflect.DeepEqual(table.expected, actual) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expected, actual)
+		}
+	}
+}
+
+func TestPredictProbabilitiesContext(t *testing.T) {
+	X, Y := separableDataset()
+	parameters := Model(X, Y, NewHyperparameters(100, 1.0, 1, 4), X.GetColumns(), X.GetRows(), &BaseCallback{})
+	expected := matrix.ToSlice(PredictProbabilities(parameters, X))
+	for _, chunkSize := range []int{0, 3, 8, 20} {
+		actual, err := PredictProbabilitiesContext(context.Background(), parameters, X, chunkSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, matrix.ToSlice(actual)) {
+			t.Errorf("Expected: %v, Actual: %v\n", expected, matrix.ToSlice(actual))
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := PredictProbabilitiesContext(ctx, parameters, X, 3); err != context.Canceled {
+		t.Errorf("Expected: %v, Actual: %v\n", context.Canceled, err)
+	}
+}
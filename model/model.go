@@ -1,9 +1,10 @@
 package model
 
 import (
-	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"os"
 
 	"github.com/chibby0ne/micro_neural_network/matrix"
 )
@@ -25,6 +26,105 @@ type Hyperparameters struct {
 	learningRate    float64
 	numHiddenLayers int
 	numHiddenUnits  int
+	batchSize       int
+	earlyStopping   *EarlyStopping
+	schedule        LearningRateSchedule
+	shuffle         bool
+	seed            int64
+	transformer     BatchTransformer
+
+	checkpointFile   string
+	checkpointEpochs int
+}
+
+// NewHyperparameters creates the Hyperparameters used to train the model
+func NewHyperparameters(numIterations int, learningRate float64, numHiddenLayers, numHiddenUnits int) *Hyperparameters {
+	hyperparameters := new(Hyperparameters)
+	hyperparameters.numIterations = numIterations
+	hyperparameters.learningRate = learningRate
+	hyperparameters.numHiddenLayers = numHiddenLayers
+	hyperparameters.numHiddenUnits = numHiddenUnits
+	hyperparameters.seed = 1
+	return hyperparameters
+}
+
+// SetShuffle shuffles the training examples at the beginning of every epoch,
+// using a random generator seeded with seed
+func (hyperparameters *Hyperparameters) SetShuffle(seed int64) {
+	hyperparameters.shuffle = true
+	hyperparameters.seed = seed
+}
+
+// SetSeed seeds the random generator used while training for shuffling and
+// transforming the mini-batches
+func (hyperparameters *Hyperparameters) SetSeed(seed int64) {
+	hyperparameters.seed = seed
+}
+
+// BatchTransformer modifies each mini-batch before using it for training,
+// e.g: for data augmentation. The random values must be drawn from random, so
+// that the training can be reproduced and resumed
+type BatchTransformer interface {
+	TransformBatch(X matrix.NumberArray, random *rand.Rand) (matrix.NumberArray, error)
+}
+
+// SetBatchTransformer makes the training transform the inputs of every
+// mini-batch with transformer. The inputs used for evaluating the cost and
+// metrics of the validation set aren't transformed
+func (hyperparameters *Hyperparameters) SetBatchTransformer(transformer BatchTransformer) {
+	hyperparameters.transformer = transformer
+}
+
+// SetCheckpointing saves a checkpoint of the training to filename at the end
+// of every epochs epochs, i.e. passes through the training set, from which the
+// training can be resumed. The checkpoints are only taken between epochs, so
+// a training interrupted in the middle of an epoch resumes from the end of the
+// last checkpointed one. An empty filename disables the checkpoints
+func (hyperparameters *Hyperparameters) SetCheckpointing(filename string, epochs int) {
+	if epochs < 1 {
+		epochs = 1
+	}
+	hyperparameters.checkpointFile = filename
+	hyperparameters.checkpointEpochs = epochs
+}
+
+// SetBatchSize splits the training set into mini-batches of batchSize
+// examples, updating the parameters after each of them. A batchSize of 0 uses
+// the entire training set as a single batch
+func (hyperparameters *Hyperparameters) SetBatchSize(batchSize int) {
+	hyperparameters.batchSize = batchSize
+}
+
+// SetLearningRateSchedule makes the learning rate change every epoch according
+// to schedule, starting from the learning rate of the hyperparameters. A nil
+// schedule keeps the learning rate constant
+func (hyperparameters *Hyperparameters) SetLearningRateSchedule(schedule LearningRateSchedule) {
+	hyperparameters.schedule = schedule
+}
+
+// Returns the learning rate for the given epoch
+func (hyperparameters *Hyperparameters) learningRateAt(epoch int) float64 {
+	if hyperparameters.schedule == nil {
+		return hyperparameters.learningRate
+	}
+	return hyperparameters.schedule.LearningRate(epoch, hyperparameters.learningRate)
+}
+
+// SetEarlyStopping makes the training monitor the validation set given in
+// earlyStopping and stop once it stops improving. A nil earlyStopping trains
+// for the whole numIterations. The metric and the shapes of the validation set
+// are checked against the network before the training starts
+func (hyperparameters *Hyperparameters) SetEarlyStopping(earlyStopping *EarlyStopping) {
+	hyperparameters.earlyStopping = earlyStopping
+}
+
+// Returns an error if the hyperparameters can't train a network with the given
+// number of input features
+func (hyperparameters *Hyperparameters) check(numberFeatures int) error {
+	if hyperparameters.earlyStopping != nil {
+		return hyperparameters.earlyStopping.check(numberFeatures)
+	}
+	return nil
 }
 
 // Cache are values calculated in the forward propagation step that are reused
@@ -52,22 +152,34 @@ func handleError(err error) {
 	}
 }
 
-// InitializeParameters initializes the models parameters (W, B)
-func initializeParameters(hyperparameters *Hyperparameters, numberTrainingExamples int, numberFeatures int) *Parameters {
-	rand.Seed(1)
+// InitializeParameters initializes the models parameters (W, B). The biases
+// are column vectors that get broadcasted to every training example
+func initializeParameters(hyperparameters *Hyperparameters, numberFeatures int) *Parameters {
+	random := rand.New(rand.NewSource(hyperparameters.seed))
 	param := new(Parameters)
 	var err error
-	param.W1, err = matrix.NewRandomMatrix(hyperparameters.numHiddenUnits, numberTrainingExamples, 0.01)
+	param.W1, err = matrix.NewRandomMatrixFrom(random, hyperparameters.numHiddenUnits, numberFeatures, 0.01)
 	handleError(err)
-	param.B1, err = matrix.NewInitializedMatrix(hyperparameters.numHiddenUnits, numberFeatures, 0)
+	param.B1, err = matrix.NewInitializedMatrix(hyperparameters.numHiddenUnits, 1, 0)
 	handleError(err)
-	param.W2, err = matrix.NewRandomMatrix(1, hyperparameters.numHiddenUnits, 0.01)
+	param.W2, err = matrix.NewRandomMatrixFrom(random, 1, hyperparameters.numHiddenUnits, 0.01)
 	handleError(err)
 	param.B2, err = matrix.NewInitializedMatrix(1, 1, 0)
 	handleError(err)
 	return param
 }
 
+// Creates a deep copy of the parameters, so that they are not modified by
+// further updates
+func copyParameters(parameters *Parameters) *Parameters {
+	param := new(Parameters)
+	param.W1 = matrix.Copy(parameters.W1)
+	param.B1 = matrix.Copy(parameters.B1)
+	param.W2 = matrix.Copy(parameters.W2)
+	param.B2 = matrix.Copy(parameters.B2)
+	return param
+}
+
 // One forward propragation step on the entire training set
 func forwardPropagation(parameters *Parameters, X matrix.NumberArray) (A2 matrix.NumberArray, cache *Cache) {
 	W1 := parameters.W1
@@ -78,13 +190,13 @@ func forwardPropagation(parameters *Parameters, X matrix.NumberArray) (A2 matrix
 	var err error
 	W1X, err := matrix.Dot(W1, X)
 	handleError(err)
-	Z1, err := matrix.Add(W1X, B1)
+	Z1, err := matrix.AddColumnVector(W1X, B1)
 	handleError(err)
 	A1 := matrix.Tanh(Z1)
 
 	W2A1, err := matrix.Dot(W2, A1)
 	handleError(err)
-	Z2, err := matrix.Add(W2A1, B2)
+	Z2, err := matrix.AddColumnVector(W2A1, B2)
 	handleError(err)
 	A2 = matrix.Sigmoid(Z2)
 
@@ -128,24 +240,26 @@ func computeCost(A2 matrix.NumberArray, Y matrix.NumberArray) float64 {
 	return val
 }
 
-// One backward propagation step from output to input
+// One backward propagation step from output to input. The arrays are copied
+// before transposing them, given that Transpose modifies the receiver
 func backwardPropagation(parameters *Parameters, cache *Cache, X matrix.NumberArray, Y matrix.NumberArray) *Gradients {
 	m := Y.GetColumns()
 	var err error
 
 	dZ2, err := matrix.Substract(cache.A2, Y)
 	handleError(err)
-	dZ2DotA1T, err := matrix.Dot(dZ2, cache.A1.Transpose())
+	dZ2DotA1T, err := matrix.Dot(dZ2, matrix.Copy(cache.A1).Transpose())
 	handleError(err)
 	dW2 := matrix.MultiplyScalar(dZ2DotA1T, 1.0/float64(m))
 	handleError(err)
 	dB2 := matrix.MultiplyScalar(matrix.SumByColumns(dZ2), 1.0/float64(m)) // along columns
-	W2TDotdZ2, err := matrix.Dot(parameters.W2.Transpose(), dZ2)
+	W2TDotdZ2, err := matrix.Dot(matrix.Copy(parameters.W2).Transpose(), dZ2)
 	handleError(err)
 	dZ1, err := matrix.MultiplyElementwise(W2TDotdZ2, matrix.DerivativeTanh(cache.Z1))
 	handleError(err)
-	dW1, err := matrix.Dot(dZ1, X.Transpose())
+	dZ1DotXT, err := matrix.Dot(dZ1, matrix.Copy(X).Transpose())
 	handleError(err)
+	dW1 := matrix.MultiplyScalar(dZ1DotXT, 1.0/float64(m))
 	dB1 := matrix.MultiplyScalar(matrix.SumByColumns(dZ1), 1.0/float64(m)) // along columns
 
 	grads := new(Gradients)
@@ -158,6 +272,23 @@ func backwardPropagation(parameters *Parameters, cache *Cache, X matrix.NumberAr
 
 }
 
+// Returns the Euclidean norm of the gradient of each parameter, keyed by the
+// name of the parameter
+func gradientNorms(grads *Gradients) map[string]float64 {
+	norms := make(map[string]float64, 4)
+	gradients := map[string]matrix.NumberArray{"W1": grads.dW1, "B1": grads.dB1, "W2": grads.dW2, "B2": grads.dB2}
+	for name, gradient := range gradients {
+		sumSquares := 0.0
+		for _, row := range matrix.ToSlice(gradient) {
+			for _, value := range row {
+				sumSquares += value * value
+			}
+		}
+		norms[name] = math.Sqrt(sumSquares)
+	}
+	return norms
+}
+
 // Updates the parameters according to Gradient descent
 func updateParameters(parameters *Parameters, grads *Gradients, learningRate float64) *Parameters {
 	W1 := parameters.W1
@@ -173,6 +304,7 @@ func updateParameters(parameters *Parameters, grads *Gradients, learningRate flo
 	W2, err = matrix.Substract(W2, matrix.MultiplyScalar(grads.dW2, learningRate))
 	handleError(err)
 	B2, err = matrix.Substract(B2, matrix.MultiplyScalar(grads.dB2, learningRate))
+	handleError(err)
 
 	parameters.W1 = W1
 	parameters.B1 = B1
@@ -182,29 +314,157 @@ func updateParameters(parameters *Parameters, grads *Gradients, learningRate flo
 	return parameters
 }
 
-// Model represents the whole model run the shallow neural network for the number of iterations
-func Model(X, Y matrix.NumberArray, hyperparameters *Hyperparameters, numberTrainingExamples, numberFeatures int) *Parameters {
-	parameters := initializeParameters(hyperparameters, numberTrainingExamples, numberFeatures)
+// Returns the number of mini-batches in which the m training examples are
+// split
+func numBatches(m, batchSize int) int {
+	if batchSize < 1 || batchSize >= m {
+		return 1
+	}
+	return (m + batchSize - 1) / batchSize
+}
 
-	for i := 0; i < hyperparameters.numIterations; i++ {
+// Returns the training examples in a random order given by random
+func shuffleExamples(X, Y matrix.NumberArray, random *rand.Rand) (XShuffled, YShuffled matrix.NumberArray) {
+	permutation := random.Perm(X.GetColumns())
+	var err error
+	XShuffled, err = matrix.SelectColumns(X, permutation)
+	handleError(err)
+	YShuffled, err = matrix.SelectColumns(Y, permutation)
+	handleError(err)
+	return XShuffled, YShuffled
+}
 
-		// Forward prop
-		A2, cache := forwardPropagation(parameters, X)
+// Model represents the whole model run the shallow neural network for the
+// number of iterations, where each iteration is an epoch over the entire
+// training set. X holds one training example per column, and when the
+// hyperparameters have early stopping set, training may end before the number
+// of iterations is reached. The callbacks are invoked throughout the training,
+// and when none are given the cost is printed every 1000 iterations
+func Model(X, Y matrix.NumberArray, hyperparameters *Hyperparameters, numberTrainingExamples, numberFeatures int, callbacks ...Callback) *Parameters {
+	handleError(hyperparameters.check(numberFeatures))
+	parameters := initializeParameters(hyperparameters, numberFeatures)
+	hyperparameters.reset()
+	return train(&arraySource{X: X, Y: Y}, hyperparameters, parameters, newTrainingState(hyperparameters.seed), callbacks)
+}
 
-		// calculate cost
-		cost := computeCost(A2, Y)
+// Clears the state left by a previous training in the early stopping and the
+// learning rate schedule
+func (hyperparameters *Hyperparameters) reset() {
+	if hyperparameters.earlyStopping != nil {
+		hyperparameters.earlyStopping.reset()
+	}
+	if adaptive, ok := hyperparameters.schedule.(AdaptiveSchedule); ok {
+		adaptive.Reset()
+	}
+}
 
-		// backward prop
-		grads := backwardPropagation(parameters, cache, X, Y)
+// Runs the training loop from the given parameters and state until the number
+// of iterations is reached or the training is stopped
+func train(source BatchSource, hyperparameters *Hyperparameters, parameters *Parameters, state *trainingState, callbacks []Callback) *Parameters {
+	if len(callbacks) == 0 {
+		callbacks = []Callback{NewCostPrinter(os.Stdout, 1000)}
+	}
+	callbackList := callbackList(callbacks)
+	earlyStopping := hyperparameters.earlyStopping
+	m := source.NumberExamples()
+	batches := numBatches(m, hyperparameters.batchSize)
+
+	logs := new(Logs)
+	logs.Epoch = state.epoch
+	logs.Iteration = state.iteration
+	logs.Parameters = parameters
+	logs.LearningRate = hyperparameters.learningRateAt(state.epoch)
+	logs.state = state
+	logs.hyperparameters = hyperparameters
+	logs.callbacks = callbackList
+	callbackList.onTrainBegin(logs)
+
+	for epoch := state.epoch; epoch < hyperparameters.numIterations; epoch++ {
+		logs.Epoch = epoch
+		logs.LearningRate = hyperparameters.learningRateAt(epoch)
+		callbackList.onEpochBegin(epoch, logs)
+		epochCost, epochAccuracy := 0.0, 0.0
+
+		epochSource := source
+		var order []int
+		if hyperparameters.shuffle {
+			epochSource, order = shuffleSource(source, batches, state.random)
+		}
 
-		// update params
-		parameters = updateParameters(parameters, grads, hyperparameters.learningRate)
+		for batch := 0; batch < batches; batch++ {
+			index := batch
+			if order != nil {
+				index = order[batch]
+			}
+			XBatch, YBatch := readBatch(epochSource, hyperparameters.batchSize, index)
+			if hyperparameters.transformer != nil {
+				var err error
+				XBatch, err = hyperparameters.transformer.TransformBatch(XBatch, state.random)
+				handleError(err)
+			}
+
+			// Forward prop
+			A2, cache := forwardPropagation(parameters, XBatch)
+
+			// calculate cost
+			cost := computeCost(A2, YBatch)
+			batchAccuracy := accuracy(A2, YBatch)
+
+			// backward prop
+			grads := backwardPropagation(parameters, cache, XBatch, YBatch)
+
+			// update params
+			parameters = updateParameters(parameters, grads, logs.LearningRate)
+
+			// weight the batch by its number of examples for the epoch values
+			weight := float64(YBatch.GetColumns()) / float64(m)
+			epochCost += cost * weight
+			epochAccuracy += batchAccuracy * weight
+
+			logs.Iteration++
+			logs.Batch = batch
+			logs.Cost = cost
+			logs.Metrics = map[string]float64{MetricAccuracy: batchAccuracy}
+			logs.GradientNorms = gradientNorms(grads)
+			logs.Parameters = parameters
+			callbackList.onBatchEnd(batch, logs)
+		}
+
+		logs.Cost = epochCost
+		logs.Metrics = map[string]float64{MetricAccuracy: epochAccuracy}
 
-		// print cost every 1000 iterations
-		if i%1000 == 0 {
-			fmt.Printf("Cost after %v iterations: %v\n", i, cost)
+		// monitor the validation set
+		if earlyStopping != nil {
+			value, evaluated, stop := earlyStopping.update(epoch, parameters)
+			if evaluated {
+				logs.Metrics["val_"+earlyStopping.Metric] = value
+			}
+			logs.StopTraining = logs.StopTraining || stop
 		}
+
+		// adapt the learning rate of the next epoch
+		if adaptive, ok := hyperparameters.schedule.(AdaptiveSchedule); ok {
+			adaptive.Update(epoch, logs)
+		}
+
+		callbackList.onEpochEnd(epoch, logs)
+
+		// save the checkpoint once all the callbacks have seen the epoch
+		if hyperparameters.checkpointFile != "" && (epoch+1)%hyperparameters.checkpointEpochs == 0 {
+			// a checkpoint that can't be saved doesn't stop the training
+			if err := SaveCheckpoint(hyperparameters.checkpointFile, logs.Checkpoint()); err != nil {
+				log.Printf("Can't save the checkpoint: %v", err)
+			}
+		}
+		if logs.StopTraining {
+			break
+		}
+	}
+	if earlyStopping != nil && earlyStopping.RestoreBestParameters && earlyStopping.bestParameters != nil {
+		parameters = copyParameters(earlyStopping.bestParameters)
+		logs.Parameters = parameters
 	}
+	callbackList.onTrainEnd(logs)
 	return parameters
 }
 
@@ -0,0 +1,290 @@
+package model
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/dataset"
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Creates a NumberArray from the given rows
+func newArray(values [][]float64) matrix.NumberArray {
+	m, _ := matrix.NewMatrix(len(values), len(values[0]))
+	for i := range values {
+		for j := range values[i] {
+			m.SetValue(i, j, values[i][j])
+		}
+	}
+	return m
+}
+
+// Linearly separable dataset with 2 features and 8 examples, one per column,
+// whose label is 1 when the first feature is positive
+func separableDataset() (X, Y matrix.NumberArray) {
+	X = newArray([][]float64{
+		{-2, -1.5, -1, -0.5, 0.5, 1, 1.5, 2},
+		{1, -1, 0.5, -0.5, 0.5, -0.5, 1, -1},
+	})
+	Y = newArray([][]float64{{0, 0, 0, 0, 1, 1, 1, 1}})
+	return X, Y
+}
+
+func TestModel(t *testing.T) {
+	X, Y := separableDataset()
+	hyperparameters := NewHyperparameters(500, 1.0, 1, 4)
+	parameters := Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows())
+	if actual := Accuracy(parameters, X, Y); actual != 1 {
+		t.Errorf("Expected: %v, Actual: %v\n", 1, actual)
+	}
+	// a single example must be predicted using the broadcasted biases
+	tables := []struct {
+		input    matrix.NumberArray
+		expected bool
+	}{
+		{newArray([][]float64{{3}, {0}}), true},
+		{newArray([][]float64{{-3}, {0}}), false},
+	}
+	for _, table := range tables {
+		if actual := Predict(parameters, table.input); table.expected != actual {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expected, actual)
+		}
+	}
+}
+
+func TestModelImageFolder(t *testing.T) {
+	// bright and dark images of 2x2 pixels with a bit of variation
+	root := t.TempDir()
+	for class, base := range map[string]int{"bright": 200, "dark": 20} {
+		if err := os.MkdirAll(filepath.Join(root, class), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for example := 0; example < 4; example++ {
+			img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+			for x := 0; x < 2; x++ {
+				for y := 0; y < 2; y++ {
+					value := uint8(base + example*10 + x*5 + y*3)
+					img.Set(x, y, color.RGBA{value, value / 2, 255 - value, 255})
+				}
+			}
+			f, err := os.Create(filepath.Join(root, class, fmt.Sprintf("%v.png", example)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = png.Encode(f, img)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	X, Y, _, err := dataset.LoadImageFolder(root, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	X = matrix.MultiplyScalar(X, 1.0/255)
+	hyperparameters := NewHyperparameters(500, 1.0, 1, 4)
+	parameters := Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), &BaseCallback{})
+	if parameters.W1.GetColumns() != 12 {
+		t.Errorf("Expected: %v input features, Actual: %v\n", 12, parameters.W1.GetColumns())
+	}
+	if actual := Accuracy(parameters, X, Y); actual != 1 {
+		t.Errorf("Expected: %v, Actual: %v\n", 1, actual)
+	}
+}
+
+func TestEarlyStopping(t *testing.T) {
+	X, Y := separableDataset()
+	// the validation labels are the opposite of the training ones, therefore
+	// the validation cost only gets worse as the training goes on
+	XValidation, _ := separableDataset()
+	YValidation := newArray([][]float64{{1, 1, 1, 1, 0, 0, 0, 0}})
+	tables := []struct {
+		metric           string
+		frequency        int
+		patience         int
+		expectedStop     int
+		expectedBestIter int
+	}{
+		{MetricCost, 1, 3, 3, 0},
+		{MetricCost, 5, 2, 14, 4},
+		{MetricCost, 1, 1000, -1, 0},
+	}
+	for _, table := range tables {
+		earlyStopping := NewEarlyStopping(XValidation, YValidation, table.metric, table.patience)
+		earlyStopping.Frequency = table.frequency
+		hyperparameters := NewHyperparameters(100, 1.0, 1, 4)
+		hyperparameters.SetEarlyStopping(earlyStopping)
+		parameters := Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows())
+
+		if actual := earlyStopping.StoppedIteration(); table.expectedStop != actual {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedStop, actual)
+		}
+		best, bestIteration := earlyStopping.Best()
+		if table.expectedBestIter != bestIteration {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedBestIter, bestIteration)
+		}
+		// the returned parameters are the ones of the best iteration
+		actual, _ := evaluateMetric(table.metric, parameters, XValidation, YValidation)
+		if math.Abs(best-actual) > 1e-12 {
+			t.Errorf("Expected: %v, Actual: %v\n", best, actual)
+		}
+	}
+}
+
+func TestEarlyStoppingImproves(t *testing.T) {
+	tables := []struct {
+		metric   string
+		best     float64
+		minDelta float64
+		value    float64
+		expected bool
+	}{
+		{MetricCost, 0.5, 0, 0.4, true},
+		{MetricCost, 0.5, 0, 0.6, false},
+		{MetricCost, 0.5, 0.2, 0.4, false},
+		{MetricAccuracy, 0.5, 0, 0.6, true},
+		{MetricAccuracy, 0.5, 0, 0.4, false},
+		{MetricAccuracy, 0.5, 0.2, 0.6, false},
+	}
+	for _, table := range tables {
+		earlyStopping := &EarlyStopping{Metric: table.metric, MinDelta: table.minDelta, best: table.best}
+		if actual := earlyStopping.improves(table.value); table.expected != actual {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expected, actual)
+		}
+	}
+}
+
+func TestEarlyStoppingCheck(t *testing.T) {
+	X, Y := separableDataset()
+	tables := []struct {
+		X             matrix.NumberArray
+		Y             matrix.NumberArray
+		metric        string
+		expectedError error
+	}{
+		{X, Y, MetricAccuracy, nil},
+		{X, Y, "acuracy", fmt.Errorf("Can't evaluate the given metric: acuracy")},
+		{nil, Y, MetricCost, fmt.Errorf("Can't monitor a training without a validation set")},
+		{newArray([][]float64{{1, 2}}), newArray([][]float64{{0, 1}}), MetricCost, fmt.Errorf("Can't monitor a network of 2 features with a validation set of 1 features")},
+		{X, newArray([][]float64{{0, 1}}), MetricCost, fmt.Errorf("Can't monitor a validation set of 8 examples with desired outputs of shape 1x2")},
+	}
+	for _, table := range tables {
+		hyperparameters := NewHyperparameters(10, 1.0, 1, 4)
+		hyperparameters.SetEarlyStopping(NewEarlyStopping(table.X, table.Y, table.metric, 1))
+		err := hyperparameters.check(X.GetRows())
+		if (err == nil) != (table.expectedError == nil) || (err != nil && err.Error() != table.expectedError.Error()) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+		// Resume returns the error before training
+		if table.expectedError == nil {
+			continue
+		}
+		checkpoint := &Checkpoint{Parameters: initializeParameters(hyperparameters, X.GetRows())}
+		if _, err := Resume(checkpoint, X, Y, hyperparameters, &BaseCallback{}); err == nil || err.Error() != table.expectedError.Error() {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+	}
+	// the folds replace the validation set but not the metric
+	hyperparameters := NewHyperparameters(10, 1.0, 1, 4)
+	hyperparameters.SetEarlyStopping(NewEarlyStopping(nil, nil, "acuracy", 1))
+	if _, err := CrossValidate(X, Y, hyperparameters, 2, 1, &BaseCallback{}); err == nil || err.Error() != tables[1].expectedError.Error() {
+		t.Errorf("Expected: %v, Actual: %v\n", tables[1].expectedError, err)
+	}
+}
+
+func TestCrossValidate(t *testing.T) {
+	X, Y := separableDataset()
+	hyperparameters := NewHyperparameters(300, 1.0, 1, 4)
+	result, err := CrossValidate(X, Y, hyperparameters, 4, 1, &BaseCallback{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Folds) != 4 {
+		t.Fatalf("Expected: %v, Actual: %v\n", 4, len(result.Folds))
+	}
+	if actual := result.Mean[MetricAccuracy]; actual != 1 {
+		t.Errorf("Expected: %v, Actual: %v\n", 1, actual)
+	}
+	if actual := result.Std[MetricAccuracy]; actual != 0 {
+		t.Errorf("Expected: %v, Actual: %v\n", 0, actual)
+	}
+	if _, ok := result.Mean[MetricCost]; !ok {
+		t.Errorf("Expected the mean of %v in %v\n", MetricCost, result.Mean)
+	}
+}
+
+func TestCrossValidateFoldHyperparameters(t *testing.T) {
+	X, Y := separableDataset()
+	hyperparameters := NewHyperparameters(20, 1.0, 1, 4)
+	earlyStopping := NewEarlyStopping(X, Y, MetricCost, 5)
+	hyperparameters.SetEarlyStopping(earlyStopping)
+	schedule := NewReduceOnPlateau(0.5, 2)
+	hyperparameters.SetLearningRateSchedule(schedule)
+	directory := t.TempDir()
+	hyperparameters.SetCheckpointing(filepath.Join(directory, "checkpoint.json"), 10)
+	if _, err := CrossValidate(X, Y, hyperparameters, 2, 1, &BaseCallback{}); err != nil {
+		t.Fatal(err)
+	}
+	// the state of the caller's hyperparameters isn't touched
+	if earlyStopping.X != X || earlyStopping.bestParameters != nil || schedule.Started {
+		t.Errorf("Expected the early stopping and schedule to be unused, Actual: %+v and %+v\n", earlyStopping, schedule)
+	}
+	for _, name := range []string{"checkpoint.fold1.json", "checkpoint.fold2.json"} {
+		checkpoint, err := LoadCheckpoint(filepath.Join(directory, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the early stopping monitored the examples left out of the fold
+		if checkpoint.EarlyStopping == nil {
+			t.Errorf("Expected the state of the early stopping in %v\n", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(directory, "checkpoint.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no checkpoint shared by the folds, Actual: %v\n", err)
+	}
+}
+
+// BatchTransformer that counts the mini-batches and examples it transforms
+type countingTransformer struct {
+	batches, examples int
+}
+
+func (transformer *countingTransformer) TransformBatch(X matrix.NumberArray, random *rand.Rand) (matrix.NumberArray, error) {
+	transformer.batches++
+	transformer.examples += X.GetColumns()
+	return matrix.MultiplyScalar(X, 1+random.Float64()*0.01), nil
+}
+
+func TestBatchTransformer(t *testing.T) {
+	X, Y := separableDataset()
+	transformer := new(countingTransformer)
+	hyperparameters := NewHyperparameters(3, 1.0, 1, 4)
+	hyperparameters.SetBatchSize(3)
+	hyperparameters.SetBatchTransformer(transformer)
+	Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), &BaseCallback{})
+	if transformer.batches != 9 || transformer.examples != 24 {
+		t.Errorf("Expected: 9 batches and 24 examples, Actual: %v and %v\n", transformer.batches, transformer.examples)
+	}
+}
+
+func TestGradientNorms(t *testing.T) {
+	grads := &Gradients{
+		dW1: newArray([][]float64{{3, 4}, {0, 0}}),
+		dB1: newArray([][]float64{{1}, {-1}}),
+		dW2: newArray([][]float64{{0, 2}}),
+		dB2: newArray([][]float64{{-0.5}}),
+	}
+	expected := map[string]float64{"W1": 5, "B1": math.Sqrt(2), "W2": 2, "B2": 0.5}
+	if actual := gradientNorms(grads); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, actual)
+	}
+}
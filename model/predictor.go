@@ -0,0 +1,210 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+// Predictor predicts with a private copy of the parameters of a model, so a
+// single Predictor can be used from many goroutines at once, even while the
+// parameters it was created from keep being trained. Unlike the forward pass
+// of the training, the inputs are checked and errors are returned instead of
+// stopping the program
+type Predictor struct {
+	parameters *Parameters
+	// Applied to the inputs before predicting, may be nil. It's only read,
+	// so it mustn't be fitted again while the Predictor is in use
+	pipeline preprocessing.Pipeline
+}
+
+// NewPredictor creates a Predictor of the model with the given parameters and
+// preprocessing, e.g. the ones returned by LoadModel
+func NewPredictor(parameters *Parameters, pipeline preprocessing.Pipeline) *Predictor {
+	return &Predictor{parameters: copyParameters(parameters), pipeline: pipeline}
+}
+
+// NumberFeatures returns the number of features of the inputs of the model,
+// before the preprocessing
+func (predictor *Predictor) NumberFeatures() int {
+	return predictor.pipeline.InputFeatures(predictor.parameters.W1.GetColumns())
+}
+
+// Predict returns the probability of each example of X, with one example per
+// column, belonging to the class 1
+func (predictor *Predictor) Predict(X matrix.NumberArray) (matrix.NumberArray, error) {
+	return predictor.PredictContext(context.Background(), X, 0)
+}
+
+// PredictContext returns the probabilities like Predict, running the forward
+// pass over chunks of chunkSize examples and stopping with the error of the
+// context as soon as it's done
+func (predictor *Predictor) PredictContext(ctx context.Context, X matrix.NumberArray, chunkSize int) (matrix.NumberArray, error) {
+	if X.GetRows() != predictor.NumberFeatures() {
+		return nil, fmt.Errorf("Can't predict examples with %v features, the model has %v", X.GetRows(), predictor.NumberFeatures())
+	}
+	if predictor.pipeline != nil {
+		var err error
+		if X, err = predictor.pipeline.Transform(X); err != nil {
+			return nil, err
+		}
+		if X.GetRows() != predictor.parameters.W1.GetColumns() {
+			return nil, fmt.Errorf("Can't predict the %v features output by the preprocessing, the network has %v inputs", X.GetRows(), predictor.parameters.W1.GetColumns())
+		}
+	}
+	return PredictProbabilitiesContext(ctx, predictor.parameters, X, chunkSize)
+}
+
+// PredictExample returns the probability of a single example, given by its
+// feature vector, belonging to the class 1
+func (predictor *Predictor) PredictExample(features []float64) (float64, error) {
+	probabilities, err := predictor.Predict(exampleColumns([][]float64{features}))
+	if err != nil {
+		return 0, err
+	}
+	probability, _ := probabilities.GetValue(0, 0)
+	return probability, nil
+}
+
+// Returns a matrix with the feature vectors of the examples as columns. All
+// the vectors must have the same length
+func exampleColumns(examples [][]float64) matrix.NumberArray {
+	X, _ := matrix.NewMatrix(len(examples[0]), len(examples))
+	for j, features := range examples {
+		for i, value := range features {
+			X.SetValue(i, j, value)
+		}
+	}
+	return X
+}
+
+// Prediction request of a single example waiting in a Batcher
+type batchRequest struct {
+	features []float64
+	// receives the result once the batch of the request is predicted. It's
+	// buffered so the batcher never waits for a request that was abandoned
+	result chan batchResult
+}
+
+// Result of a batchRequest
+type batchResult struct {
+	probability float64
+	err         error
+}
+
+// Batcher groups the single examples predicted concurrently by many
+// goroutines into batches, so that a single forward pass over a matrix
+// predicts all of them. A batch is predicted once it has maxBatchSize
+// examples or maxLatency after its first example arrived, whichever happens
+// first, so no example waits more than maxLatency for its batch to start
+type Batcher struct {
+	predictor    *Predictor
+	maxBatchSize int
+	maxLatency   time.Duration
+	requests     chan batchRequest
+	done         chan struct{}
+	closeOnce    sync.Once
+	stopped      sync.WaitGroup
+}
+
+// NewBatcher creates a Batcher predicting with the predictor, and starts
+// gathering batches until it's closed
+func NewBatcher(predictor *Predictor, maxBatchSize int, maxLatency time.Duration) *Batcher {
+	if maxBatchSize < 1 {
+		maxBatchSize = 1
+	}
+	batcher := &Batcher{
+		predictor:    predictor,
+		maxBatchSize: maxBatchSize,
+		maxLatency:   maxLatency,
+		requests:     make(chan batchRequest),
+		done:         make(chan struct{}),
+	}
+	batcher.stopped.Add(1)
+	go batcher.run()
+	return batcher
+}
+
+// Predict returns the probability of the example, given by its feature
+// vector, belonging to the class 1. It waits for the batch of the example to
+// be predicted, unless the context is done before
+func (batcher *Batcher) Predict(ctx context.Context, features []float64) (float64, error) {
+	if len(features) != batcher.predictor.NumberFeatures() {
+		return 0, fmt.Errorf("Can't predict an example with %v features, the model has %v", len(features), batcher.predictor.NumberFeatures())
+	}
+	// copied since the caller may reuse the vector once its context is done
+	request := batchRequest{features: append([]float64(nil), features...), result: make(chan batchResult, 1)}
+	select {
+	case batcher.requests <- request:
+	case <-batcher.done:
+		return 0, fmt.Errorf("Can't predict with a closed batcher")
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	select {
+	case result := <-request.result:
+		return result.probability, result.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Close stops gathering batches once the current one is predicted. Predict
+// fails after Close
+func (batcher *Batcher) Close() {
+	batcher.closeOnce.Do(func() {
+		close(batcher.done)
+	})
+	batcher.stopped.Wait()
+}
+
+// Gathers the requests into batches and predicts them until the batcher is
+// closed
+func (batcher *Batcher) run() {
+	defer batcher.stopped.Done()
+	for {
+		var batch []batchRequest
+		select {
+		case request := <-batcher.requests:
+			batch = append(batch, request)
+		case <-batcher.done:
+			return
+		}
+		timer := time.NewTimer(batcher.maxLatency)
+	gather:
+		for len(batch) < batcher.maxBatchSize {
+			select {
+			case request := <-batcher.requests:
+				batch = append(batch, request)
+			case <-timer.C:
+				break gather
+			case <-batcher.done:
+				break gather
+			}
+		}
+		timer.Stop()
+		batcher.predict(batch)
+	}
+}
+
+// Predicts the examples of the batch with a single forward pass and sends
+// each result to its request
+func (batcher *Batcher) predict(batch []batchRequest) {
+	examples := make([][]float64, len(batch))
+	for i, request := range batch {
+		examples[i] = request.features
+	}
+	probabilities, err := batcher.predictor.Predict(exampleColumns(examples))
+	for j, request := range batch {
+		if err != nil {
+			request.result <- batchResult{err: err}
+			continue
+		}
+		probability, _ := probabilities.GetValue(0, j)
+		request.result <- batchResult{probability: probability}
+	}
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+// Transform that leaves the inputs as they are and records the number of
+// examples of each call to Transform, i.e. the size of each forward pass
+type batchSizeRecorder struct {
+	mutex sync.Mutex
+	sizes []int
+}
+
+func (recorder *batchSizeRecorder) Fit(X matrix.NumberArray) error {
+	return nil
+}
+
+func (recorder *batchSizeRecorder) Transform(X matrix.NumberArray) (matrix.NumberArray, error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.sizes = append(recorder.sizes, X.GetColumns())
+	return X, nil
+}
+
+// Returns the feature vector of the example of column j of X
+func column(X matrix.NumberArray, j int) []float64 {
+	features := make([]float64, X.GetRows())
+	for i := range features {
+		features[i], _ = X.GetValue(i, j)
+	}
+	return features
+}
+
+func TestPredictor(t *testing.T) {
+	X, Y := separableDataset()
+	parameters := Model(X, Y, NewHyperparameters(100, 1.0, 1, 4), X.GetColumns(), X.GetRows(), &BaseCallback{})
+	expected := matrix.ToSlice(PredictProbabilities(parameters, X))
+	predictor := NewPredictor(parameters, nil)
+
+	// the predictor keeps its own copy of the parameters
+	parameters.W1.SetValue(0, 0, 100)
+
+	var wait sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			actual, err := predictor.Predict(X)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !reflect.DeepEqual(expected, matrix.ToSlice(actual)) {
+				t.Errorf("Expected: %v, Actual: %v\n", expected, matrix.ToSlice(actual))
+			}
+			for j := 0; j < X.GetColumns(); j++ {
+				probability, err := predictor.PredictExample(column(X, j))
+				if err != nil || probability != expected[0][j] {
+					t.Errorf("Expected: %v, Actual: %v %v\n", expected[0][j], probability, err)
+				}
+			}
+		}()
+	}
+	wait.Wait()
+
+	if _, err := predictor.PredictExample([]float64{1, 2, 3}); err == nil {
+		t.Errorf("Expected an error predicting an example with 3 features\n")
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	X, Y := separableDataset()
+	parameters := Model(X, Y, NewHyperparameters(100, 1.0, 1, 4), X.GetColumns(), X.GetRows(), &BaseCallback{})
+	expected := matrix.ToSlice(PredictProbabilities(parameters, X))
+	tables := []struct {
+		maxBatchSize int
+		maxLatency   time.Duration
+	}{
+		{4, time.Second},
+		{100, 10 * time.Millisecond},
+		{1, time.Second},
+	}
+	for _, table := range tables {
+		recorder := &batchSizeRecorder{}
+		batcher := NewBatcher(NewPredictor(parameters, preprocessing.Pipeline{recorder}), table.maxBatchSize, table.maxLatency)
+		var wait sync.WaitGroup
+		for r := 0; r < 4; r++ {
+			for j := 0; j < X.GetColumns(); j++ {
+				wait.Add(1)
+				go func(j int) {
+					defer wait.Done()
+					probability, err := batcher.Predict(context.Background(), column(X, j))
+					if err != nil || probability != expected[0][j] {
+						t.Errorf("Expected: %v, Actual: %v %v\n", expected[0][j], probability, err)
+					}
+				}(j)
+			}
+		}
+		wait.Wait()
+		batcher.Close()
+
+		total := 0
+		for _, size := range recorder.sizes {
+			if size > table.maxBatchSize {
+				t.Errorf("Expected batches of at most %v examples, Actual: %v\n", table.maxBatchSize, recorder.sizes)
+			}
+			total += size
+		}
+		if total != 4*X.GetColumns() || (table.maxBatchSize > 1 && len(recorder.sizes) == total) {
+			t.Errorf("Expected: %v examples in batches, Actual: %v\n", 4*X.GetColumns(), recorder.sizes)
+		}
+		if _, err := batcher.Predict(context.Background(), column(X, 0)); err == nil {
+			t.Errorf("Expected an error predicting with a closed batcher\n")
+		}
+	}
+}
+
+func TestBatcherContext(t *testing.T) {
+	X, _ := separableDataset()
+	parameters := initializeParameters(NewHyperparameters(1, 1.0, 1, 4), X.GetRows())
+	batcher := NewBatcher(NewPredictor(parameters, nil), 10, time.Hour)
+	defer batcher.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := batcher.Predict(ctx, column(X, 0)); err != context.DeadlineExceeded {
+		t.Errorf("Expected: %v, Actual: %v\n", context.DeadlineExceeded, err)
+	}
+	if _, err := batcher.Predict(context.Background(), []float64{1}); err == nil {
+		t.Errorf("Expected an error predicting an example with 1 feature\n")
+	}
+}
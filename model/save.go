@@ -0,0 +1,176 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+// Version of the format of the models written by SaveModel. Models written
+// before the format was versioned have version 0 and no architecture
+const modelFormatVersion int = 1
+
+// GobExtension is the extension of the model files written in the gob binary
+// format instead of JSON
+const GobExtension string = ".gob"
+
+// Activations of the layers of the network
+const (
+	TanhActivation    string = "tanh"
+	SigmoidActivation string = "sigmoid"
+)
+
+// Layer describes a fully connected layer of the network
+type Layer struct {
+	Units      int    `json:"units"`
+	Activation string `json:"activation"`
+}
+
+// Architecture describes the shape of the network: the number of features of
+// its input and its layers, from the hidden layer to the output layer
+type Architecture struct {
+	Inputs int     `json:"inputs"`
+	Layers []Layer `json:"layers"`
+}
+
+// NewArchitecture returns the architecture of the network with the given
+// parameters
+func NewArchitecture(parameters *Parameters) Architecture {
+	return Architecture{
+		Inputs: parameters.W1.GetColumns(),
+		Layers: []Layer{
+			{Units: parameters.W1.GetRows(), Activation: TanhActivation},
+			{Units: parameters.W2.GetRows(), Activation: SigmoidActivation},
+		},
+	}
+}
+
+// Content of a model file
+type savedModel struct {
+	Version       int                    `json:"version"`
+	Architecture  *Architecture          `json:"architecture,omitempty"`
+	Parameters    *Parameters            `json:"parameters"`
+	Preprocessing preprocessing.Pipeline `json:"preprocessing,omitempty"`
+}
+
+// Returns the parameters and preprocessing of the saved model, checking that
+// its format is known, that the shapes of its parameters match each other and
+// that they match its architecture
+func (saved *savedModel) check() (*Parameters, preprocessing.Pipeline, error) {
+	if saved.Version < 0 || saved.Version > modelFormatVersion {
+		return nil, nil, fmt.Errorf("unknown format version %v, expected at most %v", saved.Version, modelFormatVersion)
+	}
+	if saved.Parameters == nil || saved.Parameters.W1 == nil || saved.Parameters.B1 == nil ||
+		saved.Parameters.W2 == nil || saved.Parameters.B2 == nil {
+		return nil, nil, fmt.Errorf("missing parameters")
+	}
+	// the shapes of the other parameters follow from the hidden units of W1
+	hidden := saved.Parameters.W1.GetRows()
+	shapes := []struct {
+		name       string
+		array      matrix.NumberArray
+		rows, cols int
+	}{
+		{"B1", saved.Parameters.B1, hidden, 1},
+		{"W2", saved.Parameters.W2, 1, hidden},
+		{"B2", saved.Parameters.B2, 1, 1},
+	}
+	for _, shape := range shapes {
+		if shape.array.GetRows() != shape.rows || shape.array.GetColumns() != shape.cols {
+			return nil, nil, fmt.Errorf("parameter %v of shape %vx%v, expected %vx%v", shape.name,
+				shape.array.GetRows(), shape.array.GetColumns(), shape.rows, shape.cols)
+		}
+	}
+	if saved.Architecture != nil {
+		if expected := NewArchitecture(saved.Parameters); !reflect.DeepEqual(*saved.Architecture, expected) {
+			return nil, nil, fmt.Errorf("architecture %+v doesn't match the parameters %+v", *saved.Architecture, expected)
+		}
+	}
+	if err := saved.Preprocessing.Check(); err != nil {
+		return nil, nil, err
+	}
+	return saved.Parameters, saved.Preprocessing, nil
+}
+
+// SaveModel writes the parameters to filename, along with the architecture of
+// the network and the fitted preprocessing that must be applied to the inputs
+// before predicting. The model is written in the gob binary format when
+// filename has the GobExtension, and as JSON otherwise. The file is replaced
+// atomically, so that a model being saved is never read half written, e.g. by
+// a server watching the directory
+func SaveModel(filename string, parameters *Parameters, pipeline preprocessing.Pipeline) error {
+	architecture := NewArchitecture(parameters)
+	saved := savedModel{
+		Version:       modelFormatVersion,
+		Architecture:  &architecture,
+		Parameters:    parameters,
+		Preprocessing: pipeline,
+	}
+	var data []byte
+	if filepath.Ext(filename) == GobExtension {
+		var buffer bytes.Buffer
+		if err := gob.NewEncoder(&buffer).Encode(saved); err != nil {
+			return fmt.Errorf("Can't encode model %v: %v", filename, err)
+		}
+		data = buffer.Bytes()
+	} else {
+		var err error
+		if data, err = json.Marshal(saved); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomically(filename, data, 0644)
+}
+
+// Writes the data to a temporary file of the directory of filename and renames
+// it to filename, so that readers never see a partially written file
+func writeFileAtomically(filename string, data []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Chmod(perm)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// LoadModel reads the parameters and preprocessing written by SaveModel, in
+// the format given by the extension of filename
+func LoadModel(filename string) (*Parameters, preprocessing.Pipeline, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	var saved savedModel
+	if filepath.Ext(filename) == GobExtension {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&saved)
+	} else {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't read model %v: %v", filename, err)
+	}
+	parameters, pipeline, err := saved.check()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't read model %v: %v", filename, err)
+	}
+	return parameters, pipeline, nil
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+func TestSaveLoadModel(t *testing.T) {
+	X, Y := separableDataset()
+	pipeline := preprocessing.Pipeline{preprocessing.NewStandardizer()}
+	XStandardized, err := pipeline.FitTransform(X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameters := Model(XStandardized, Y, NewHyperparameters(10, 1, 1, 3), X.GetColumns(), X.GetRows(), &BaseCallback{})
+
+	for _, name := range []string{"model.json", "model" + GobExtension} {
+		directory := t.TempDir()
+		filename := filepath.Join(directory, name)
+		if err := SaveModel(filename, parameters, pipeline); err != nil {
+			t.Fatal(err)
+		}
+		// no temporary file is left next to the model
+		if files, _ := ioutil.ReadDir(directory); len(files) != 1 || files[0].Name() != name || files[0].Mode().Perm() != 0644 {
+			t.Errorf("%v Expected: only the model file, Actual: %v\n", name, files)
+		}
+		loadedParameters, loadedPipeline, err := LoadModel(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(pipeline, loadedPipeline) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", name, pipeline, loadedPipeline)
+		}
+		arrays := []struct{ expected, actual matrix.NumberArray }{
+			{parameters.W1, loadedParameters.W1},
+			{parameters.B1, loadedParameters.B1},
+			{parameters.W2, loadedParameters.W2},
+			{parameters.B2, loadedParameters.B2},
+		}
+		for _, array := range arrays {
+			if !reflect.DeepEqual(matrix.ToSlice(array.expected), matrix.ToSlice(array.actual)) {
+				t.Errorf("%v Expected: %v, Actual: %v\n", name, array.expected, array.actual)
+			}
+		}
+		// the loaded preprocessing and parameters predict as the original ones
+		input, _ := loadedPipeline.Transform(X)
+		if expected, actual := Accuracy(parameters, XStandardized, Y), Accuracy(loadedParameters, input, Y); expected != actual {
+			t.Errorf("%v Expected: %v, Actual: %v\n", name, expected, actual)
+		}
+	}
+}
+
+func TestLoadModelVersions(t *testing.T) {
+	parameters := `"parameters": {"W1": [[1, 2], [3, 4], [5, 6]], "B1": [[0], [0], [0]], "W2": [[1, 1, 1]], "B2": [[0]]}`
+	tables := []struct {
+		name          string
+		data          string
+		expectedError bool
+	}{
+		{"unversioned", `{` + parameters + `}`, false},
+		{"version 1", `{"version": 1, "architecture": {"inputs": 2, "layers": [{"units": 3, "activation": "tanh"}, {"units": 1, "activation": "sigmoid"}]}, ` + parameters + `}`, false},
+		{"newer version", `{"version": 2, ` + parameters + `}`, true},
+		{"wrong architecture", `{"version": 1, "architecture": {"inputs": 3, "layers": [{"units": 3, "activation": "tanh"}, {"units": 1, "activation": "sigmoid"}]}, ` + parameters + `}`, true},
+		{"wrong activation", `{"version": 1, "architecture": {"inputs": 2, "layers": [{"units": 3, "activation": "relu"}, {"units": 1, "activation": "sigmoid"}]}, ` + parameters + `}`, true},
+		{"missing parameters", `{"version": 1}`, true},
+		// parameters whose shapes don't match W1, with or without architecture
+		{"B1 of wrong rows", `{"parameters": {"W1": [[1, 2], [3, 4], [5, 6]], "B1": [[0], [0]], "W2": [[1, 1, 1]], "B2": [[0]]}}`, true},
+		{"B1 of wrong columns", `{"parameters": {"W1": [[1, 2], [3, 4], [5, 6]], "B1": [[0, 0], [0, 0], [0, 0]], "W2": [[1, 1, 1]], "B2": [[0]]}}`, true},
+		{"W2 of wrong columns", `{"version": 1, "parameters": {"W1": [[1, 2], [3, 4], [5, 6]], "B1": [[0], [0], [0]], "W2": [[1, 1]], "B2": [[0]]}}`, true},
+		{"W2 of wrong rows", `{"parameters": {"W1": [[1, 2], [3, 4], [5, 6]], "B1": [[0], [0], [0]], "W2": [[1, 1, 1], [1, 1, 1]], "B2": [[0]]}}`, true},
+		{"B2 of wrong rows", `{"parameters": {"W1": [[1, 2], [3, 4], [5, 6]], "B1": [[0], [0], [0]], "W2": [[1, 1, 1]], "B2": [[0], [0]]}}`, true},
+		{"B2 of wrong columns", `{"parameters": {"W1": [[1, 2], [3, 4], [5, 6]], "B1": [[0], [0], [0]], "W2": [[1, 1, 1]], "B2": [[0, 0]]}}`, true},
+	}
+	for _, table := range tables {
+		filename := filepath.Join(t.TempDir(), "model.json")
+		if err := ioutil.WriteFile(filename, []byte(table.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		loaded, _, err := LoadModel(filename)
+		if (err != nil) != table.expectedError {
+			t.Errorf("%v Expected error: %v, Actual: %v\n", table.name, table.expectedError, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(NewArchitecture(loaded), Architecture{Inputs: 2, Layers: []Layer{{3, TanhActivation}, {1, SigmoidActivation}}}) {
+			t.Errorf("%v Unexpected architecture: %+v\n", table.name, NewArchitecture(loaded))
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// LearningRateSchedule decides the learning rate used to update the
+// parameters in each epoch
+type LearningRateSchedule interface {
+	// LearningRate returns the learning rate for the given epoch, starting
+	// from the learning rate set in the hyperparameters
+	LearningRate(epoch int, initialLearningRate float64) float64
+}
+
+// AdaptiveSchedule is a LearningRateSchedule that also adapts the learning
+// rate to the values of the training, updated at the end of every epoch. Its
+// state is reset at the beginning of every training
+type AdaptiveSchedule interface {
+	LearningRateSchedule
+	Update(epoch int, logs *Logs)
+	Reset()
+}
+
+// StepDecay multiplies the learning rate by Factor every StepSize epochs
+type StepDecay struct {
+	Factor   float64
+	StepSize int
+}
+
+// NewStepDecay creates a StepDecay schedule
+func NewStepDecay(factor float64, stepSize int) *StepDecay {
+	return &StepDecay{Factor: factor, StepSize: stepSize}
+}
+
+// LearningRate returns initialLearningRate * Factor^floor(epoch / StepSize)
+func (schedule *StepDecay) LearningRate(epoch int, initialLearningRate float64) float64 {
+	if schedule.StepSize < 1 {
+		return initialLearningRate
+	}
+	return initialLearningRate * math.Pow(schedule.Factor, float64(epoch/schedule.StepSize))
+}
+
+// ExponentialDecay multiplies the learning rate by DecayRate every DecaySteps
+// epochs, decaying it continuously in between
+type ExponentialDecay struct {
+	DecayRate  float64
+	DecaySteps int
+}
+
+// NewExponentialDecay creates an ExponentialDecay schedule
+func NewExponentialDecay(decayRate float64, decaySteps int) *ExponentialDecay {
+	return &ExponentialDecay{DecayRate: decayRate, DecaySteps: decaySteps}
+}
+
+// LearningRate returns initialLearningRate * DecayRate^(epoch / DecaySteps)
+func (schedule *ExponentialDecay) LearningRate(epoch int, initialLearningRate float64) float64 {
+	if schedule.DecaySteps < 1 {
+		return initialLearningRate
+	}
+	return initialLearningRate * math.Pow(schedule.DecayRate, float64(epoch)/float64(schedule.DecaySteps))
+}
+
+// InverseTimeDecay divides the learning rate by a factor that grows linearly
+// with the epochs
+type InverseTimeDecay struct {
+	DecayRate  float64
+	DecaySteps int
+}
+
+// NewInverseTimeDecay creates an InverseTimeDecay schedule
+func NewInverseTimeDecay(decayRate float64, decaySteps int) *InverseTimeDecay {
+	return &InverseTimeDecay{DecayRate: decayRate, DecaySteps: decaySteps}
+}
+
+// LearningRate returns initialLearningRate / (1 + DecayRate * epoch / DecaySteps)
+func (schedule *InverseTimeDecay) LearningRate(epoch int, initialLearningRate float64) float64 {
+	if schedule.DecaySteps < 1 {
+		return initialLearningRate
+	}
+	return initialLearningRate / (1 + schedule.DecayRate*float64(epoch)/float64(schedule.DecaySteps))
+}
+
+// CosineAnnealing anneals the learning rate down to MinLearningRate following
+// half a cosine wave during Period epochs, and then restarts it. After each
+// restart the period is multiplied by PeriodMultiplier, i.e: SGDR
+type CosineAnnealing struct {
+	Period           int
+	PeriodMultiplier int
+	MinLearningRate  float64
+}
+
+// NewCosineAnnealing creates a CosineAnnealing schedule with warm restarts
+// every period epochs, growing by periodMultiplier after each restart
+func NewCosineAnnealing(period, periodMultiplier int, minLearningRate float64) *CosineAnnealing {
+	return &CosineAnnealing{Period: period, PeriodMultiplier: periodMultiplier, MinLearningRate: minLearningRate}
+}
+
+// LearningRate returns MinLearningRate + (initialLearningRate -
+// MinLearningRate) * (1 + cos(pi * t / T)) / 2, where t is the number of epochs
+// since the last restart and T the length of the current period
+func (schedule *CosineAnnealing) LearningRate(epoch int, initialLearningRate float64) float64 {
+	if schedule.Period < 1 {
+		return initialLearningRate
+	}
+	multiplier := schedule.PeriodMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	t, period := epoch, schedule.Period
+	for t >= period {
+		t -= period
+		period *= multiplier
+	}
+	cosine := (1 + math.Cos(math.Pi*float64(t)/float64(period))) / 2
+	return schedule.MinLearningRate + (initialLearningRate-schedule.MinLearningRate)*cosine
+}
+
+// LinearWarmup increases the learning rate linearly during the first
+// WarmupEpochs, and then follows the After schedule, or keeps the learning
+// rate constant if it's nil. The epochs seen by After start at 0 after the
+// warmup
+type LinearWarmup struct {
+	WarmupEpochs int
+	After        LearningRateSchedule
+}
+
+// NewLinearWarmup creates a LinearWarmup schedule
+func NewLinearWarmup(warmupEpochs int, after LearningRateSchedule) *LinearWarmup {
+	return &LinearWarmup{WarmupEpochs: warmupEpochs, After: after}
+}
+
+// LearningRate returns initialLearningRate * (epoch + 1) / WarmupEpochs during
+// the warmup
+func (schedule *LinearWarmup) LearningRate(epoch int, initialLearningRate float64) float64 {
+	if epoch < schedule.WarmupEpochs {
+		return initialLearningRate * float64(epoch+1) / float64(schedule.WarmupEpochs)
+	}
+	if schedule.After == nil {
+		return initialLearningRate
+	}
+	return schedule.After.LearningRate(epoch-schedule.WarmupEpochs, initialLearningRate)
+}
+
+// Update forwards the values of the training to the After schedule if it's
+// adaptive
+func (schedule *LinearWarmup) Update(epoch int, logs *Logs) {
+	if adaptive, ok := schedule.After.(AdaptiveSchedule); ok && epoch >= schedule.WarmupEpochs {
+		adaptive.Update(epoch-schedule.WarmupEpochs, logs)
+	}
+}
+
+// Reset resets the After schedule if it's adaptive
+func (schedule *LinearWarmup) Reset() {
+	if adaptive, ok := schedule.After.(AdaptiveSchedule); ok {
+		adaptive.Reset()
+	}
+}
+
+// ReduceOnPlateau multiplies the learning rate by Factor once the monitored
+// value hasn't improved for Patience epochs
+type ReduceOnPlateau struct {
+	// Name of the monitored value, MetricCost for the training cost or any of
+	// the metrics in the logs, e.g: "val_cost"
+	Monitor string
+	Factor  float64
+	// Number of epochs without improvement before reducing the learning rate
+	Patience int
+	// Minimum change of the monitored value that is considered an improvement
+	MinDelta float64
+	// Number of epochs to wait after a reduction before monitoring again
+	Cooldown int
+	// The learning rate is never reduced below MinLearningRate
+	MinLearningRate float64
+
+	// State of the schedule. Scale is the factor applied to the initial
+	// learning rate
+	Scale             float64
+	Best              float64
+	Wait              int
+	CooldownRemaining int
+	Started           bool
+}
+
+// NewReduceOnPlateau creates a ReduceOnPlateau schedule that monitors the
+// training cost
+func NewReduceOnPlateau(factor float64, patience int) *ReduceOnPlateau {
+	return &ReduceOnPlateau{Monitor: MetricCost, Factor: factor, Patience: patience}
+}
+
+// LearningRate returns the initial learning rate reduced as many times as
+// plateaus were found, but not lower than MinLearningRate
+func (schedule *ReduceOnPlateau) LearningRate(epoch int, initialLearningRate float64) float64 {
+	if !schedule.Started {
+		return initialLearningRate
+	}
+	return math.Max(initialLearningRate*schedule.Scale, schedule.MinLearningRate)
+}
+
+// Update checks whether the monitored value improved in this epoch, and
+// reduces the learning rate if it has reached a plateau
+func (schedule *ReduceOnPlateau) Update(epoch int, logs *Logs) {
+	value, ok := logs.Cost, true
+	if schedule.Monitor != "" && schedule.Monitor != MetricCost {
+		value, ok = logs.Metrics[schedule.Monitor]
+	}
+	if !ok {
+		return
+	}
+	sign := 1.0
+	if higherIsBetter(strings.TrimPrefix(schedule.Monitor, "val_")) {
+		sign = -1.0
+	}
+	if !schedule.Started {
+		schedule.Started = true
+		schedule.Scale = 1
+		schedule.Best = sign * value
+		return
+	}
+	if schedule.CooldownRemaining > 0 {
+		schedule.CooldownRemaining--
+	}
+	// the values are negated when higher is better, so lower is always better
+	if sign*value < schedule.Best-schedule.MinDelta {
+		schedule.Best = sign * value
+		schedule.Wait = 0
+		return
+	}
+	if schedule.CooldownRemaining > 0 {
+		return
+	}
+	schedule.Wait++
+	if schedule.Wait >= schedule.Patience {
+		schedule.Scale *= schedule.Factor
+		schedule.Wait = 0
+		schedule.CooldownRemaining = schedule.Cooldown
+	}
+}
+
+// MarshalJSON writes the schedule as a JSON object, with a non-finite best
+// value as a string
+func (schedule *ReduceOnPlateau) MarshalJSON() ([]byte, error) {
+	type plain ReduceOnPlateau
+	return json.Marshal(struct {
+		*plain
+		Best jsonFloat
+	}{(*plain)(schedule), jsonFloat(schedule.Best)})
+}
+
+// UnmarshalJSON reads the schedule written by MarshalJSON
+func (schedule *ReduceOnPlateau) UnmarshalJSON(data []byte) error {
+	type plain ReduceOnPlateau
+	decoded := struct {
+		*plain
+		Best jsonFloat
+	}{plain: (*plain)(schedule)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	schedule.Best = float64(decoded.Best)
+	return nil
+}
+
+// Reset clears the state of the schedule, so that the learning rate starts
+// from the initial one
+func (schedule *ReduceOnPlateau) Reset() {
+	schedule.Scale = 0
+	schedule.Best = 0
+	schedule.Wait = 0
+	schedule.CooldownRemaining = 0
+	schedule.Started = false
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLearningRateSchedules(t *testing.T) {
+	tables := []struct {
+		name     string
+		schedule LearningRateSchedule
+		epochs   []int
+		expected []float64
+	}{
+		{"step", NewStepDecay(0.5, 2), []int{0, 1, 2, 3, 4}, []float64{1, 1, 0.5, 0.5, 0.25}},
+		{"exponential", NewExponentialDecay(0.5, 2), []int{0, 1, 2, 4}, []float64{1, math.Sqrt(0.5), 0.5, 0.25}},
+		{"inverse time", NewInverseTimeDecay(1, 2), []int{0, 2, 4}, []float64{1, 0.5, 1.0 / 3}},
+		{"cosine", NewCosineAnnealing(4, 1, 0), []int{0, 2, 4, 6}, []float64{1, 0.5, 1, 0.5}},
+		{"cosine multiplier", NewCosineAnnealing(2, 2, 0.2), []int{0, 1, 2, 4, 6}, []float64{1, 0.6, 1, 0.6, 1}},
+		{"warmup", NewLinearWarmup(4, nil), []int{0, 1, 3, 10}, []float64{0.25, 0.5, 1, 1}},
+		{"warmup then step", NewLinearWarmup(2, NewStepDecay(0.1, 1)), []int{0, 1, 2, 3}, []float64{0.5, 1, 1, 0.1}},
+	}
+	for _, table := range tables {
+		for i, epoch := range table.epochs {
+			actual := table.schedule.LearningRate(epoch, 1)
+			if math.Abs(table.expected[i]-actual) > 1e-12 {
+				t.Errorf("%v epoch %v Expected: %v, Actual: %v\n", table.name, epoch, table.expected[i], actual)
+			}
+		}
+	}
+}
+
+func TestReduceOnPlateau(t *testing.T) {
+	schedule := NewReduceOnPlateau(0.5, 2)
+	schedule.Cooldown = 2
+	schedule.MinLearningRate = 0.2
+	costs := []float64{1, 0.9, 0.95, 0.92, 0.91, 0.93, 0.94, 0.96, 0.99, 0.98}
+	expected := []float64{1, 1, 1, 0.5, 0.5, 0.5, 0.25, 0.25, 0.25, 0.2}
+	for epoch, cost := range costs {
+		schedule.Update(epoch, &Logs{Cost: cost})
+		if actual := schedule.LearningRate(epoch+1, 1); expected[epoch] != actual {
+			t.Errorf("Epoch %v Expected: %v, Actual: %v\n", epoch, expected[epoch], actual)
+		}
+	}
+}
+
+func TestModelLearningRateSchedule(t *testing.T) {
+	X, Y := separableDataset()
+	history := NewHistory(false)
+	hyperparameters := NewHyperparameters(4, 1, 1, 4)
+	hyperparameters.SetLearningRateSchedule(NewStepDecay(0.1, 2))
+	Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), history)
+	expected := []float64{1, 1, 0.1, 0.1}
+	for i, record := range history.Records {
+		if math.Abs(expected[i]-record.LearningRate) > 1e-12 {
+			t.Errorf("Epoch %v Expected: %v, Actual: %v\n", i, expected[i], record.LearningRate)
+		}
+	}
+}
+
+func TestModelResetsSchedule(t *testing.T) {
+	X, Y := separableDataset()
+	schedule := NewReduceOnPlateau(0.5, 1)
+	// the state left by a previous training that reduced the learning rate
+	schedule.Update(0, &Logs{Cost: 1})
+	schedule.Update(1, &Logs{Cost: 2})
+	if actual := schedule.LearningRate(2, 1); actual != 0.5 {
+		t.Fatalf("Expected: %v, Actual: %v\n", 0.5, actual)
+	}
+	hyperparameters := NewHyperparameters(1, 1, 1, 4)
+	hyperparameters.SetLearningRateSchedule(NewLinearWarmup(0, schedule))
+	history := NewHistory(false)
+	Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), history)
+	if actual := history.Records[0].LearningRate; actual != 1 {
+		t.Errorf("Expected: %v, Actual: %v\n", 1, actual)
+	}
+}
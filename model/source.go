@@ -0,0 +1,84 @@
+package model
+
+import (
+	"math/rand"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// BatchSource provides the training examples in mini-batches, so that the
+// training set doesn't need to be held in memory as a whole, e.g. when it's
+// read from disk one batch at a time
+type BatchSource interface {
+	// NumberExamples returns the number of training examples
+	NumberExamples() int
+	// Batch returns the inputs, with one example per column, and the desired
+	// outputs of the examples from index from up to but not including to
+	Batch(from, to int) (X, Y matrix.NumberArray, err error)
+}
+
+// Training set held in memory
+type arraySource struct {
+	X matrix.NumberArray
+	Y matrix.NumberArray
+}
+
+// NumberExamples returns the number of columns of X
+func (source *arraySource) NumberExamples() int {
+	return source.X.GetColumns()
+}
+
+// Batch returns the columns from up to to of X and Y
+func (source *arraySource) Batch(from, to int) (X, Y matrix.NumberArray, err error) {
+	if from == 0 && to == source.X.GetColumns() {
+		return source.X, source.Y, nil
+	}
+	if X, err = matrix.SliceColumns(source.X, from, to); err != nil {
+		return nil, nil, err
+	}
+	if Y, err = matrix.SliceColumns(source.Y, from, to); err != nil {
+		return nil, nil, err
+	}
+	return X, Y, nil
+}
+
+// Returns the training examples of the epoch in a random order given by
+// random. Examples held in memory are shuffled individually, while for any
+// other source only the order in which the batches are read is shuffled, as
+// gathering single examples would defeat reading them in contiguous batches.
+// order is nil when the batches are read in order
+func shuffleSource(source BatchSource, batches int, random *rand.Rand) (shuffled BatchSource, order []int) {
+	if memory, ok := source.(*arraySource); ok {
+		X, Y := shuffleExamples(memory.X, memory.Y, random)
+		return &arraySource{X: X, Y: Y}, nil
+	}
+	return source, random.Perm(batches)
+}
+
+// Returns the inputs and desired outputs of the given mini-batch of the
+// source. The whole training set is a single batch when batchSize isn't set
+func readBatch(source BatchSource, batchSize, batch int) (XBatch, YBatch matrix.NumberArray) {
+	m := source.NumberExamples()
+	from, to := 0, m
+	if batchSize >= 1 && batchSize < m {
+		from = batch * batchSize
+		to = from + batchSize
+		if to > m {
+			to = m
+		}
+	}
+	XBatch, YBatch, err := source.Batch(from, to)
+	handleError(err)
+	return XBatch, YBatch
+}
+
+// ModelFromSource trains the model like Model, reading the training examples
+// from the source one mini-batch at a time. The batch size of the
+// hyperparameters should be set, otherwise the whole source is read as a
+// single batch
+func ModelFromSource(source BatchSource, hyperparameters *Hyperparameters, numberFeatures int, callbacks ...Callback) *Parameters {
+	handleError(hyperparameters.check(numberFeatures))
+	parameters := initializeParameters(hyperparameters, numberFeatures)
+	hyperparameters.reset()
+	return train(source, hyperparameters, parameters, newTrainingState(hyperparameters.seed), callbacks)
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// BatchSource that records the ranges of the batches that are read
+type recordingSource struct {
+	arraySource
+	reads [][2]int
+}
+
+func (source *recordingSource) Batch(from, to int) (X, Y matrix.NumberArray, err error) {
+	source.reads = append(source.reads, [2]int{from, to})
+	return source.arraySource.Batch(from, to)
+}
+
+func TestModelFromSource(t *testing.T) {
+	X, Y := separableDataset()
+	hyperparameters := NewHyperparameters(20, 1.0, 1, 4)
+	hyperparameters.SetBatchSize(3)
+	expected := Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), &BaseCallback{})
+
+	source := &recordingSource{arraySource: arraySource{X: X, Y: Y}}
+	actual := ModelFromSource(source, hyperparameters, X.GetRows(), &BaseCallback{})
+	if !reflect.DeepEqual(matrix.ToSlice(expected.W1), matrix.ToSlice(actual.W1)) {
+		t.Errorf("Expected: %v, Actual: %v\n", expected.W1, actual.W1)
+	}
+	if len(source.reads) != 60 || source.reads[2] != [2]int{6, 8} {
+		t.Errorf("Expected: %v reads ending with %v, Actual: %v\n", 60, [2]int{6, 8}, source.reads[:3])
+	}
+
+	// only the order of the batches is shuffled
+	source.reads = nil
+	hyperparameters.SetShuffle(2)
+	ModelFromSource(source, hyperparameters, X.GetRows(), &BaseCallback{})
+	seen := make(map[[2]int]int)
+	for _, read := range source.reads {
+		seen[read]++
+	}
+	for _, read := range [][2]int{{0, 3}, {3, 6}, {6, 8}} {
+		if seen[read] != 20 {
+			t.Errorf("Expected: %v, Actual: %v\n", 20, seen[read])
+		}
+	}
+}
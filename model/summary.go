@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Size in bytes of a parameter
+const parameterSize int = 8
+
+// LayerSummary describes a layer of the network and the size of its
+// parameters
+type LayerSummary struct {
+	Name       string
+	Activation string
+	// Number of features of the input and of the output of the layer, for
+	// every example
+	Inputs int
+	Units  int
+	// Number of weights and biases
+	Parameters int
+	// Memory used by the weights and biases
+	Bytes int
+}
+
+// InputShape returns the shape of the input of the layer, with the examples as
+// columns
+func (layer LayerSummary) InputShape() string {
+	return fmt.Sprintf("(%v, m)", layer.Inputs)
+}
+
+// OutputShape returns the shape of the output of the layer, with the examples
+// as columns
+func (layer LayerSummary) OutputShape() string {
+	return fmt.Sprintf("(%v, m)", layer.Units)
+}
+
+// Summary describes the layers of a network, from the hidden layer to the
+// output layer, and their total size
+type Summary struct {
+	Layers          []LayerSummary
+	TotalParameters int
+	TotalBytes      int
+}
+
+// NewSummary returns the summary of the network with the given parameters
+func NewSummary(parameters *Parameters) *Summary {
+	architecture := NewArchitecture(parameters)
+	summary := new(Summary)
+	inputs := architecture.Inputs
+	for i, layer := range architecture.Layers {
+		name := "hidden"
+		if i == len(architecture.Layers)-1 {
+			name = "output"
+		}
+		// a weight per unit and input, and a bias per unit
+		count := layer.Units*inputs + layer.Units
+		summary.Layers = append(summary.Layers, LayerSummary{
+			Name:       name,
+			Activation: layer.Activation,
+			Inputs:     inputs,
+			Units:      layer.Units,
+			Parameters: count,
+			Bytes:      count * parameterSize,
+		})
+		summary.TotalParameters += count
+		summary.TotalBytes += count * parameterSize
+		inputs = layer.Units
+	}
+	return summary
+}
+
+// Returns the number of bytes in a human readable form, e.g. 1.5 KiB
+func formatBytes(bytes int) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%v B", bytes)
+	}
+	value := float64(bytes) / 1024
+	for _, unit := range []string{"KiB", "MiB"} {
+		if value < 1024 {
+			return fmt.Sprintf("%.1f %v", value, unit)
+		}
+		value /= 1024
+	}
+	return fmt.Sprintf("%.1f GiB", value)
+}
+
+// Write writes the summary as a table with a row per layer, followed by the
+// totals
+func (summary *Summary) Write(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Layer\tActivation\tInput shape\tOutput shape\tParameters\tMemory")
+	for _, layer := range summary.Layers {
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\t%v\n", layer.Name, layer.Activation,
+			layer.InputShape(), layer.OutputShape(), layer.Parameters, formatBytes(layer.Bytes))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "Total parameters: %v\nTotal memory: %v\n", summary.TotalParameters, formatBytes(summary.TotalBytes))
+	return err
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+func TestSummary(t *testing.T) {
+	W1, _ := matrix.NewMatrix(200, 12288)
+	B1, _ := matrix.NewMatrix(200, 1)
+	W2, _ := matrix.NewMatrix(1, 200)
+	B2, _ := matrix.NewMatrix(1, 1)
+	summary := NewSummary(&Parameters{W1: W1, B1: B1, W2: W2, B2: B2})
+
+	expected := &Summary{
+		Layers: []LayerSummary{
+			{Name: "hidden", Activation: TanhActivation, Inputs: 12288, Units: 200, Parameters: 2457800, Bytes: 19662400},
+			{Name: "output", Activation: SigmoidActivation, Inputs: 200, Units: 1, Parameters: 201, Bytes: 1608},
+		},
+		TotalParameters: 2458001,
+		TotalBytes:      19664008,
+	}
+	if !reflect.DeepEqual(expected, summary) {
+		t.Errorf("Expected: %+v, Actual: %+v\n", expected, summary)
+	}
+
+	var buffer bytes.Buffer
+	if err := summary.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	expectedOutput := "Layer   Activation  Input shape  Output shape  Parameters  Memory\n" +
+		"hidden  tanh        (12288, m)   (200, m)      2457800     18.8 MiB\n" +
+		"output  sigmoid     (200, m)     (1, m)        201         1.6 KiB\n" +
+		"Total parameters: 2458001\n" +
+		"Total memory: 18.8 MiB\n"
+	if buffer.String() != expectedOutput {
+		t.Errorf("Expected:\n%v\nActual:\n%v\n", expectedOutput, buffer.String())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tables := []struct {
+		bytes    int
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{3 << 20, "3.0 MiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+	for _, table := range tables {
+		if actual := formatBytes(table.bytes); actual != table.expected {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expected, actual)
+		}
+	}
+}
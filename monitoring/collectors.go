@@ -0,0 +1,107 @@
+package monitoring
+
+import (
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+// Buckets of the histograms of the latencies, in seconds
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Buckets of the histograms of the number of examples, from 1 to 4096
+var sizeBuckets = ExponentialBuckets(1, 2, 13)
+
+// Buckets of the histograms of the gradient norms
+var normBuckets = ExponentialBuckets(1e-6, 10, 10)
+
+// TrainingCollector is a Callback that updates the metrics of the training
+// of a model at the end of every mini-batch and epoch
+type TrainingCollector struct {
+	model.BaseCallback
+	iterations    *Counter
+	epochs        *Counter
+	cost          *Gauge
+	epochCost     *Gauge
+	metrics       *Gauge
+	learningRate  *Gauge
+	gradientNorms *Histogram
+	batchDuration *Histogram
+	lastBatch     time.Time
+}
+
+// NewTrainingCollector creates a TrainingCollector with its metrics
+// registered in the registry
+func NewTrainingCollector(registry *Registry) *TrainingCollector {
+	return &TrainingCollector{
+		iterations:    registry.NewCounter("mnn_training_iterations_total", "Number of updates of the parameters."),
+		epochs:        registry.NewCounter("mnn_training_epochs_total", "Number of passes through the training set."),
+		cost:          registry.NewGauge("mnn_training_cost", "Cost of the last mini-batch."),
+		epochCost:     registry.NewGauge("mnn_training_epoch_cost", "Average cost of the last epoch."),
+		metrics:       registry.NewGauge("mnn_training_metric", "Value of each metric at the end of the last epoch.", "metric"),
+		learningRate:  registry.NewGauge("mnn_training_learning_rate", "Learning rate of the last update of the parameters."),
+		gradientNorms: registry.NewHistogram("mnn_training_gradient_norm", "Euclidean norm of the gradient of each parameter.", normBuckets, "parameter"),
+		batchDuration: registry.NewHistogram("mnn_training_batch_duration_seconds", "Time taken to train on a mini-batch.", latencyBuckets),
+	}
+}
+
+// OnEpochBegin starts measuring the duration of the first mini-batch
+func (collector *TrainingCollector) OnEpochBegin(epoch int, logs *model.Logs) {
+	collector.lastBatch = time.Now()
+}
+
+// OnBatchEnd updates the metrics of the mini-batch
+func (collector *TrainingCollector) OnBatchEnd(batch int, logs *model.Logs) {
+	now := time.Now()
+	collector.batchDuration.Observe(now.Sub(collector.lastBatch).Seconds())
+	collector.lastBatch = now
+	collector.iterations.Inc()
+	collector.cost.Set(logs.Cost)
+	collector.learningRate.Set(logs.LearningRate)
+	for parameter, norm := range logs.GradientNorms {
+		collector.gradientNorms.Observe(norm, parameter)
+	}
+}
+
+// OnEpochEnd updates the metrics of the epoch
+func (collector *TrainingCollector) OnEpochEnd(epoch int, logs *model.Logs) {
+	collector.epochs.Inc()
+	collector.epochCost.Set(logs.Cost)
+	for name, value := range logs.Metrics {
+		collector.metrics.Set(value, name)
+	}
+}
+
+// ServingCollector is a server.PredictionObserver that updates the metrics
+// of the prediction requests answered by each API
+type ServingCollector struct {
+	requests    *Counter
+	errors      *Counter
+	predictions *Counter
+	latency     *Histogram
+	batchSize   *Histogram
+}
+
+// NewServingCollector creates a ServingCollector with its metrics registered
+// in the registry
+func NewServingCollector(registry *Registry) *ServingCollector {
+	return &ServingCollector{
+		requests:    registry.NewCounter("mnn_prediction_requests_total", "Number of prediction requests answered.", "api"),
+		errors:      registry.NewCounter("mnn_prediction_errors_total", "Number of prediction requests answered with an error.", "api"),
+		predictions: registry.NewCounter("mnn_predictions_total", "Number of examples predicted.", "api"),
+		latency:     registry.NewHistogram("mnn_prediction_latency_seconds", "Time taken to answer a prediction request.", latencyBuckets, "api"),
+		batchSize:   registry.NewHistogram("mnn_prediction_batch_size", "Number of examples of the successful prediction requests.", sizeBuckets, "api"),
+	}
+}
+
+// ObservePrediction updates the metrics of the API with the request
+func (collector *ServingCollector) ObservePrediction(api string, examples int, latency time.Duration, err error) {
+	collector.requests.Inc(api)
+	collector.latency.Observe(latency.Seconds(), api)
+	if err != nil {
+		collector.errors.Inc(api)
+		return
+	}
+	collector.predictions.Add(float64(examples), api)
+	collector.batchSize.Observe(float64(examples), api)
+}
@@ -0,0 +1,104 @@
+package monitoring
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/server"
+)
+
+// Returns the lines of the metrics of the registry scraped through an HTTP
+// test server
+func scrape(t *testing.T, registry *Registry) map[string]bool {
+	testServer := httptest.NewServer(registry)
+	defer testServer.Close()
+	response, err := http.Get(testServer.URL + MetricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(string(body), "\n") {
+		lines[line] = true
+	}
+	return lines
+}
+
+func TestTrainingCollector(t *testing.T) {
+	X, _ := matrix.NewMatrixFromSlice([][]float64{
+		{-2, -1.5, -1, -0.5, 0.5, 1, 1.5, 2},
+		{1, -1, 0.5, -0.5, 0.5, -0.5, 1, -1},
+	})
+	Y, _ := matrix.NewMatrixFromSlice([][]float64{{0, 0, 0, 0, 1, 1, 1, 1}})
+	registry := NewRegistry()
+	hyperparameters := model.NewHyperparameters(3, 1.0, 1, 4)
+	hyperparameters.SetBatchSize(4)
+	model.Model(X, Y, hyperparameters, X.GetColumns(), X.GetRows(), NewTrainingCollector(registry))
+
+	lines := scrape(t, registry)
+	for _, expected := range []string{
+		"mnn_training_iterations_total 6",
+		"mnn_training_epochs_total 3",
+		"mnn_training_learning_rate 1",
+		`mnn_training_gradient_norm_count{parameter="W1"} 6`,
+		`mnn_training_gradient_norm_count{parameter="B2"} 6`,
+		"mnn_training_batch_duration_seconds_count 6",
+	} {
+		if !lines[expected] {
+			t.Errorf("Expected: %v, Actual: %v\n", expected, lines)
+		}
+	}
+	found := false
+	for line := range lines {
+		found = found || strings.HasPrefix(line, `mnn_training_metric{metric="accuracy"} `)
+	}
+	if !found {
+		t.Errorf("Expected the accuracy, Actual: %v\n", lines)
+	}
+}
+
+func TestServingCollector(t *testing.T) {
+	W1, _ := matrix.NewMatrixFromSlice([][]float64{{1, 0}})
+	B1, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	W2, _ := matrix.NewMatrixFromSlice([][]float64{{1}})
+	B2, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	parameters := &model.Parameters{W1: W1, B1: B1, W2: W2, B2: B2}
+	registry := NewRegistry()
+	predictionServer := server.NewServer(server.NewModel("test", parameters, nil), 0.5)
+	predictionServer.SetObserver(NewServingCollector(registry))
+
+	for _, body := range []string{
+		`{"instances": [[1, 2], [3, 4], [5, 6]]}`,
+		`{"instances": [[1, 2]]}`,
+		`{"instances": [[1, 2, 3]]}`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, server.PredictPath, bytes.NewReader([]byte(body)))
+		predictionServer.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	lines := scrape(t, registry)
+	for _, expected := range []string{
+		`mnn_prediction_requests_total{api="http"} 3`,
+		`mnn_prediction_errors_total{api="http"} 1`,
+		`mnn_predictions_total{api="http"} 4`,
+		`mnn_prediction_latency_seconds_count{api="http"} 3`,
+		`mnn_prediction_batch_size_bucket{api="http",le="1"} 1`,
+		`mnn_prediction_batch_size_bucket{api="http",le="2"} 1`,
+		`mnn_prediction_batch_size_bucket{api="http",le="4"} 2`,
+		`mnn_prediction_batch_size_sum{api="http"} 4`,
+	} {
+		if !lines[expected] {
+			t.Errorf("Expected: %v, Actual: %v\n", expected, lines)
+		}
+	}
+}
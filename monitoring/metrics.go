@@ -0,0 +1,269 @@
+// Package monitoring exports metrics of the training and the serving of
+// models in the Prometheus text exposition format, so they can be scraped
+// over HTTP without depending on a Prometheus client library
+package monitoring
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MetricsPath is the path the metrics are usually scraped from
+const MetricsPath string = "/metrics"
+
+// Content type of the text exposition format
+const textContentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+// Separator of the label values in the keys of the series
+const labelSeparator string = "\xff"
+
+// Types of the metrics
+const (
+	counterType   string = "counter"
+	gaugeType     string = "gauge"
+	histogramType string = "histogram"
+)
+
+// Values of a metric for a combination of label values
+type series struct {
+	labelValues []string
+	// value of a counter or a gauge
+	value float64
+	// number of observations of a histogram in each bucket, not cumulative,
+	// the last one being the +Inf bucket
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Metric with all its series
+type family struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	// upper bounds of the buckets of a histogram, in increasing order
+	buckets []float64
+	series  map[string]*series
+}
+
+// Registry holds the metrics and writes them in the text exposition format.
+// It's safe for concurrent use, and is an http.Handler serving the metrics
+type Registry struct {
+	mutex    sync.Mutex
+	families []*family
+}
+
+// NewRegistry creates a Registry without any metric
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Adds a metric to the registry. It panics if the name is already taken, as
+// it's a programming error
+func (registry *Registry) register(metric *family) *family {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for _, registered := range registry.families {
+		if registered.name == metric.name {
+			panic(fmt.Sprintf("Can't register metric %v twice", metric.name))
+		}
+	}
+	metric.series = make(map[string]*series)
+	registry.families = append(registry.families, metric)
+	return metric
+}
+
+// Returns the series of the label values, creating it when it doesn't exist.
+// Must be called with the mutex of the registry locked
+func (metric *family) with(labelValues []string) *series {
+	if len(labelValues) != len(metric.labelNames) {
+		panic(fmt.Sprintf("Can't use %v label values for metric %v with labels %v", len(labelValues), metric.name, metric.labelNames))
+	}
+	key := strings.Join(labelValues, labelSeparator)
+	values, ok := metric.series[key]
+	if !ok {
+		values = &series{labelValues: append([]string(nil), labelValues...)}
+		if metric.metricType == histogramType {
+			values.counts = make([]uint64, len(metric.buckets)+1)
+		}
+		metric.series[key] = values
+	}
+	return values
+}
+
+// Counter is a metric whose value only increases, e.g. a number of requests
+type Counter struct {
+	registry *Registry
+	metric   *family
+}
+
+// NewCounter registers a Counter with the given labels
+func (registry *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	metric := &family{name: name, help: help, metricType: counterType, labelNames: labelNames}
+	return &Counter{registry: registry, metric: registry.register(metric)}
+}
+
+// Add adds the value, which can't be negative, to the counter of the label
+// values
+func (counter *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic(fmt.Sprintf("Can't decrease counter %v", counter.metric.name))
+	}
+	counter.registry.mutex.Lock()
+	defer counter.registry.mutex.Unlock()
+	counter.metric.with(labelValues).value += value
+}
+
+// Inc adds one to the counter of the label values
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Gauge is a metric whose value can go up and down, e.g. a cost
+type Gauge struct {
+	registry *Registry
+	metric   *family
+}
+
+// NewGauge registers a Gauge with the given labels
+func (registry *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	metric := &family{name: name, help: help, metricType: gaugeType, labelNames: labelNames}
+	return &Gauge{registry: registry, metric: registry.register(metric)}
+}
+
+// Set sets the gauge of the label values to the value
+func (gauge *Gauge) Set(value float64, labelValues ...string) {
+	gauge.registry.mutex.Lock()
+	defer gauge.registry.mutex.Unlock()
+	gauge.metric.with(labelValues).value = value
+}
+
+// Histogram counts the observed values in buckets, e.g. for latencies
+type Histogram struct {
+	registry *Registry
+	metric   *family
+}
+
+// NewHistogram registers a Histogram with the given upper bounds of the
+// buckets, in increasing order, and labels. The +Inf bucket is always added
+func (registry *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("Can't use unsorted buckets %v for histogram %v", buckets, name))
+	}
+	metric := &family{name: name, help: help, metricType: histogramType, labelNames: labelNames, buckets: buckets}
+	return &Histogram{registry: registry, metric: registry.register(metric)}
+}
+
+// Observe adds the value to the histogram of the label values
+func (histogram *Histogram) Observe(value float64, labelValues ...string) {
+	histogram.registry.mutex.Lock()
+	defer histogram.registry.mutex.Unlock()
+	values := histogram.metric.with(labelValues)
+	bucket := sort.SearchFloat64s(histogram.metric.buckets, value)
+	values.counts[bucket]++
+	values.sum += value
+	values.count++
+}
+
+// ExponentialBuckets returns count upper bounds of buckets, the first one
+// being start and each of the others factor times the previous one
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// Formats a value as in the text exposition format
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// Escapes the backslashes and line feeds of the help, plus the double quotes
+// of label values
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// Formats the labels, along with an extra one when extraName isn't empty,
+// e.g. {api="http",le="0.1"}
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+labelEscaper.Replace(extraValue)+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// WriteText writes all the metrics in the text exposition format, in the
+// order they were registered and with their series sorted by label values
+func (registry *Registry) WriteText(w io.Writer) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	buffered := bufio.NewWriter(w)
+	for _, metric := range registry.families {
+		fmt.Fprintf(buffered, "# HELP %v %v\n", metric.name, helpEscaper.Replace(metric.help))
+		fmt.Fprintf(buffered, "# TYPE %v %v\n", metric.name, metric.metricType)
+		keys := make([]string, 0, len(metric.series))
+		for key := range metric.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values := metric.series[key]
+			if metric.metricType != histogramType {
+				fmt.Fprintf(buffered, "%v%v %v\n", metric.name,
+					formatLabels(metric.labelNames, values.labelValues, "", ""), formatValue(values.value))
+				continue
+			}
+			cumulative := uint64(0)
+			for i, count := range values.counts {
+				cumulative += count
+				bound := math.Inf(1)
+				if i < len(metric.buckets) {
+					bound = metric.buckets[i]
+				}
+				fmt.Fprintf(buffered, "%v_bucket%v %v\n", metric.name,
+					formatLabels(metric.labelNames, values.labelValues, "le", formatValue(bound)), cumulative)
+			}
+			labels := formatLabels(metric.labelNames, values.labelValues, "", "")
+			fmt.Fprintf(buffered, "%v_sum%v %v\n", metric.name, labels, formatValue(values.sum))
+			fmt.Fprintf(buffered, "%v_count%v %v\n", metric.name, labels, values.count)
+		}
+	}
+	return buffered.Flush()
+}
+
+// ServeHTTP writes the metrics as the body of the response
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", textContentType)
+	if err := registry.WriteText(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
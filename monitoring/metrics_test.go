@@ -0,0 +1,97 @@
+package monitoring
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounter("requests_total", "Number of requests.", "api", "code")
+	cost := registry.NewGauge("cost", "Cost with \"quotes\" and a\nline feed.")
+	latency := registry.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "api")
+
+	requests.Inc("http", "200")
+	requests.Add(2, "http", "200")
+	requests.Inc("grpc", "OK")
+	requests.Inc("http", `say "hi"`)
+	cost.Set(0.25)
+	cost.Set(math.Inf(1))
+	for _, value := range []float64{0.05, 0.1, 0.5, 3} {
+		latency.Observe(value, "http")
+	}
+
+	expected := strings.Join([]string{
+		"# HELP requests_total Number of requests.",
+		"# TYPE requests_total counter",
+		`requests_total{api="grpc",code="OK"} 1`,
+		`requests_total{api="http",code="200"} 3`,
+		`requests_total{api="http",code="say \"hi\""} 1`,
+		`# HELP cost Cost with "quotes" and a\nline feed.`,
+		"# TYPE cost gauge",
+		"cost +Inf",
+		"# HELP latency_seconds Latency.",
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{api="http",le="0.1"} 2`,
+		`latency_seconds_bucket{api="http",le="1"} 3`,
+		`latency_seconds_bucket{api="http",le="+Inf"} 4`,
+		`latency_seconds_sum{api="http"} 3.65`,
+		`latency_seconds_count{api="http"} 4`,
+		"",
+	}, "\n")
+	var buffer bytes.Buffer
+	if err := registry.WriteText(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if buffer.String() != expected {
+		t.Errorf("Expected: %v, Actual: %v\n", expected, buffer.String())
+	}
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected ||
+		!strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Expected: %v, Actual: %v %v %v\n", expected, recorder.Code, recorder.Header(), recorder.Body)
+	}
+}
+
+func TestRegistryMisuse(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("total", "Total.", "label")
+	tables := []struct {
+		name string
+		call func()
+	}{
+		{"duplicate name", func() { registry.NewGauge("total", "Again.") }},
+		{"missing label", func() { counter.Inc() }},
+		{"negative increment", func() { counter.Add(-1, "a") }},
+		{"unsorted buckets", func() { registry.NewHistogram("h", "H.", []float64{2, 1}) }},
+	}
+	for _, table := range tables {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for %v\n", table.name)
+				}
+			}()
+			table.call()
+		}()
+	}
+}
+
+func TestExponentialBuckets(t *testing.T) {
+	expected := []float64{1, 2, 4, 8}
+	actual := ExponentialBuckets(1, 2, 4)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected: %v, Actual: %v\n", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected: %v, Actual: %v\n", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package onnx exports trained models as ONNX files, so they can be deployed
+// with other runtimes. The protobuf messages are encoded by the package
+// itself, and only the subset of ONNX describing feed-forward networks is
+// supported
+package onnx
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+// Versions of the format and of the operators used
+const (
+	irVersion    int64 = 7
+	opsetVersion int64 = 13
+)
+
+// Name of the program written in the files
+const producerName string = "micro_neural_network"
+
+// Name of the symbolic dimension of the number of examples
+const batchDimension string = "N"
+
+// Names of the input and output of the graph
+const (
+	// InputName is the input of the graph, with one row per example
+	InputName string = "input"
+	// OutputName is the output of the graph, with one row per example
+	OutputName string = "output"
+)
+
+// Activations of the layers, named as their ONNX operators
+const (
+	Tanh    string = "Tanh"
+	Sigmoid string = "Sigmoid"
+	Relu    string = "Relu"
+	// Softmax is applied to the outputs of each example
+	Softmax string = "Softmax"
+)
+
+// Layer is a fully connected layer, computing the activation of W x + B for
+// the inputs x of every example
+type Layer struct {
+	// Weights with one row per unit of the layer and one column per input
+	Weights matrix.NumberArray
+	// Biases with one row per unit of the layer and a single column
+	Biases     matrix.NumberArray
+	Activation string
+}
+
+// Layers returns the layers of the network with the given parameters, i.e.
+// a hidden layer with the hyperbolic tangent as activation and an output
+// layer with the sigmoid
+func Layers(parameters *model.Parameters) []Layer {
+	return []Layer{
+		{Weights: parameters.W1, Biases: parameters.B1, Activation: Tanh},
+		{Weights: parameters.W2, Biases: parameters.B2, Activation: Sigmoid},
+	}
+}
+
+// Returns the values of the matrix in row-major order
+func float32Values(array matrix.NumberArray) []float32 {
+	values := make([]float32, 0, array.GetRows()*array.GetColumns())
+	for _, row := range matrix.ToSlice(array) {
+		for _, value := range row {
+			values = append(values, float32(value))
+		}
+	}
+	return values
+}
+
+// NewModel builds the ONNX model of the layers. Unlike the model package, the
+// input of the graph has one row per example, as usual for ONNX runtimes, so
+// each layer is a Gemm node multiplying the inputs by the transpose of the
+// weights, an Add node adding the biases, and the node of the activation
+func NewModel(layers []Layer) (*Model, error) {
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("Can't export a network without layers")
+	}
+	graph := &Graph{Name: producerName}
+	input := InputName
+	numberInputs := layers[0].Weights.GetColumns()
+	for i, layer := range layers {
+		units := layer.Weights.GetRows()
+		if layer.Weights.GetColumns() != numberInputs {
+			return nil, fmt.Errorf("Can't export layer %v with %v inputs, the previous layer has %v units", i+1, layer.Weights.GetColumns(), numberInputs)
+		}
+		if layer.Biases.GetRows() != units || layer.Biases.GetColumns() != 1 {
+			return nil, fmt.Errorf("Can't export layer %v with biases of shape %vx%v, expected %vx1", i+1, layer.Biases.GetRows(), layer.Biases.GetColumns(), units)
+		}
+		var attributes []*Attribute
+		switch layer.Activation {
+		case Tanh, Sigmoid, Relu:
+		case Softmax:
+			attributes = []*Attribute{{Name: "axis", Type: AttributeInt, Int: 1}}
+		default:
+			return nil, fmt.Errorf("Can't export layer %v with activation %v", i+1, layer.Activation)
+		}
+
+		weights := fmt.Sprintf("W%v", i+1)
+		biases := fmt.Sprintf("B%v", i+1)
+		graph.Initializers = append(graph.Initializers,
+			&Tensor{Name: weights, Dims: []int64{int64(units), int64(numberInputs)}, DataType: TensorFloat, Values: float32Values(layer.Weights)},
+			&Tensor{Name: biases, Dims: []int64{int64(units)}, DataType: TensorFloat, Values: float32Values(layer.Biases)},
+		)
+		product := fmt.Sprintf("%v_%v", input, weights)
+		linear := fmt.Sprintf("Z%v", i+1)
+		output := fmt.Sprintf("A%v", i+1)
+		if i == len(layers)-1 {
+			output = OutputName
+		}
+		graph.Nodes = append(graph.Nodes,
+			&Node{
+				Name:       fmt.Sprintf("layer%v_gemm", i+1),
+				OpType:     "Gemm",
+				Inputs:     []string{input, weights},
+				Outputs:    []string{product},
+				Attributes: []*Attribute{{Name: "transB", Type: AttributeInt, Int: 1}},
+			},
+			&Node{Name: fmt.Sprintf("layer%v_add", i+1), OpType: "Add", Inputs: []string{product, biases}, Outputs: []string{linear}},
+			&Node{
+				Name:       fmt.Sprintf("layer%v_%v", i+1, layer.Activation),
+				OpType:     layer.Activation,
+				Inputs:     []string{linear},
+				Outputs:    []string{output},
+				Attributes: attributes,
+			},
+		)
+		input = output
+		numberInputs = units
+	}
+	graph.Inputs = []*ValueInfo{{
+		Name:      InputName,
+		ElemType:  TensorFloat,
+		Dims:      []int64{0, int64(layers[0].Weights.GetColumns())},
+		DimParams: []string{batchDimension, ""},
+	}}
+	graph.Outputs = []*ValueInfo{{
+		Name:      OutputName,
+		ElemType:  TensorFloat,
+		Dims:      []int64{0, int64(numberInputs)},
+		DimParams: []string{batchDimension, ""},
+	}}
+	return &Model{IRVersion: irVersion, ProducerName: producerName, OpsetVersion: opsetVersion, Graph: graph}, nil
+}
+
+// Export writes the network with the parameters to filename as an ONNX
+// model. The preprocessing of the model isn't exported, so it must be
+// applied to the inputs before running the exported model
+func Export(filename string, parameters *model.Parameters) error {
+	onnxModel, err := NewModel(Layers(parameters))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, onnxModel.Marshal(), 0644); err != nil {
+		return fmt.Errorf("Can't write ONNX model %v: %v", filename, err)
+	}
+	return nil
+}
+
+// ReadModel reads the ONNX model of the file
+func ReadModel(filename string) (*Model, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	onnxModel := new(Model)
+	if err := onnxModel.Unmarshal(data); err != nil {
+		return nil, fmt.Errorf("Can't read ONNX model %v: %v", filename, err)
+	}
+	return onnxModel, nil
+}
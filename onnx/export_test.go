@@ -0,0 +1,259 @@
+package onnx
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+// Parameters of a network with 3 features and 2 hidden units
+func testParameters() *model.Parameters {
+	W1, _ := matrix.NewMatrixFromSlice([][]float64{{0.5, -1, 0.25}, {1.5, 0.5, -0.75}})
+	B1, _ := matrix.NewMatrixFromSlice([][]float64{{0.1}, {-0.2}})
+	W2, _ := matrix.NewMatrixFromSlice([][]float64{{2, -3}})
+	B2, _ := matrix.NewMatrixFromSlice([][]float64{{0.3}})
+	return &model.Parameters{W1: W1, B1: B1, W2: W2, B2: B2}
+}
+
+// Runs the graph on the examples, one per row, with the operators used by
+// NewModel, and returns the values of its output
+func run(t *testing.T, graph *Graph, examples [][]float64) [][]float64 {
+	values := map[string][][]float64{InputName: examples}
+	for _, initializer := range graph.Initializers {
+		columns := 1
+		if len(initializer.Dims) == 2 {
+			columns = int(initializer.Dims[1])
+		}
+		rows := make([][]float64, len(initializer.Values)/columns)
+		for i := range rows {
+			rows[i] = make([]float64, columns)
+			for j := range rows[i] {
+				rows[i][j] = float64(initializer.Values[i*columns+j])
+			}
+		}
+		if len(initializer.Dims) == 1 {
+			// a vector broadcast to every example
+			vector := make([]float64, len(rows))
+			for i := range rows {
+				vector[i] = rows[i][0]
+			}
+			rows = [][]float64{vector}
+		}
+		values[initializer.Name] = rows
+	}
+	apply := func(input [][]float64, function func(row []float64) []float64) [][]float64 {
+		output := make([][]float64, len(input))
+		for i, row := range input {
+			output[i] = function(row)
+		}
+		return output
+	}
+	for _, node := range graph.Nodes {
+		inputs := make([][][]float64, len(node.Inputs))
+		for i, name := range node.Inputs {
+			var ok bool
+			if inputs[i], ok = values[name]; !ok {
+				t.Fatalf("Node %v uses %v before it's computed\n", node.Name, name)
+			}
+		}
+		var output [][]float64
+		switch node.OpType {
+		case "Gemm":
+			// A times the transpose of B
+			output = apply(inputs[0], func(row []float64) []float64 {
+				result := make([]float64, len(inputs[1]))
+				for j, weights := range inputs[1] {
+					for k, weight := range weights {
+						result[j] += row[k] * weight
+					}
+				}
+				return result
+			})
+		case "Add":
+			output = apply(inputs[0], func(row []float64) []float64 {
+				result := make([]float64, len(row))
+				for j := range row {
+					result[j] = row[j] + inputs[1][0][j]
+				}
+				return result
+			})
+		case "Tanh", "Sigmoid", "Relu":
+			functions := map[string]func(float64) float64{
+				"Tanh":    math.Tanh,
+				"Sigmoid": func(x float64) float64 { return 1 / (1 + math.Exp(-x)) },
+				"Relu":    func(x float64) float64 { return math.Max(x, 0) },
+			}
+			output = apply(inputs[0], func(row []float64) []float64 {
+				result := make([]float64, len(row))
+				for j := range row {
+					result[j] = functions[node.OpType](row[j])
+				}
+				return result
+			})
+		case "Softmax":
+			output = apply(inputs[0], func(row []float64) []float64 {
+				result := make([]float64, len(row))
+				sum := 0.0
+				for j := range row {
+					result[j] = math.Exp(row[j])
+					sum += result[j]
+				}
+				for j := range result {
+					result[j] /= sum
+				}
+				return result
+			})
+		default:
+			t.Fatalf("Unexpected operator %v\n", node.OpType)
+		}
+		values[node.Outputs[0]] = output
+	}
+	return values[graph.Outputs[0].Name]
+}
+
+func TestExport(t *testing.T) {
+	parameters := testParameters()
+	filename := filepath.Join(t.TempDir(), "model.onnx")
+	if err := Export(filename, parameters); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ReadModel(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.IRVersion != irVersion || exported.OpsetVersion != opsetVersion || exported.ProducerName != producerName {
+		t.Errorf("Unexpected model: %+v\n", exported)
+	}
+	graph := exported.Graph
+
+	expectedNodes := []struct {
+		opType     string
+		inputs     []string
+		outputs    []string
+		attributes []Attribute
+	}{
+		{"Gemm", []string{"input", "W1"}, []string{"input_W1"}, []Attribute{{Name: "transB", Type: AttributeInt, Int: 1}}},
+		{"Add", []string{"input_W1", "B1"}, []string{"Z1"}, nil},
+		{"Tanh", []string{"Z1"}, []string{"A1"}, nil},
+		{"Gemm", []string{"A1", "W2"}, []string{"A1_W2"}, []Attribute{{Name: "transB", Type: AttributeInt, Int: 1}}},
+		{"Add", []string{"A1_W2", "B2"}, []string{"Z2"}, nil},
+		{"Sigmoid", []string{"Z2"}, []string{"output"}, nil},
+	}
+	if len(graph.Nodes) != len(expectedNodes) {
+		t.Fatalf("Expected: %v nodes, Actual: %v\n", len(expectedNodes), len(graph.Nodes))
+	}
+	for i, expected := range expectedNodes {
+		node := graph.Nodes[i]
+		var attributes []Attribute
+		for _, attribute := range node.Attributes {
+			attributes = append(attributes, *attribute)
+		}
+		if node.OpType != expected.opType || !reflect.DeepEqual(node.Inputs, expected.inputs) ||
+			!reflect.DeepEqual(node.Outputs, expected.outputs) || !reflect.DeepEqual(attributes, expected.attributes) {
+			t.Errorf("Expected: %+v, Actual: %+v %v\n", expected, *node, attributes)
+		}
+	}
+
+	expectedInitializers := []struct {
+		name   string
+		dims   []int64
+		values []float32
+	}{
+		{"W1", []int64{2, 3}, []float32{0.5, -1, 0.25, 1.5, 0.5, -0.75}},
+		{"B1", []int64{2}, []float32{0.1, -0.2}},
+		{"W2", []int64{1, 2}, []float32{2, -3}},
+		{"B2", []int64{1}, []float32{0.3}},
+	}
+	if len(graph.Initializers) != len(expectedInitializers) {
+		t.Fatalf("Expected: %v initializers, Actual: %v\n", len(expectedInitializers), len(graph.Initializers))
+	}
+	for i, expected := range expectedInitializers {
+		initializer := graph.Initializers[i]
+		if initializer.Name != expected.name || initializer.DataType != TensorFloat ||
+			!reflect.DeepEqual(initializer.Dims, expected.dims) || !reflect.DeepEqual(initializer.Values, expected.values) {
+			t.Errorf("Expected: %+v, Actual: %+v\n", expected, *initializer)
+		}
+	}
+
+	expectedIO := []struct {
+		actual    []*ValueInfo
+		name      string
+		dims      []int64
+		dimParams []string
+	}{
+		{graph.Inputs, InputName, []int64{0, 3}, []string{"N", ""}},
+		{graph.Outputs, OutputName, []int64{0, 1}, []string{"N", ""}},
+	}
+	for _, expected := range expectedIO {
+		if len(expected.actual) != 1 || expected.actual[0].Name != expected.name || expected.actual[0].ElemType != TensorFloat ||
+			!reflect.DeepEqual(expected.actual[0].Dims, expected.dims) || !reflect.DeepEqual(expected.actual[0].DimParams, expected.dimParams) {
+			t.Errorf("Expected: %v %v %v, Actual: %+v\n", expected.name, expected.dims, expected.dimParams, expected.actual)
+		}
+	}
+
+	// the graph computes the predictions of the model
+	examples := [][]float64{{1, 2, 3}, {-1, 0.5, 0}, {0, 0, 0}}
+	X, _ := matrix.NewMatrix(3, len(examples))
+	for j, example := range examples {
+		for i, value := range example {
+			X.SetValue(i, j, value)
+		}
+	}
+	expected := model.PredictProbabilities(parameters, X)
+	actual := run(t, graph, examples)
+	for j := range examples {
+		probability, _ := expected.GetValue(0, j)
+		if math.Abs(actual[j][0]-probability) > 1e-6 {
+			t.Errorf("Expected: %v, Actual: %v\n", probability, actual[j][0])
+		}
+	}
+}
+
+func TestNewModelLayers(t *testing.T) {
+	parameters := testParameters()
+	W3, _ := matrix.NewMatrixFromSlice([][]float64{{1}, {-1}})
+	B3, _ := matrix.NewMatrixFromSlice([][]float64{{0}, {0.5}})
+	onnxModel, err := NewModel([]Layer{
+		{Weights: parameters.W1, Biases: parameters.B1, Activation: Relu},
+		{Weights: parameters.W2, Biases: parameters.B2, Activation: Tanh},
+		{Weights: W3, Biases: B3, Activation: Softmax},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Model)
+	if err := decoded.Unmarshal(onnxModel.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(onnxModel, decoded) {
+		t.Errorf("Expected: %+v, Actual: %+v\n", onnxModel.Graph, decoded.Graph)
+	}
+	softmax := decoded.Graph.Nodes[len(decoded.Graph.Nodes)-1]
+	if softmax.OpType != Softmax || len(softmax.Attributes) != 1 || softmax.Attributes[0].Name != "axis" {
+		t.Errorf("Unexpected softmax node: %+v\n", softmax)
+	}
+	for _, row := range run(t, decoded.Graph, [][]float64{{1, 2, 3}, {0, 0, 0}}) {
+		if len(row) != 2 || math.Abs(row[0]+row[1]-1) > 1e-9 {
+			t.Errorf("Expected probabilities summing to 1, Actual: %v\n", row)
+		}
+	}
+
+	tables := []struct {
+		name   string
+		layers []Layer
+	}{
+		{"no layers", nil},
+		{"wrong inputs", []Layer{{Weights: parameters.W1, Biases: parameters.B1, Activation: Tanh}, {Weights: parameters.W1, Biases: parameters.B1, Activation: Tanh}}},
+		{"wrong biases", []Layer{{Weights: parameters.W1, Biases: parameters.B2, Activation: Tanh}}},
+		{"unknown activation", []Layer{{Weights: parameters.W1, Biases: parameters.B1, Activation: "Swish"}}},
+	}
+	for _, table := range tables {
+		if _, err := NewModel(table.layers); err == nil {
+			t.Errorf("Expected an error for %v\n", table.name)
+		}
+	}
+}
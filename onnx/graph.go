@@ -0,0 +1,399 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Element types of the tensors
+const (
+	// TensorFloat is the element type of float32 tensors
+	TensorFloat int32 = 1
+)
+
+// Types of the attributes
+const (
+	// AttributeFloat is the type of the float attributes
+	AttributeFloat int32 = 1
+	// AttributeInt is the type of the integer attributes
+	AttributeInt int32 = 2
+)
+
+// Model is the top level message of an ONNX file. The types of this file are
+// the subset of the messages of onnx.proto needed to describe feed-forward
+// networks, encoded with the field numbers of onnx.proto. Unknown fields are
+// skipped when decoding
+type Model struct {
+	IRVersion       int64
+	ProducerName    string
+	ProducerVersion string
+	// Version of the default operator set used by the graph
+	OpsetVersion int64
+	Graph        *Graph
+}
+
+// Graph is the computation of a model
+type Graph struct {
+	Name  string
+	Nodes []*Node
+	// Constant tensors, e.g. the weights, used as inputs by the nodes
+	Initializers []*Tensor
+	Inputs       []*ValueInfo
+	Outputs      []*ValueInfo
+}
+
+// Node is an operator of the graph, whose inputs and outputs are named
+// values of the graph
+type Node struct {
+	Inputs     []string
+	Outputs    []string
+	Name       string
+	OpType     string
+	Attributes []*Attribute
+}
+
+// Attribute is a parameter of an operator, either an integer or a float
+type Attribute struct {
+	Name  string
+	Type  int32
+	Float float32
+	Int   int64
+}
+
+// Tensor is a named constant of float32 values in row-major order
+type Tensor struct {
+	Name     string
+	Dims     []int64
+	DataType int32
+	Values   []float32
+}
+
+// ValueInfo describes an input or output of the graph, a tensor of float32
+// values with the given dimensions. A dimension is either fixed, when its
+// name is empty, or symbolic, e.g. the number of examples
+type ValueInfo struct {
+	Name      string
+	Dims      []int64
+	DimParams []string
+	ElemType  int32
+}
+
+// Marshal encodes the model as an ONNX protobuf message
+func (model *Model) Marshal() []byte {
+	var e encoder
+	e.varint(1, model.IRVersion)
+	e.string(2, model.ProducerName)
+	e.string(3, model.ProducerVersion)
+	if model.Graph != nil {
+		e.message(7, model.Graph)
+	}
+	opset := encoder{}
+	opset.varint(2, model.OpsetVersion)
+	e.bytes(8, opset.buffer)
+	return e.buffer
+}
+
+func (graph *Graph) marshal() []byte {
+	var e encoder
+	for _, node := range graph.Nodes {
+		e.message(1, node)
+	}
+	e.string(2, graph.Name)
+	for _, initializer := range graph.Initializers {
+		e.message(5, initializer)
+	}
+	for _, input := range graph.Inputs {
+		e.message(11, input)
+	}
+	for _, output := range graph.Outputs {
+		e.message(12, output)
+	}
+	return e.buffer
+}
+
+func (node *Node) marshal() []byte {
+	var e encoder
+	for _, input := range node.Inputs {
+		e.bytes(1, []byte(input))
+	}
+	for _, output := range node.Outputs {
+		e.bytes(2, []byte(output))
+	}
+	e.string(3, node.Name)
+	e.string(4, node.OpType)
+	for _, attribute := range node.Attributes {
+		e.message(5, attribute)
+	}
+	return e.buffer
+}
+
+func (attribute *Attribute) marshal() []byte {
+	var e encoder
+	e.string(1, attribute.Name)
+	switch attribute.Type {
+	case AttributeFloat:
+		e.float(2, attribute.Float)
+	case AttributeInt:
+		e.varint(3, attribute.Int)
+	}
+	e.varint(20, int64(attribute.Type))
+	return e.buffer
+}
+
+// The values are written as raw data, in little endian
+func (tensor *Tensor) marshal() []byte {
+	var e encoder
+	e.packedVarints(1, tensor.Dims)
+	e.varint(2, int64(tensor.DataType))
+	e.string(8, tensor.Name)
+	raw := make([]byte, 0, 4*len(tensor.Values))
+	for _, value := range tensor.Values {
+		raw = appendFloat32(raw, value)
+	}
+	e.bytes(9, raw)
+	return e.buffer
+}
+
+func (info *ValueInfo) marshal() []byte {
+	var shape encoder
+	for i, dim := range info.Dims {
+		var dimension encoder
+		if info.DimParams[i] != "" {
+			dimension.string(2, info.DimParams[i])
+		} else {
+			dimension.varint(1, dim)
+		}
+		shape.bytes(1, dimension.buffer)
+	}
+	var tensorType encoder
+	tensorType.varint(1, int64(info.ElemType))
+	tensorType.bytes(2, shape.buffer)
+	var typeProto encoder
+	typeProto.bytes(1, tensorType.buffer)
+
+	var e encoder
+	e.string(1, info.Name)
+	e.bytes(2, typeProto.buffer)
+	return e.buffer
+}
+
+// Unmarshal decodes an ONNX protobuf message into the model
+func (model *Model) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, current := range fields {
+		switch current.number {
+		case 1:
+			model.IRVersion = int64(current.value)
+		case 2:
+			model.ProducerName = string(current.data)
+		case 3:
+			model.ProducerVersion = string(current.data)
+		case 7:
+			model.Graph = new(Graph)
+			if err := model.Graph.unmarshal(current.data); err != nil {
+				return err
+			}
+		case 8:
+			opset, err := parseFields(current.data)
+			if err != nil {
+				return err
+			}
+			domain := ""
+			version := int64(0)
+			for _, opsetField := range opset {
+				switch opsetField.number {
+				case 1:
+					domain = string(opsetField.data)
+				case 2:
+					version = int64(opsetField.value)
+				}
+			}
+			if domain == "" || domain == "ai.onnx" {
+				model.OpsetVersion = version
+			}
+		}
+	}
+	return nil
+}
+
+func (graph *Graph) unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, current := range fields {
+		switch current.number {
+		case 1:
+			node := new(Node)
+			if err := node.unmarshal(current.data); err != nil {
+				return err
+			}
+			graph.Nodes = append(graph.Nodes, node)
+		case 2:
+			graph.Name = string(current.data)
+		case 5:
+			tensor := new(Tensor)
+			if err := tensor.unmarshal(current.data); err != nil {
+				return err
+			}
+			graph.Initializers = append(graph.Initializers, tensor)
+		case 11, 12:
+			info := new(ValueInfo)
+			if err := info.unmarshal(current.data); err != nil {
+				return err
+			}
+			if current.number == 11 {
+				graph.Inputs = append(graph.Inputs, info)
+			} else {
+				graph.Outputs = append(graph.Outputs, info)
+			}
+		}
+	}
+	return nil
+}
+
+func (node *Node) unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, current := range fields {
+		switch current.number {
+		case 1:
+			node.Inputs = append(node.Inputs, string(current.data))
+		case 2:
+			node.Outputs = append(node.Outputs, string(current.data))
+		case 3:
+			node.Name = string(current.data)
+		case 4:
+			node.OpType = string(current.data)
+		case 5:
+			attribute := new(Attribute)
+			if err := attribute.unmarshal(current.data); err != nil {
+				return err
+			}
+			node.Attributes = append(node.Attributes, attribute)
+		}
+	}
+	return nil
+}
+
+func (attribute *Attribute) unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, current := range fields {
+		switch current.number {
+		case 1:
+			attribute.Name = string(current.data)
+		case 2:
+			attribute.Float = math.Float32frombits(uint32(current.value))
+		case 3:
+			attribute.Int = int64(current.value)
+		case 20:
+			attribute.Type = int32(current.value)
+		}
+	}
+	return nil
+}
+
+// The values may be either raw data or float data
+func (tensor *Tensor) unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, current := range fields {
+		switch current.number {
+		case 1:
+			if tensor.Dims, err = appendVarints(tensor.Dims, current); err != nil {
+				return err
+			}
+		case 2:
+			tensor.DataType = int32(current.value)
+		case 4:
+			if tensor.Values, err = appendFloats(tensor.Values, current); err != nil {
+				return err
+			}
+		case 8:
+			tensor.Name = string(current.data)
+		case 9:
+			if len(current.data)%4 != 0 {
+				return fmt.Errorf("Can't decode the raw data of tensor %v", tensor.Name)
+			}
+			for i := 0; i < len(current.data); i += 4 {
+				tensor.Values = append(tensor.Values, math.Float32frombits(binary.LittleEndian.Uint32(current.data[i:])))
+			}
+		}
+	}
+	return nil
+}
+
+func (info *ValueInfo) unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, current := range fields {
+		switch current.number {
+		case 1:
+			info.Name = string(current.data)
+		case 2:
+			if err := info.unmarshalType(current.data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Decodes the TypeProto of a ValueInfo, which must be a tensor type
+func (info *ValueInfo) unmarshalType(data []byte) error {
+	typeFields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, typeField := range typeFields {
+		if typeField.number != 1 {
+			continue
+		}
+		tensorFields, err := parseFields(typeField.data)
+		if err != nil {
+			return err
+		}
+		for _, tensorField := range tensorFields {
+			switch tensorField.number {
+			case 1:
+				info.ElemType = int32(tensorField.value)
+			case 2:
+				dimensions, err := parseFields(tensorField.data)
+				if err != nil {
+					return err
+				}
+				for _, dimension := range dimensions {
+					dimensionFields, err := parseFields(dimension.data)
+					if err != nil {
+						return err
+					}
+					value, param := int64(0), ""
+					for _, dimensionField := range dimensionFields {
+						switch dimensionField.number {
+						case 1:
+							value = int64(dimensionField.value)
+						case 2:
+							param = string(dimensionField.data)
+						}
+					}
+					info.Dims = append(info.Dims, value)
+					info.DimParams = append(info.DimParams, param)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Wire types of the protobuf encoding
+const (
+	wireVarint  int = 0
+	wireFixed64 int = 1
+	wireBytes   int = 2
+	wireFixed32 int = 5
+)
+
+// Appends the varint encoding of the value to buffer
+func appendUvarint(buffer []byte, value uint64) []byte {
+	var encoded [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(encoded[:], value)
+	return append(buffer, encoded[:n]...)
+}
+
+// Appends the little endian encoding of the value to buffer
+func appendFloat32(buffer []byte, value float32) []byte {
+	var encoded [4]byte
+	binary.LittleEndian.PutUint32(encoded[:], math.Float32bits(value))
+	return append(buffer, encoded[:]...)
+}
+
+// Appends the fields of a message in the protobuf encoding
+type encoder struct {
+	buffer []byte
+}
+
+// Appends the key of a field
+func (e *encoder) key(field, wireType int) {
+	e.buffer = appendUvarint(e.buffer, uint64(field<<3|wireType))
+}
+
+// Appends an integer field, e.g. int64, int32 or an enum
+func (e *encoder) varint(field int, value int64) {
+	e.key(field, wireVarint)
+	e.buffer = appendUvarint(e.buffer, uint64(value))
+}
+
+// Appends a float field
+func (e *encoder) float(field int, value float32) {
+	e.key(field, wireFixed32)
+	e.buffer = appendFloat32(e.buffer, value)
+}
+
+// Appends a length delimited field
+func (e *encoder) bytes(field int, data []byte) {
+	e.key(field, wireBytes)
+	e.buffer = appendUvarint(e.buffer, uint64(len(data)))
+	e.buffer = append(e.buffer, data...)
+}
+
+// Appends a string field, omitted when empty
+func (e *encoder) string(field int, value string) {
+	if value != "" {
+		e.bytes(field, []byte(value))
+	}
+}
+
+// Appends an embedded message field
+func (e *encoder) message(field int, message interface{ marshal() []byte }) {
+	e.bytes(field, message.marshal())
+}
+
+// Appends a repeated integer field in the packed encoding
+func (e *encoder) packedVarints(field int, values []int64) {
+	var packed []byte
+	for _, value := range values {
+		packed = appendUvarint(packed, uint64(value))
+	}
+	e.bytes(field, packed)
+}
+
+// Field of an encoded message
+type field struct {
+	number   int
+	wireType int
+	// value of the varint and fixed fields
+	value uint64
+	// value of the length delimited fields
+	data []byte
+}
+
+// Splits an encoded message into its fields
+func parseFields(data []byte) ([]field, error) {
+	var fields []field
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("Can't decode the key of a field")
+		}
+		data = data[n:]
+		current := field{number: int(key >> 3), wireType: int(key & 7)}
+		switch current.wireType {
+		case wireVarint:
+			if current.value, n = binary.Uvarint(data); n <= 0 {
+				return nil, fmt.Errorf("Can't decode the varint of field %v", current.number)
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, fmt.Errorf("Can't decode the fixed64 of field %v", current.number)
+			}
+			current.value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, fmt.Errorf("Can't decode the fixed32 of field %v", current.number)
+			}
+			current.value = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, fmt.Errorf("Can't decode the length of field %v", current.number)
+			}
+			current.data = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("Can't decode field %v of wire type %v", current.number, current.wireType)
+		}
+		fields = append(fields, current)
+	}
+	return fields, nil
+}
+
+// Decodes a repeated integer field, either packed or not, appending its
+// values to values
+func appendVarints(values []int64, current field) ([]int64, error) {
+	if current.wireType == wireVarint {
+		return append(values, int64(current.value)), nil
+	}
+	data := current.data
+	for len(data) > 0 {
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("Can't decode the packed varints of field %v", current.number)
+		}
+		values = append(values, int64(value))
+		data = data[n:]
+	}
+	return values, nil
+}
+
+// Decodes a repeated float field, either packed or not, appending its values
+// to values
+func appendFloats(values []float32, current field) ([]float32, error) {
+	if current.wireType == wireFixed32 {
+		return append(values, math.Float32frombits(uint32(current.value))), nil
+	}
+	if len(current.data)%4 != 0 {
+		return nil, fmt.Errorf("Can't decode the packed floats of field %v", current.number)
+	}
+	for i := 0; i < len(current.data); i += 4 {
+		values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(current.data[i:])))
+	}
+	return values, nil
+}
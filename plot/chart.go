@@ -0,0 +1,264 @@
+// Package plot renders line charts, e.g. the learning curves of a training or
+// the ROC curve of a model, as SVG or PNG images using only the standard
+// library
+package plot
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Default size of the charts in pixels
+const (
+	defaultWidth  int = 640
+	defaultHeight int = 400
+)
+
+// Margins around the area of the lines, leaving room for the title, the
+// ticks and the labels of the axes
+const (
+	marginLeft   float64 = 70
+	marginRight  float64 = 20
+	marginTop    float64 = 40
+	marginBottom float64 = 50
+)
+
+// Sizes of the text in pixels
+const (
+	titleSize float64 = 16
+	labelSize float64 = 12
+)
+
+// Approximate number of ticks of each axis
+const numberTicks int = 5
+
+// Extensions of the image formats
+const (
+	SVGExtension string = ".svg"
+	PNGExtension string = ".png"
+)
+
+// Colors of the chart, and of the series in order
+var (
+	background = color.RGBA{255, 255, 255, 255}
+	foreground = color.RGBA{0, 0, 0, 255}
+	grid       = color.RGBA{224, 224, 224, 255}
+	palette    = []color.RGBA{
+		{31, 119, 180, 255},
+		{255, 127, 14, 255},
+		{44, 160, 44, 255},
+		{214, 39, 40, 255},
+		{148, 103, 189, 255},
+		{140, 86, 75, 255},
+	}
+)
+
+// Anchors of the text, i.e. the point of the text at the given position
+const (
+	anchorStart int = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// Drawing primitives used to render a chart, whose coordinates are pixels
+// from the top left corner
+type canvas interface {
+	line(x1, y1, x2, y2 float64, stroke color.RGBA, width float64)
+	polyline(xs, ys []float64, stroke color.RGBA, width float64)
+	// The text is drawn with its baseline at y, rotated by 90 degrees
+	// counterclockwise when vertical
+	text(x, y float64, s string, size float64, anchor int, vertical bool)
+}
+
+// Series is a named line of a chart
+type Series struct {
+	Name string
+	X    []float64
+	Y    []float64
+}
+
+// Chart is a line chart of one or more series
+type Chart struct {
+	Title  string
+	XLabel string
+	YLabel string
+	Series []Series
+	Width  int
+	Height int
+	// Ranges of the axes, computed from the values of the series unless set
+	xRange, yRange *[2]float64
+}
+
+// NewChart creates an empty chart of the default size
+func NewChart(title, xLabel, yLabel string) *Chart {
+	return &Chart{Title: title, XLabel: xLabel, YLabel: yLabel, Width: defaultWidth, Height: defaultHeight}
+}
+
+// Add adds a series to the chart. Points with non-finite coordinates are
+// skipped
+func (chart *Chart) Add(name string, x, y []float64) error {
+	if len(x) != len(y) {
+		return fmt.Errorf("Can't add series %v with %v x values and %v y values", name, len(x), len(y))
+	}
+	series := Series{Name: name}
+	for i := range x {
+		if isFinite(x[i]) && isFinite(y[i]) {
+			series.X = append(series.X, x[i])
+			series.Y = append(series.Y, y[i])
+		}
+	}
+	chart.Series = append(chart.Series, series)
+	return nil
+}
+
+// SetXRange fixes the range of the x axis
+func (chart *Chart) SetXRange(min, max float64) {
+	chart.xRange = &[2]float64{min, max}
+}
+
+// SetYRange fixes the range of the y axis
+func (chart *Chart) SetYRange(min, max float64) {
+	chart.yRange = &[2]float64{min, max}
+}
+
+// True when the value is neither infinite nor NaN
+func isFinite(value float64) bool {
+	return !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+// Returns the minimum and maximum of the values of all the series, widened
+// when they're equal so that the range isn't empty
+func dataRange(series []Series, values func(Series) []float64) (min, max float64) {
+	min, max = math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, value := range values(s) {
+			min = math.Min(min, value)
+			max = math.Max(max, value)
+		}
+	}
+	if math.IsInf(min, 1) {
+		return 0, 1
+	}
+	if min == max {
+		return min - 0.5, max + 0.5
+	}
+	return min, max
+}
+
+// Returns a step of the form 1, 2 or 5 times a power of 10 giving about
+// numberTicks ticks between min and max
+func tickStep(min, max float64) float64 {
+	rough := (max - min) / float64(numberTicks)
+	magnitude := math.Pow(10, math.Floor(math.Log10(rough)))
+	for _, factor := range []float64{1, 2, 5} {
+		if factor*magnitude >= rough {
+			return factor * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// Returns the multiples of step between min and max
+func ticks(min, max, step float64) []float64 {
+	var values []float64
+	for i := math.Ceil(min/step - 1e-9); i*step <= max+step*1e-9; i++ {
+		values = append(values, i*step)
+	}
+	return values
+}
+
+// Formats a tick with the number of decimals of the step
+func formatTick(value, step float64) string {
+	decimals := int(math.Max(0, -math.Floor(math.Log10(step))))
+	if math.Abs(value) < step*1e-9 {
+		value = 0
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+// Draws the chart on the canvas
+func (chart *Chart) draw(c canvas) {
+	width, height := float64(chart.Width), float64(chart.Height)
+	left, right := marginLeft, width-marginRight
+	top, bottom := marginTop, height-marginBottom
+
+	xMin, xMax := dataRange(chart.Series, func(s Series) []float64 { return s.X })
+	if chart.xRange != nil {
+		xMin, xMax = chart.xRange[0], chart.xRange[1]
+	}
+	yMin, yMax := dataRange(chart.Series, func(s Series) []float64 { return s.Y })
+	if chart.yRange != nil {
+		yMin, yMax = chart.yRange[0], chart.yRange[1]
+	}
+	toX := func(x float64) float64 { return left + (x-xMin)/(xMax-xMin)*(right-left) }
+	toY := func(y float64) float64 { return bottom - (y-yMin)/(yMax-yMin)*(bottom-top) }
+
+	// grid and ticks
+	xStep := tickStep(xMin, xMax)
+	for _, tick := range ticks(xMin, xMax, xStep) {
+		x := toX(tick)
+		c.line(x, top, x, bottom, grid, 1)
+		c.text(x, bottom+16, formatTick(tick, xStep), labelSize, anchorMiddle, false)
+	}
+	yStep := tickStep(yMin, yMax)
+	for _, tick := range ticks(yMin, yMax, yStep) {
+		y := toY(tick)
+		c.line(left, y, right, y, grid, 1)
+		c.text(left-6, y+4, formatTick(tick, yStep), labelSize, anchorEnd, false)
+	}
+
+	// axes, title and labels
+	c.line(left, bottom, right, bottom, foreground, 1)
+	c.line(left, top, left, bottom, foreground, 1)
+	c.text(width/2, top-16, chart.Title, titleSize, anchorMiddle, false)
+	c.text((left+right)/2, height-12, chart.XLabel, labelSize, anchorMiddle, false)
+	c.text(18, (top+bottom)/2, chart.YLabel, labelSize, anchorMiddle, true)
+
+	// lines, clipped to the area of the chart
+	clip := func(value, min, max float64) float64 { return math.Max(min, math.Min(max, value)) }
+	for i, series := range chart.Series {
+		xs := make([]float64, len(series.X))
+		ys := make([]float64, len(series.Y))
+		for j := range series.X {
+			xs[j] = clip(toX(series.X[j]), left, right)
+			ys[j] = clip(toY(series.Y[j]), top, bottom)
+		}
+		c.polyline(xs, ys, palette[i%len(palette)], 2)
+	}
+
+	// legend in the top right corner
+	for i, series := range chart.Series {
+		y := top + 14 + 16*float64(i)
+		c.line(right-110, y-4, right-90, y-4, palette[i%len(palette)], 2)
+		c.text(right-84, y, series.Name, labelSize, anchorStart, false)
+	}
+}
+
+// Save writes the chart to filename as SVG or PNG, depending on its extension
+func (chart *Chart) Save(filename string) (err error) {
+	var write func(io.Writer) error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case SVGExtension:
+		write = chart.WriteSVG
+	case PNGExtension:
+		write = chart.WritePNG
+	default:
+		return fmt.Errorf("Can't save chart %v: unknown image format", filename)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return write(f)
+}
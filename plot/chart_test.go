@@ -0,0 +1,136 @@
+package plot
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTicks(t *testing.T) {
+	tables := []struct {
+		min, max      float64
+		expectedStep  float64
+		expectedTicks []string
+	}{
+		{0, 1, 0.2, []string{"0.0", "0.2", "0.4", "0.6", "0.8", "1.0"}},
+		{0.31, 0.69, 0.1, []string{"0.4", "0.5", "0.6"}},
+		{0, 1000, 200, []string{"0", "200", "400", "600", "800", "1000"}},
+		{-3, 7, 2, []string{"-2", "0", "2", "4", "6"}},
+	}
+	for _, table := range tables {
+		step := tickStep(table.min, table.max)
+		if math.Abs(step-table.expectedStep) > 1e-12 {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedStep, step)
+		}
+		var labels []string
+		for _, tick := range ticks(table.min, table.max, step) {
+			labels = append(labels, formatTick(tick, step))
+		}
+		if !reflect.DeepEqual(table.expectedTicks, labels) {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedTicks, labels)
+		}
+	}
+}
+
+// Chart of two series, one of them with a point that can't be drawn
+func testChart(t *testing.T) *Chart {
+	chart := NewChart("cost & accuracy", "Iteration", "cost")
+	if err := chart.Add("cost", []float64{0, 100, 200}, []float64{0.69, 0.4, 0.3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := chart.Add("val_cost", []float64{0, 100, 200}, []float64{0.7, math.NaN(), 0.35}); err != nil {
+		t.Fatal(err)
+	}
+	if err := chart.Add("wrong", []float64{0}, nil); err == nil {
+		t.Errorf("Expected an error adding a series of different lengths")
+	}
+	return chart
+}
+
+func TestWriteSVG(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := testChart(t).WriteSVG(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	// the image is well formed, with a line per series
+	var polylines []string
+	var texts []string
+	decoder := xml.NewDecoder(&buffer)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if element, ok := token.(xml.StartElement); ok && element.Name.Local == "polyline" {
+			for _, attribute := range element.Attr {
+				if attribute.Name.Local == "points" {
+					polylines = append(polylines, attribute.Value)
+				}
+			}
+		}
+		if data, ok := token.(xml.CharData); ok && strings.TrimSpace(string(data)) != "" {
+			texts = append(texts, string(data))
+		}
+	}
+	if len(polylines) != 2 || len(strings.Fields(polylines[0])) != 3 || len(strings.Fields(polylines[1])) != 2 {
+		t.Errorf("Expected: 2 lines of 3 and 2 points, Actual: %v\n", polylines)
+	}
+	for _, expected := range []string{"cost & accuracy", "Iteration", "val_cost", "200"} {
+		found := false
+		for _, text := range texts {
+			found = found || text == expected
+		}
+		if !found {
+			t.Errorf("Expected the text %v, Actual: %v\n", expected, texts)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := testChart(t).WritePNG(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != defaultWidth || bounds.Dy() != defaultHeight {
+		t.Errorf("Expected: %vx%v, Actual: %v\n", defaultWidth, defaultHeight, bounds)
+	}
+	// both series and some text are drawn
+	counts := map[[3]uint32]int{}
+	for x := 0; x < defaultWidth; x++ {
+		for y := 0; y < defaultHeight; y++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			counts[[3]uint32{r >> 8, g >> 8, b >> 8}]++
+		}
+	}
+	for _, c := range append(palette[:2], foreground) {
+		if counts[[3]uint32{uint32(c.R), uint32(c.G), uint32(c.B)}] == 0 {
+			t.Errorf("Expected pixels of color %v\n", c)
+		}
+	}
+}
+
+func TestSave(t *testing.T) {
+	chart := testChart(t)
+	directory := t.TempDir()
+	for _, name := range []string{"chart.svg", "chart.PNG"} {
+		if err := chart.Save(filepath.Join(directory, name)); err != nil {
+			t.Errorf("Expected no error saving %v, Actual: %v\n", name, err)
+		}
+	}
+	if err := chart.Save(filepath.Join(directory, "chart.gif")); err == nil {
+		t.Errorf("Expected an error saving to an unknown format")
+	}
+}
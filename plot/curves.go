@@ -0,0 +1,94 @@
+package plot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+// Prefix of the metrics evaluated on the validation set
+const validationPrefix string = "val_"
+
+// LearningCurves returns the charts of the cost and of every metric of the
+// records against the iteration, keyed by the name of the cost or metric. The
+// values on the training set and on the validation set, e.g. accuracy and
+// val_accuracy, are drawn on the same chart. Records without a metric are
+// skipped in its series
+func LearningCurves(records []model.HistoryRecord) (map[string]*Chart, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("Can't plot a history without records")
+	}
+	iterations := make([]float64, len(records))
+	costs := make([]float64, len(records))
+	names := map[string]bool{}
+	for i, record := range records {
+		iterations[i] = float64(record.Iteration)
+		costs[i] = record.Cost
+		for name := range record.Metrics {
+			names[name] = true
+		}
+	}
+	charts := map[string]*Chart{model.MetricCost: NewChart(model.MetricCost, "Iteration", model.MetricCost)}
+	if err := charts[model.MetricCost].Add(model.MetricCost, iterations, costs); err != nil {
+		return nil, err
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		base := strings.TrimPrefix(name, validationPrefix)
+		chart, ok := charts[base]
+		if !ok {
+			chart = NewChart(base, "Iteration", base)
+			charts[base] = chart
+		}
+		var x, y []float64
+		for i, record := range records {
+			if value, ok := record.Metrics[name]; ok {
+				x = append(x, iterations[i])
+				y = append(y, value)
+			}
+		}
+		if err := chart.Add(name, x, y); err != nil {
+			return nil, err
+		}
+	}
+	return charts, nil
+}
+
+// Returns the coordinates of the points
+func coordinates(points []model.CurvePoint) (x, y []float64) {
+	for _, point := range points {
+		x = append(x, point.X)
+		y = append(y, point.Y)
+	}
+	return x, y
+}
+
+// ROCChart returns the chart of a ROC curve, along with the diagonal of a
+// random classifier. The area under the curve is written in the title
+func ROCChart(points []model.CurvePoint) *Chart {
+	chart := NewChart(fmt.Sprintf("ROC curve (AUC %.3f)", model.AreaUnderCurve(points)), "False positive rate", "True positive rate")
+	chart.SetXRange(0, 1)
+	chart.SetYRange(0, 1)
+	x, y := coordinates(points)
+	chart.Add("model", x, y)
+	chart.Add("random", []float64{0, 1}, []float64{0, 1})
+	return chart
+}
+
+// PrecisionRecallChart returns the chart of a precision-recall curve. The area
+// under the curve is written in the title
+func PrecisionRecallChart(points []model.CurvePoint) *Chart {
+	chart := NewChart(fmt.Sprintf("Precision-recall curve (AUC %.3f)", model.AreaUnderCurve(points)), "Recall", "Precision")
+	chart.SetXRange(0, 1)
+	chart.SetYRange(0, 1)
+	x, y := coordinates(points)
+	chart.Add("model", x, y)
+	return chart
+}
@@ -0,0 +1,60 @@
+package plot
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+func TestLearningCurves(t *testing.T) {
+	records := []model.HistoryRecord{
+		{Iteration: 10, Cost: 0.6, Metrics: map[string]float64{"val_cost": 0.65, "val_accuracy": 0.7}},
+		{Iteration: 20, Cost: 0.5, Metrics: map[string]float64{"val_cost": 0.55}},
+		{Iteration: 30, Cost: 0.4, Metrics: map[string]float64{"val_cost": 0.5, "val_accuracy": 0.8}},
+	}
+	charts, err := LearningCurves(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]Series{
+		"cost": {
+			{Name: "cost", X: []float64{10, 20, 30}, Y: []float64{0.6, 0.5, 0.4}},
+			{Name: "val_cost", X: []float64{10, 20, 30}, Y: []float64{0.65, 0.55, 0.5}},
+		},
+		"accuracy": {
+			{Name: "val_accuracy", X: []float64{10, 30}, Y: []float64{0.7, 0.8}},
+		},
+	}
+	if len(charts) != len(expected) {
+		t.Errorf("Expected: %v charts, Actual: %v\n", len(expected), charts)
+	}
+	for name, series := range expected {
+		chart, ok := charts[name]
+		if !ok {
+			t.Errorf("Expected the chart %v\n", name)
+			continue
+		}
+		if !reflect.DeepEqual(series, chart.Series) {
+			t.Errorf("Expected: %v, Actual: %v\n", series, chart.Series)
+		}
+	}
+
+	if _, err := LearningCurves(nil); err == nil {
+		t.Errorf("Expected an error plotting an empty history")
+	}
+}
+
+func TestCurveCharts(t *testing.T) {
+	points := []model.CurvePoint{{X: 0, Y: 0, Threshold: math.Inf(1)}, {X: 0, Y: 1, Threshold: 0.9}, {X: 1, Y: 1, Threshold: 0.1}}
+	roc := ROCChart(points)
+	if !strings.Contains(roc.Title, "AUC 1.000") || len(roc.Series) != 2 || len(roc.Series[0].X) != 3 {
+		t.Errorf("Unexpected chart: %+v\n", roc)
+	}
+	pr := PrecisionRecallChart(points)
+	if !strings.Contains(pr.Title, "AUC 1.000") || len(pr.Series) != 1 {
+		t.Errorf("Unexpected chart: %+v\n", pr)
+	}
+}
@@ -0,0 +1,207 @@
+package plot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+// Pixels between the tiles of the weights
+const tileGap int = 2
+
+// Fraction of the range of the data added around it in a decision boundary
+const boundaryPadding float64 = 0.1
+
+// Radius in pixels of the examples drawn over a decision boundary
+const pointRadius int = 4
+
+// Colors of the classes 0 and 1 in a decision boundary, the probabilities in
+// between are blended with white
+var (
+	class0Color = color.RGBA{49, 99, 190, 255}
+	class1Color = color.RGBA{200, 50, 45, 255}
+)
+
+// WeightTiles returns an image with a tile per hidden unit showing its
+// weights, i.e. a row of W1, reshaped to an image of the given size. The
+// weights are laid out as the features of the images, i.e. the value of the
+// row i, column j and channel c is the weight i*width*channels + j*channels +
+// c, and channels is either 1 for grayscale or 3 for RGB. Each tile is
+// normalized to its own minimum and maximum
+func WeightTiles(W1 matrix.NumberArray, height, width, channels int) (*image.RGBA, error) {
+	if channels != 1 && channels != 3 {
+		return nil, fmt.Errorf("Can't draw weights with %v channels", channels)
+	}
+	if W1.GetColumns() != height*width*channels {
+		return nil, fmt.Errorf("Can't reshape %v weights to images of %vx%vx%v", W1.GetColumns(), height, width, channels)
+	}
+	units := W1.GetRows()
+	columns := int(math.Ceil(math.Sqrt(float64(units))))
+	rows := (units + columns - 1) / columns
+	img := image.NewRGBA(image.Rect(0, 0, columns*(width+tileGap)+tileGap, rows*(height+tileGap)+tileGap))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.ZP, draw.Src)
+
+	for unit, weights := range matrix.ToSlice(W1) {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, weight := range weights {
+			min = math.Min(min, weight)
+			max = math.Max(max, weight)
+		}
+		intensity := func(weight float64) uint8 {
+			if max == min {
+				return 128
+			}
+			return uint8(math.Round(255 * (weight - min) / (max - min)))
+		}
+		left := tileGap + (unit%columns)*(width+tileGap)
+		top := tileGap + (unit/columns)*(height+tileGap)
+		for i := 0; i < height; i++ {
+			for j := 0; j < width; j++ {
+				feature := i*width*channels + j*channels
+				pixel := color.RGBA{A: 255}
+				pixel.R = intensity(weights[feature])
+				pixel.G, pixel.B = pixel.R, pixel.R
+				if channels == 3 {
+					pixel.G = intensity(weights[feature+1])
+					pixel.B = intensity(weights[feature+2])
+				}
+				img.SetRGBA(left+j, top+i, pixel)
+			}
+		}
+	}
+	return img, nil
+}
+
+// Returns the color of a probability, from the color of the class 0 through
+// white to the color of the class 1
+func probabilityColor(probability float64) color.RGBA {
+	target, weight := class1Color, 2*probability-1
+	if probability < 0.5 {
+		target, weight = class0Color, 1-2*probability
+	}
+	blend := func(value uint8) uint8 {
+		return uint8(math.Round(255 + weight*(float64(value)-255)))
+	}
+	return color.RGBA{blend(target.R), blend(target.G), blend(target.B), 255}
+}
+
+// DecisionBoundary returns a heatmap of size x size pixels of the probability
+// predicted by the network over the plane of the 2 features of X, with the
+// decision boundary at a probability of 0.5 drawn in black and the examples of
+// X overlaid with the colors of their classes in Y. The pipeline, which may be
+// nil, is applied to the points of the plane before predicting
+func DecisionBoundary(parameters *model.Parameters, pipeline preprocessing.Pipeline, X, Y matrix.NumberArray, size int) (*image.RGBA, error) {
+	if X.GetRows() != 2 {
+		return nil, fmt.Errorf("Can't draw the decision boundary of %v features, expected 2", X.GetRows())
+	}
+	if Y.GetColumns() != X.GetColumns() {
+		return nil, fmt.Errorf("Can't draw %v examples with %v labels", X.GetColumns(), Y.GetColumns())
+	}
+	if size < 2 {
+		return nil, fmt.Errorf("Can't draw a decision boundary of %v pixels", size)
+	}
+	examples := matrix.ToSlice(X)
+	var bounds [2][2]float64
+	for feature, values := range examples {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, value := range values {
+			min = math.Min(min, value)
+			max = math.Max(max, value)
+		}
+		padding := boundaryPadding * math.Max(max-min, 1e-9)
+		bounds[feature] = [2]float64{min - padding, max + padding}
+	}
+	// the centre of the pixel in column j and row i, with the second feature
+	// increasing upwards
+	toFeatures := func(i, j int) (float64, float64) {
+		x := bounds[0][0] + (float64(j)+0.5)/float64(size)*(bounds[0][1]-bounds[0][0])
+		y := bounds[1][1] - (float64(i)+0.5)/float64(size)*(bounds[1][1]-bounds[1][0])
+		return x, y
+	}
+	toPixel := func(x, y float64) (int, int) {
+		i := int((bounds[1][1] - y) / (bounds[1][1] - bounds[1][0]) * float64(size))
+		j := int((x - bounds[0][0]) / (bounds[0][1] - bounds[0][0]) * float64(size))
+		return i, j
+	}
+
+	// the plane is predicted one row of pixels at a time
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	above := make([][]bool, size)
+	grid, _ := matrix.NewMatrix(2, size)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			x, y := toFeatures(i, j)
+			grid.SetValue(0, j, x)
+			grid.SetValue(1, j, y)
+		}
+		var input matrix.NumberArray = grid
+		if pipeline != nil {
+			var err error
+			if input, err = pipeline.Transform(grid); err != nil {
+				return nil, err
+			}
+		}
+		if input.GetRows() != parameters.W1.GetColumns() {
+			return nil, fmt.Errorf("Can't predict %v features with a model of %v features", input.GetRows(), parameters.W1.GetColumns())
+		}
+		probabilities := model.PredictProbabilities(parameters, input)
+		above[i] = make([]bool, size)
+		for j := 0; j < size; j++ {
+			probability, _ := probabilities.GetValue(0, j)
+			above[i][j] = probability >= 0.5
+			img.SetRGBA(j, i, probabilityColor(probability))
+		}
+	}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if (i > 0 && above[i][j] != above[i-1][j]) || (j > 0 && above[i][j] != above[i][j-1]) {
+				img.SetRGBA(j, i, foreground)
+			}
+		}
+	}
+
+	labels := matrix.ToSlice(Y)[0]
+	for example := range labels {
+		fill := class0Color
+		if labels[example] > 0.5 {
+			fill = class1Color
+		}
+		ci, cj := toPixel(examples[0][example], examples[1][example])
+		for di := -pointRadius; di <= pointRadius; di++ {
+			for dj := -pointRadius; dj <= pointRadius; dj++ {
+				distance := di*di + dj*dj
+				if distance > pointRadius*pointRadius {
+					continue
+				}
+				pixel := fill
+				if distance > (pointRadius-1)*(pointRadius-1) {
+					pixel = foreground
+				}
+				img.SetRGBA(cj+dj, ci+di, pixel)
+			}
+		}
+	}
+	return img, nil
+}
+
+// SavePNG writes the image to filename as PNG
+func SavePNG(filename string, img image.Image) (err error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return png.Encode(f, img)
+}
@@ -0,0 +1,120 @@
+package plot
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+func TestWeightTiles(t *testing.T) {
+	// 5 units of 2x3 RGB images
+	weights := make([][]float64, 5)
+	for unit := range weights {
+		weights[unit] = make([]float64, 18)
+		for i := range weights[unit] {
+			weights[unit][i] = float64(unit * i)
+		}
+	}
+	W1, _ := matrix.NewMatrixFromSlice(weights)
+	img, err := WeightTiles(W1, 2, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a grid of 3 columns and 2 rows of tiles
+	if bounds := img.Bounds(); bounds.Dx() != 17 || bounds.Dy() != 10 {
+		t.Errorf("Expected: 17x10, Actual: %v\n", bounds)
+	}
+	tables := []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		// the weights of the first unit are all 0
+		{2, 2, color.RGBA{128, 128, 128, 255}},
+		// the first and last pixels of the second unit
+		{7, 2, color.RGBA{0, 15, 30, 255}},
+		{9, 3, color.RGBA{225, 240, 255, 255}},
+		// the fourth unit starts the second row of tiles
+		{2, 6, color.RGBA{0, 15, 30, 255}},
+		// gaps and the missing sixth tile
+		{1, 1, background},
+		{13, 7, background},
+	}
+	for _, table := range tables {
+		if actual := img.RGBAAt(table.x, table.y); actual != table.expected {
+			t.Errorf("(%v, %v) Expected: %v, Actual: %v\n", table.x, table.y, table.expected, actual)
+		}
+	}
+
+	grayscale, err := WeightTiles(W1, 3, 6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := grayscale.RGBAAt(7, 2); actual.R != actual.G || actual.G != actual.B {
+		t.Errorf("Expected a gray pixel, Actual: %v\n", actual)
+	}
+	if _, err := WeightTiles(W1, 64, 64, 3); err == nil {
+		t.Errorf("Expected an error reshaping to the wrong size")
+	}
+	if _, err := WeightTiles(W1, 3, 3, 2); err == nil {
+		t.Errorf("Expected an error with 2 channels")
+	}
+}
+
+// Returns the probability predicted for the point x, y
+func predictPoint(t *testing.T, parameters *model.Parameters, pipeline preprocessing.Pipeline, x, y float64) float64 {
+	var input matrix.NumberArray
+	input, _ = matrix.NewMatrixFromSlice([][]float64{{x}, {y}})
+	if pipeline != nil {
+		var err error
+		if input, err = pipeline.Transform(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	probability, _ := model.PredictProbabilities(parameters, input).GetValue(0, 0)
+	return probability
+}
+
+func TestDecisionBoundary(t *testing.T) {
+	// predicts the class 1 when the first feature is positive
+	W1, _ := matrix.NewMatrixFromSlice([][]float64{{1, 0}})
+	B1, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	W2, _ := matrix.NewMatrixFromSlice([][]float64{{10}})
+	B2, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	parameters := &model.Parameters{W1: W1, B1: B1, W2: W2, B2: B2}
+	X, _ := matrix.NewMatrixFromSlice([][]float64{{-1, 1}, {-1, 1}})
+	Y, _ := matrix.NewMatrixFromSlice([][]float64{{0, 1}})
+
+	for _, pipeline := range []preprocessing.Pipeline{nil, {preprocessing.NewRescaler(2)}} {
+		img, err := DecisionBoundary(parameters, pipeline, X, Y, 60)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the examples are at 1/12 and 11/12 of the image, the boundary in the
+		// middle
+		tables := []struct {
+			x, y     int
+			expected color.RGBA
+		}{
+			{5, 54, class0Color},
+			{54, 5, class1Color},
+			{30, 20, foreground},
+			{0, 0, probabilityColor(predictPoint(t, parameters, pipeline, -1.18, 1.18))},
+		}
+		for _, table := range tables {
+			if actual := img.RGBAAt(table.x, table.y); actual != table.expected {
+				t.Errorf("(%v, %v) Expected: %v, Actual: %v\n", table.x, table.y, table.expected, actual)
+			}
+		}
+		if corner := img.RGBAAt(59, 59); corner.R <= corner.B {
+			t.Errorf("Expected the color of the class 1, Actual: %v\n", corner)
+		}
+	}
+
+	X3, _ := matrix.NewMatrixFromSlice([][]float64{{1}, {2}, {3}})
+	if _, err := DecisionBoundary(parameters, nil, X3, Y, 60); err == nil {
+		t.Errorf("Expected an error with 3 features")
+	}
+}
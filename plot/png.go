@@ -0,0 +1,99 @@
+package plot
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+)
+
+// Canvas drawing the primitives on an image, with the bitmap font of
+// font.go
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+// Sets a square of width pixels centred at x, y
+func (c *pngCanvas) dot(x, y float64, stroke color.RGBA, width float64) {
+	half := int(math.Floor(width / 2))
+	cx, cy := int(math.Round(x)), int(math.Round(y))
+	for i := cx - half; i < cx-half+int(math.Max(width, 1)); i++ {
+		for j := cy - half; j < cy-half+int(math.Max(width, 1)); j++ {
+			c.img.SetRGBA(i, j, stroke)
+		}
+	}
+}
+
+func (c *pngCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA, width float64) {
+	steps := int(math.Ceil(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))))
+	if steps == 0 {
+		c.dot(x1, y1, stroke, width)
+		return
+	}
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		c.dot(x1+t*(x2-x1), y1+t*(y2-y1), stroke, width)
+	}
+}
+
+func (c *pngCanvas) polyline(xs, ys []float64, stroke color.RGBA, width float64) {
+	if len(xs) == 1 {
+		c.dot(xs[0], ys[0], stroke, 2*width)
+	}
+	for i := 1; i < len(xs); i++ {
+		c.line(xs[i-1], ys[i-1], xs[i], ys[i], stroke, width)
+	}
+}
+
+// The text is drawn in upper case, scaled by an integer factor close to
+// size / 10
+func (c *pngCanvas) text(x, y float64, s string, size float64, anchor int, vertical bool) {
+	s = strings.ToUpper(s)
+	scale := int(math.Max(1, math.Round(size/10)))
+	length := float64(len(s) * glyphAdvance * scale)
+	offset := 0.0
+	switch anchor {
+	case anchorMiddle:
+		offset = -length / 2
+	case anchorEnd:
+		offset = -length
+	}
+	// set maps a pixel of the unrotated text, relative to the start of its
+	// baseline, to the image
+	set := func(dx, dy int) {
+		px, py := x+offset+float64(dx), y-float64(glyphHeight*scale)+float64(dy)
+		if vertical {
+			px, py = x-float64(glyphHeight*scale)+float64(dy), y-offset-float64(dx)
+		}
+		c.img.SetRGBA(int(math.Round(px)), int(math.Round(py)), foreground)
+	}
+	for i, r := range s {
+		rows, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		for row, bits := range rows {
+			for column := 0; column < glyphWidth; column++ {
+				if bits&(1<<uint(glyphWidth-1-column)) == 0 {
+					continue
+				}
+				for sx := 0; sx < scale; sx++ {
+					for sy := 0; sy < scale; sy++ {
+						set((i*glyphAdvance+column)*scale+sx, row*scale+sy)
+					}
+				}
+			}
+		}
+	}
+}
+
+// WritePNG writes the chart as a PNG image
+func (chart *Chart) WritePNG(w io.Writer) error {
+	img := image.NewRGBA(image.Rect(0, 0, chart.Width, chart.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.ZP, draw.Src)
+	chart.draw(&pngCanvas{img: img})
+	return png.Encode(w, img)
+}
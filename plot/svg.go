@@ -0,0 +1,57 @@
+package plot
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// Canvas writing the primitives as SVG elements
+type svgCanvas struct {
+	w *bufio.Writer
+}
+
+// Returns the color in the hexadecimal notation, e.g. #ff0000
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (c *svgCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA, width float64) {
+	fmt.Fprintf(c.w, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%v" stroke-width="%v"/>`+"\n",
+		x1, y1, x2, y2, hexColor(stroke), width)
+}
+
+func (c *svgCanvas) polyline(xs, ys []float64, stroke color.RGBA, width float64) {
+	points := make([]string, len(xs))
+	for i := range xs {
+		points[i] = fmt.Sprintf("%.1f,%.1f", xs[i], ys[i])
+	}
+	fmt.Fprintf(c.w, `<polyline points="%v" fill="none" stroke="%v" stroke-width="%v" stroke-linejoin="round"/>`+"\n",
+		strings.Join(points, " "), hexColor(stroke), width)
+}
+
+func (c *svgCanvas) text(x, y float64, s string, size float64, anchor int, vertical bool) {
+	anchors := map[int]string{anchorStart: "start", anchorMiddle: "middle", anchorEnd: "end"}
+	transform := ""
+	if vertical {
+		transform = fmt.Sprintf(` transform="rotate(-90 %.1f %.1f)"`, x, y)
+	}
+	fmt.Fprintf(c.w, `<text x="%.1f" y="%.1f" font-family="sans-serif" font-size="%v" text-anchor="%v"%v>`,
+		x, y, size, anchors[anchor], transform)
+	xml.EscapeText(c.w, []byte(s))
+	fmt.Fprintln(c.w, "</text>")
+}
+
+// WriteSVG writes the chart as an SVG image
+func (chart *Chart) WriteSVG(w io.Writer) error {
+	c := &svgCanvas{w: bufio.NewWriter(w)}
+	fmt.Fprintf(c.w, `<svg xmlns="http://www.w3.org/2000/svg" width="%v" height="%v" viewBox="0 0 %v %v">`+"\n",
+		chart.Width, chart.Height, chart.Width, chart.Height)
+	fmt.Fprintf(c.w, `<rect width="100%%" height="100%%" fill="%v"/>`+"\n", hexColor(background))
+	chart.draw(c)
+	fmt.Fprintln(c.w, "</svg>")
+	return c.w.Flush()
+}
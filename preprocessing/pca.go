@@ -0,0 +1,371 @@
+package preprocessing
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Maximum number of sweeps of the Jacobi eigenvalue algorithm
+const maxJacobiSweeps = 100
+
+// Extra components drawn by the randomized PCA to capture the kept ones
+// accurately
+const pcaOversampling = 10
+
+// Number of power iterations of the randomized PCA, which sharpen the
+// separation between the kept components and the rest
+const pcaPowerIterations = 4
+
+// Smallest variance a component is scaled by, so that the components without
+// variance, e.g. of constant pixels, aren't scaled to infinity when Epsilon is
+// 0
+const pcaMinVariance = 1e-12
+
+// PCAWhitening projects the centered inputs onto the principal components of
+// the training set, and scales each of them to unit variance. The output has
+// one row per component instead of one per feature
+type PCAWhitening struct {
+	// Number of principal components kept, all of them when 0
+	NumComponents int `json:"num_components"`
+	// Added to the variances before scaling, must not be negative
+	Epsilon float64 `json:"epsilon"`
+	// Mean of each feature in the training set
+	Mean []float64 `json:"mean"`
+	// Principal components, one per row, divided by the square root of their
+	// variance
+	Components [][]float64 `json:"components"`
+}
+
+// NewPCAWhitening creates a PCAWhitening keeping numComponents components, or
+// all of them if numComponents is 0. Fit returns an error if epsilon is
+// negative
+func NewPCAWhitening(numComponents int, epsilon float64) *PCAWhitening {
+	return &PCAWhitening{NumComponents: numComponents, Epsilon: epsilon}
+}
+
+// Fit learns the principal components of the centered X. When only a few
+// components are kept they are found with a randomized PCA, which only
+// multiplies X by thin matrices. Otherwise the eigenvectors of the covariance
+// matrix or of the Gram matrix of the examples, whichever is smaller, are
+// computed, so the cost grows with the cube of the smallest of the number of
+// features and the number of examples. Keeping all the components of an input
+// with more features than examples keeps as many components as examples. The
+// variances are at least pcaMinVariance, and an error is returned if any
+// whitened component isn't finite, e.g. when X isn't finite
+func (pca *PCAWhitening) Fit(X matrix.NumberArray) error {
+	if pca.Epsilon < 0 || math.IsNaN(pca.Epsilon) {
+		return fmt.Errorf("Can't fit PCAWhitening with an epsilon of %v", pca.Epsilon)
+	}
+	n, m := X.GetRows(), X.GetColumns()
+	if pca.NumComponents < 0 || pca.NumComponents > n {
+		return fmt.Errorf("Can't keep %v components of an input with %v"+
+			" features", pca.NumComponents, n)
+	}
+	rank := n
+	if m < n {
+		rank = m
+	}
+	if pca.NumComponents > rank {
+		return fmt.Errorf("Can't keep %v components of %v examples", pca.NumComponents, m)
+	}
+	numComponents := pca.NumComponents
+	if numComponents == 0 {
+		numComponents = rank
+	}
+	pca.Mean = featureMeans(X)
+	centered := applyFeatureFunc(X, func(i int, val float64) float64 {
+		return val - pca.Mean[i]
+	})
+
+	var variances []float64
+	var components [][]float64
+	var err error
+	switch {
+	case numComponents+pcaOversampling < rank:
+		variances, components, err = randomizedComponents(centered, numComponents)
+	case n <= m:
+		variances, components, err = covarianceComponents(centered)
+	default:
+		variances, components, err = gramComponents(centered)
+	}
+	if err != nil {
+		return err
+	}
+	whitened := make([][]float64, numComponents)
+	for k := range whitened {
+		variance := math.Max(variances[k], 0) + pca.Epsilon
+		if variance < pcaMinVariance {
+			variance = pcaMinVariance
+		}
+		scale := 1 / math.Sqrt(variance)
+		whitened[k] = make([]float64, n)
+		for i := 0; i < n; i++ {
+			whitened[k][i] = components[k][i] * scale
+			if math.IsNaN(whitened[k][i]) || math.IsInf(whitened[k][i], 0) {
+				return fmt.Errorf("Can't whiten component %v with a variance of %v", k, variances[k])
+			}
+		}
+	}
+	pca.Components = whitened
+	return nil
+}
+
+// Returns an error if the components don't match the features of the mean
+func (pca *PCAWhitening) check() error {
+	for k, component := range pca.Components {
+		if len(component) != len(pca.Mean) {
+			return fmt.Errorf("Can't apply PCAWhitening with a component %v of"+
+				" %v features and a mean of %v features", k, len(component), len(pca.Mean))
+		}
+	}
+	if len(pca.Mean) > 0 && len(pca.Components) == 0 {
+		return fmt.Errorf("Can't apply PCAWhitening without components")
+	}
+	return nil
+}
+
+// Transform centers X and projects it onto the whitened components
+func (pca *PCAWhitening) Transform(X matrix.NumberArray) (matrix.NumberArray, error) {
+	if err := pca.check(); err != nil {
+		return nil, err
+	}
+	if err := checkFitted("PCAWhitening", len(pca.Mean), X); err != nil {
+		return nil, err
+	}
+	centered := applyFeatureFunc(X, func(i int, val float64) float64 {
+		return val - pca.Mean[i]
+	})
+	components, err := matrix.NewMatrixFromSlice(pca.Components)
+	if err != nil {
+		return nil, err
+	}
+	return matrix.Dot(components, centered)
+}
+
+// Returns the transpose of a without modifying it
+func transposed(a matrix.NumberArray) matrix.NumberArray {
+	return matrix.Copy(a).Transpose()
+}
+
+// Returns the eigenvalues of the symmetric matrix a in decreasing order, and
+// their eigenvectors, one per row. When basis isn't nil the eigenvectors are
+// multiplied by it, i.e. mapped from the columns of basis to its rows
+func sortedEigen(a, basis matrix.NumberArray) ([]float64, [][]float64, error) {
+	eigenvalues, eigenvectors := symmetricEigen(matrix.ToSlice(a))
+	var V matrix.NumberArray
+	V, err := matrix.NewMatrixFromSlice(eigenvectors)
+	if err != nil {
+		return nil, nil, err
+	}
+	if basis != nil {
+		if V, err = matrix.Dot(basis, V); err != nil {
+			return nil, nil, err
+		}
+	}
+	columns := matrix.ToSlice(transposed(V))
+	order := make([]int, len(eigenvalues))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return eigenvalues[order[a]] > eigenvalues[order[b]]
+	})
+	sortedValues := make([]float64, len(order))
+	sortedVectors := make([][]float64, len(order))
+	for k, i := range order {
+		sortedValues[k] = eigenvalues[i]
+		sortedVectors[k] = columns[i]
+	}
+	return sortedValues, sortedVectors, nil
+}
+
+// Returns the variances and the principal components of the centered data,
+// from the eigendecomposition of its covariance matrix
+func covarianceComponents(centered matrix.NumberArray) ([]float64, [][]float64, error) {
+	covariance, err := matrix.Dot(centered, transposed(centered))
+	if err != nil {
+		return nil, nil, err
+	}
+	covariance = matrix.MultiplyScalar(covariance, 1.0/float64(centered.GetColumns()))
+	return sortedEigen(covariance, nil)
+}
+
+// Returns the variances and the principal components of the centered data,
+// from the eigendecomposition of the Gram matrix of its examples. When v is an
+// eigenvector of the Gram matrix with eigenvalue m*variance, X*v is a
+// principal component with norm sqrt(m*variance)
+func gramComponents(centered matrix.NumberArray) ([]float64, [][]float64, error) {
+	m := float64(centered.GetColumns())
+	gram, err := matrix.Dot(transposed(centered), centered)
+	if err != nil {
+		return nil, nil, err
+	}
+	gram = matrix.MultiplyScalar(gram, 1/m)
+	variances, components, err := sortedEigen(gram, centered)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, component := range components {
+		norm := math.Sqrt(math.Max(variances[k], 0) * m)
+		for i := range component {
+			// the directions without variance are left out of the output
+			if variances[k] <= 1e-12*variances[0] {
+				component[i] = 0
+			} else {
+				component[i] /= norm
+			}
+		}
+	}
+	return variances, components, nil
+}
+
+// Returns the variances and the numComponents first principal components of
+// the centered data with a randomized PCA: the range of the data is
+// approximated by projecting it on a few random directions, refined with
+// power iterations, and the components are found within that range
+func randomizedComponents(centered matrix.NumberArray, numComponents int) ([]float64, [][]float64, error) {
+	m := centered.GetColumns()
+	samples := numComponents + pcaOversampling
+	// the same random directions on every fit, so that it's reproducible
+	random := rand.New(rand.NewSource(1))
+	directions := make([][]float64, m)
+	for j := range directions {
+		directions[j] = make([]float64, samples)
+		for k := range directions[j] {
+			directions[j][k] = random.NormFloat64()
+		}
+	}
+	omega, err := matrix.NewMatrixFromSlice(directions)
+	if err != nil {
+		return nil, nil, err
+	}
+	centeredT := transposed(centered)
+	Y, err := matrix.Dot(centered, omega)
+	if err != nil {
+		return nil, nil, err
+	}
+	Q := orthonormalColumns(Y)
+	for iteration := 0; iteration < pcaPowerIterations; iteration++ {
+		Z, err := matrix.Dot(centeredT, Q)
+		if err != nil {
+			return nil, nil, err
+		}
+		if Y, err = matrix.Dot(centered, orthonormalColumns(Z)); err != nil {
+			return nil, nil, err
+		}
+		Q = orthonormalColumns(Y)
+	}
+	// covariance of the data projected onto the range Q
+	B, err := matrix.Dot(transposed(Q), centered)
+	if err != nil {
+		return nil, nil, err
+	}
+	covariance, err := matrix.Dot(B, transposed(B))
+	if err != nil {
+		return nil, nil, err
+	}
+	covariance = matrix.MultiplyScalar(covariance, 1.0/float64(m))
+	variances, components, err := sortedEigen(covariance, Q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return variances[:numComponents], components[:numComponents], nil
+}
+
+// Returns an array with orthonormal columns spanning the columns of a, found
+// with the modified Gram-Schmidt process. Columns that are dependent on the
+// previous ones are set to 0
+func orthonormalColumns(a matrix.NumberArray) matrix.NumberArray {
+	columns := matrix.ToSlice(transposed(a))
+	for k, column := range columns {
+		original := 0.0
+		for _, value := range column {
+			original += value * value
+		}
+		for _, previous := range columns[:k] {
+			projection := 0.0
+			for i := range column {
+				projection += column[i] * previous[i]
+			}
+			for i := range column {
+				column[i] -= projection * previous[i]
+			}
+		}
+		norm := 0.0
+		for _, value := range column {
+			norm += value * value
+		}
+		norm = math.Sqrt(norm)
+		for i := range column {
+			if norm <= 1e-10*math.Sqrt(original) {
+				column[i] = 0
+			} else {
+				column[i] /= norm
+			}
+		}
+	}
+	orthonormal, _ := matrix.NewMatrixFromSlice(columns)
+	return orthonormal.Transpose()
+}
+
+// Computes the eigenvalues and eigenvectors of the symmetric matrix a with the
+// cyclic Jacobi eigenvalue algorithm. The eigenvectors are the columns of the
+// returned matrix. The values of a are modified
+func symmetricEigen(a [][]float64) (eigenvalues []float64, eigenvectors [][]float64) {
+	n := len(a)
+	eigenvectors = make([][]float64, n)
+	for i := range eigenvectors {
+		eigenvectors[i] = make([]float64, n)
+		eigenvectors[i][i] = 1
+	}
+	for sweep := 0; sweep < maxJacobiSweeps; sweep++ {
+		offDiagonal := 0.0
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				offDiagonal += a[p][q] * a[p][q]
+			}
+		}
+		if offDiagonal < 1e-22 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// rotation that zeroes a[p][q]
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := eigenvectors[k][p], eigenvectors[k][q]
+					eigenvectors[k][p] = c*vkp - s*vkq
+					eigenvectors[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	eigenvalues = make([]float64, n)
+	for i := range eigenvalues {
+		eigenvalues[i] = a[i][i]
+	}
+	return eigenvalues, eigenvectors
+}
@@ -0,0 +1,258 @@
+// Package preprocessing provides the transforms applied to the inputs of the
+// neural network before training and predicting. Their statistics are learned
+// on the training set and then applied unchanged to any other input.
+//
+// As in the model package, the inputs are arrays with one feature per row and
+// one example per column
+package preprocessing
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Transform is a preprocessing step that learns its statistics with Fit, and
+// applies them to any input with the same features with Transform
+type Transform interface {
+	Fit(X matrix.NumberArray) error
+	Transform(X matrix.NumberArray) (matrix.NumberArray, error)
+}
+
+// Pipeline is a sequence of transforms applied one after the other
+type Pipeline []Transform
+
+// Fit fits each transform on the output of the previous ones
+func (pipeline Pipeline) Fit(X matrix.NumberArray) error {
+	_, err := pipeline.FitTransform(X)
+	return err
+}
+
+// FitTransform fits each transform on the output of the previous ones and
+// returns the output of the last one
+func (pipeline Pipeline) FitTransform(X matrix.NumberArray) (matrix.NumberArray, error) {
+	var err error
+	for _, transform := range pipeline {
+		if err = transform.Fit(X); err != nil {
+			return nil, err
+		}
+		if X, err = transform.Transform(X); err != nil {
+			return nil, err
+		}
+	}
+	return X, nil
+}
+
+// Transform applies all the transforms in order
+func (pipeline Pipeline) Transform(X matrix.NumberArray) (matrix.NumberArray, error) {
+	var err error
+	for _, transform := range pipeline {
+		if X, err = transform.Transform(X); err != nil {
+			return nil, err
+		}
+	}
+	return X, nil
+}
+
+// InputFeatures returns the number of features of the inputs of the fitted
+// pipeline, given the number of features of its outputs, e.g. the number of
+// inputs of the network. Only PCAWhitening changes the number of features
+func (pipeline Pipeline) InputFeatures(outputFeatures int) int {
+	numberFeatures := outputFeatures
+	for i := len(pipeline) - 1; i >= 0; i-- {
+		if pca, ok := pipeline[i].(*PCAWhitening); ok {
+			numberFeatures = len(pca.Mean)
+		}
+	}
+	return numberFeatures
+}
+
+// Check returns an error if the fitted statistics of any of the transforms
+// are inconsistent, e.g. a loaded Standardizer with fewer deviations than
+// means, which would otherwise fail while transforming
+func (pipeline Pipeline) Check() error {
+	for _, transform := range pipeline {
+		if checked, ok := transform.(interface{ check() error }); ok {
+			if err := checked.check(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns an error if the transform wasn't fitted for the features of X
+func checkFitted(name string, numberFeatures int, X matrix.NumberArray) error {
+	if numberFeatures == 0 {
+		return fmt.Errorf("Can't apply %v before fitting it", name)
+	}
+	if numberFeatures != X.GetRows() {
+		return fmt.Errorf("Can't apply %v fitted on %v features to an input"+
+			" with %v features", name, numberFeatures, X.GetRows())
+	}
+	return nil
+}
+
+// Type used for handling the functions applied to each value of a feature
+type featureFunc func(feature int, val float64) float64
+
+// Applies function to every value of X and returns the result in a new array
+func applyFeatureFunc(X matrix.NumberArray, function featureFunc) matrix.NumberArray {
+	resultingMatrix, _ := matrix.NewMatrix(X.GetRows(), X.GetColumns())
+	for i := 0; i < X.GetRows(); i++ {
+		for j := 0; j < X.GetColumns(); j++ {
+			val, _ := X.GetValue(i, j)
+			resultingMatrix.SetValue(i, j, function(i, val))
+		}
+	}
+	return resultingMatrix
+}
+
+// Returns the mean of each feature of X
+func featureMeans(X matrix.NumberArray) []float64 {
+	means := make([]float64, X.GetRows())
+	for i := range means {
+		for j := 0; j < X.GetColumns(); j++ {
+			val, _ := X.GetValue(i, j)
+			means[i] += val
+		}
+		means[i] /= float64(X.GetColumns())
+	}
+	return means
+}
+
+// Rescaler multiplies every value by a constant. It has nothing to learn,
+// but it's saved along with the rest of the transforms
+type Rescaler struct {
+	Scale float64 `json:"scale"`
+}
+
+// NewRescaler creates a Rescaler that multiplies by scale
+func NewRescaler(scale float64) *Rescaler {
+	return &Rescaler{Scale: scale}
+}
+
+// NewPixelRescaler creates a Rescaler that divides by 255, scaling pixels
+// coded with 8 bits into [0, 1]
+func NewPixelRescaler() *Rescaler {
+	return NewRescaler(1.0 / 255)
+}
+
+// Fit does nothing
+func (rescaler *Rescaler) Fit(X matrix.NumberArray) error {
+	return nil
+}
+
+// Transform multiplies every value of X by the scale
+func (rescaler *Rescaler) Transform(X matrix.NumberArray) (matrix.NumberArray, error) {
+	return matrix.MultiplyScalar(X, rescaler.Scale), nil
+}
+
+// MinMaxScaler scales each feature linearly so that its minimum and maximum
+// in the training set become RangeMin and RangeMax
+type MinMaxScaler struct {
+	RangeMin float64   `json:"range_min"`
+	RangeMax float64   `json:"range_max"`
+	Min      []float64 `json:"min"`
+	Max      []float64 `json:"max"`
+}
+
+// NewMinMaxScaler creates a MinMaxScaler into the range [rangeMin, rangeMax]
+func NewMinMaxScaler(rangeMin, rangeMax float64) *MinMaxScaler {
+	return &MinMaxScaler{RangeMin: rangeMin, RangeMax: rangeMax}
+}
+
+// Fit learns the minimum and maximum of each feature
+func (scaler *MinMaxScaler) Fit(X matrix.NumberArray) error {
+	scaler.Min = make([]float64, X.GetRows())
+	scaler.Max = make([]float64, X.GetRows())
+	for i := 0; i < X.GetRows(); i++ {
+		scaler.Min[i], scaler.Max[i] = math.Inf(1), math.Inf(-1)
+		for j := 0; j < X.GetColumns(); j++ {
+			val, _ := X.GetValue(i, j)
+			scaler.Min[i] = math.Min(scaler.Min[i], val)
+			scaler.Max[i] = math.Max(scaler.Max[i], val)
+		}
+	}
+	return nil
+}
+
+// Returns an error if there isn't a maximum for every minimum
+func (scaler *MinMaxScaler) check() error {
+	if len(scaler.Min) != len(scaler.Max) {
+		return fmt.Errorf("Can't apply MinMaxScaler with %v minimums and %v"+
+			" maximums", len(scaler.Min), len(scaler.Max))
+	}
+	return nil
+}
+
+// Transform scales the features of X. Features that were constant in the
+// training set are mapped to RangeMin
+func (scaler *MinMaxScaler) Transform(X matrix.NumberArray) (matrix.NumberArray, error) {
+	if err := scaler.check(); err != nil {
+		return nil, err
+	}
+	if err := checkFitted("MinMaxScaler", len(scaler.Min), X); err != nil {
+		return nil, err
+	}
+	return applyFeatureFunc(X, func(i int, val float64) float64 {
+		width := scaler.Max[i] - scaler.Min[i]
+		if width == 0 {
+			return scaler.RangeMin
+		}
+		return scaler.RangeMin + (val-scaler.Min[i])/width*(scaler.RangeMax-scaler.RangeMin)
+	}), nil
+}
+
+// Standardizer centers each feature at 0 and scales it to unit variance,
+// i.e: (x - mean) / std
+type Standardizer struct {
+	Mean []float64 `json:"mean"`
+	Std  []float64 `json:"std"`
+}
+
+// NewStandardizer creates a Standardizer
+func NewStandardizer() *Standardizer {
+	return new(Standardizer)
+}
+
+// Fit learns the mean and standard deviation of each feature
+func (standardizer *Standardizer) Fit(X matrix.NumberArray) error {
+	standardizer.Mean = featureMeans(X)
+	standardizer.Std = make([]float64, X.GetRows())
+	for i := range standardizer.Std {
+		for j := 0; j < X.GetColumns(); j++ {
+			val, _ := X.GetValue(i, j)
+			standardizer.Std[i] += math.Pow(val-standardizer.Mean[i], 2)
+		}
+		standardizer.Std[i] = math.Sqrt(standardizer.Std[i] / float64(X.GetColumns()))
+	}
+	return nil
+}
+
+// Returns an error if there isn't a standard deviation for every mean
+func (standardizer *Standardizer) check() error {
+	if len(standardizer.Mean) != len(standardizer.Std) {
+		return fmt.Errorf("Can't apply Standardizer with %v means and %v"+
+			" standard deviations", len(standardizer.Mean), len(standardizer.Std))
+	}
+	return nil
+}
+
+// Transform standardizes the features of X. Features that were constant in
+// the training set are only centered
+func (standardizer *Standardizer) Transform(X matrix.NumberArray) (matrix.NumberArray, error) {
+	if err := standardizer.check(); err != nil {
+		return nil, err
+	}
+	if err := checkFitted("Standardizer", len(standardizer.Mean), X); err != nil {
+		return nil, err
+	}
+	return applyFeatureFunc(X, func(i int, val float64) float64 {
+		if standardizer.Std[i] == 0 {
+			return val - standardizer.Mean[i]
+		}
+		return (val - standardizer.Mean[i]) / standardizer.Std[i]
+	}), nil
+}
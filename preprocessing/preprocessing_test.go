@@ -0,0 +1,312 @@
+package preprocessing
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+)
+
+// Creates a NumberArray from the given rows
+func newArray(values [][]float64) matrix.NumberArray {
+	m, _ := matrix.NewMatrixFromSlice(values)
+	return m
+}
+
+// True when both arrays have the same dimensions and values up to 1e-9
+func equalArrays(a, b matrix.NumberArray) bool {
+	if !matrix.EqualDimensions(a, b) {
+		return false
+	}
+	for i := 0; i < a.GetRows(); i++ {
+		for j := 0; j < a.GetColumns(); j++ {
+			valA, _ := a.GetValue(i, j)
+			valB, _ := b.GetValue(i, j)
+			if math.Abs(valA-valB) > 1e-9 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Training set with 2 features, the second one constant, and 4 examples
+var training = [][]float64{
+	{0, 50, 100, 250},
+	{3, 3, 3, 3},
+}
+
+func TestTransforms(t *testing.T) {
+	// standard deviation of the first feature of the training set
+	std := math.Sqrt(8750)
+	tables := []struct {
+		name      string
+		transform Transform
+		input     [][]float64
+		expected  [][]float64
+	}{
+		{"rescale", NewPixelRescaler(), [][]float64{{255, 51}, {0, 25.5}}, [][]float64{{1, 0.2}, {0, 0.1}}},
+		{"min max", NewMinMaxScaler(0, 1), training, [][]float64{{0, 0.2, 0.4, 1}, {0, 0, 0, 0}}},
+		{"min max range", NewMinMaxScaler(-1, 1), [][]float64{{125, 500}, {7, 3}}, [][]float64{{0, 3}, {-1, -1}}},
+		{"standardize", NewStandardizer(), training, [][]float64{{-100 / std, -50 / std, 0, 150 / std}, {0, 0, 0, 0}}},
+		{"standardize unseen", NewStandardizer(), [][]float64{{400}, {5}}, [][]float64{{300 / std}, {2}}},
+	}
+	for _, table := range tables {
+		if err := table.transform.Fit(newArray(training)); err != nil {
+			t.Fatalf("%v: %v", table.name, err)
+		}
+		actual, err := table.transform.Transform(newArray(table.input))
+		if err != nil {
+			t.Fatalf("%v: %v", table.name, err)
+		}
+		if !equalArrays(newArray(table.expected), actual) {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.name, table.expected, matrix.ToSlice(actual))
+		}
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	tables := []struct {
+		transform     Transform
+		fit           bool
+		expectedError error
+	}{
+		{NewMinMaxScaler(0, 1), false, fmt.Errorf("Can't apply MinMaxScaler before fitting it")},
+		{NewStandardizer(), true, fmt.Errorf("Can't apply Standardizer fitted on 2 features to an input with 3 features")},
+		{NewPCAWhitening(0, 0), false, fmt.Errorf("Can't apply PCAWhitening before fitting it")},
+		// statistics of inconsistent lengths, e.g. from a corrupted file
+		{&MinMaxScaler{Min: []float64{0, 0, 0}, Max: []float64{1}}, false, fmt.Errorf("Can't apply MinMaxScaler with 3 minimums and 1 maximums")},
+		{&Standardizer{Mean: []float64{0, 0, 0}, Std: []float64{1, 1}}, false, fmt.Errorf("Can't apply Standardizer with 3 means and 2 standard deviations")},
+		{&PCAWhitening{Mean: []float64{0, 0, 0}, Components: [][]float64{{1, 0}}}, false, fmt.Errorf("Can't apply PCAWhitening with a component 0 of 2 features and a mean of 3 features")},
+	}
+	for _, table := range tables {
+		if table.fit {
+			table.transform.Fit(newArray(training))
+		}
+		_, err := table.transform.Transform(newArray([][]float64{{1}, {2}, {3}}))
+		if err == nil || table.expectedError.Error() != err.Error() {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+	}
+	if err := (Pipeline{NewRescaler(2), tables[3].transform}).Check(); err == nil || err.Error() != tables[3].expectedError.Error() {
+		t.Errorf("Expected: %v, Actual: %v\n", tables[3].expectedError, err)
+	}
+	if err := (Pipeline{NewRescaler(2), NewStandardizer()}).Check(); err != nil {
+		t.Errorf("Expected no error checking an unfitted pipeline, Actual: %v\n", err)
+	}
+}
+
+func TestPCAWhitening(t *testing.T) {
+	// correlated features
+	X := newArray([][]float64{
+		{2.5, 0.5, 2.2, 1.9, 3.1, 2.3, 2.0, 1.0, 1.5, 1.1},
+		{2.4, 0.7, 2.9, 2.2, 3.0, 2.7, 1.6, 1.1, 1.6, 0.9},
+		{1.0, 2.0, 0.5, 1.5, 0.3, 0.8, 1.2, 2.1, 1.7, 1.9},
+	})
+	pca := NewPCAWhitening(0, 0)
+	whitened, err := Pipeline{pca}.FitTransform(X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the covariance of the whitened data is the identity
+	covariance, _ := matrix.Dot(whitened, matrix.Copy(whitened).Transpose())
+	covariance = matrix.MultiplyScalar(covariance, 1.0/float64(X.GetColumns()))
+	identity := newArray([][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}})
+	if !equalArrays(identity, covariance) {
+		t.Errorf("Expected: %v, Actual: %v\n", matrix.ToSlice(identity), matrix.ToSlice(covariance))
+	}
+
+	reduced := NewPCAWhitening(1, 0)
+	reduced.Fit(X)
+	actual, _ := reduced.Transform(X)
+	if actual.GetRows() != 1 || actual.GetColumns() != X.GetColumns() {
+		t.Errorf("Expected: 1x%v, Actual: %vx%v\n", X.GetColumns(), actual.GetRows(), actual.GetColumns())
+	}
+	pipelines := []struct {
+		pipeline Pipeline
+		expected int
+	}{
+		{nil, 1},
+		{Pipeline{NewRescaler(255)}, 1},
+		{Pipeline{NewRescaler(255), reduced}, 3},
+	}
+	for _, table := range pipelines {
+		if actual := table.pipeline.InputFeatures(1); actual != table.expected {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expected, actual)
+		}
+	}
+	if err := NewPCAWhitening(4, 0).Fit(X); err == nil {
+		t.Errorf("Expected an error keeping more components than features")
+	}
+}
+
+func TestPCAWhiteningWithoutVariance(t *testing.T) {
+	// the second feature of the training set is constant, so one of the
+	// components has no variance
+	pca := NewPCAWhitening(0, 0)
+	whitened, err := Pipeline{pca}.FitTransform(newArray(training))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range append(matrix.ToSlice(whitened), pca.Components...) {
+		for _, value := range row {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				t.Errorf("Expected finite values, Actual: %v\n", row)
+			}
+		}
+	}
+	if _, err := json.Marshal(Pipeline{pca}); err != nil {
+		t.Errorf("Expected no error writing the pipeline, Actual: %v\n", err)
+	}
+
+	tables := []struct {
+		pca           *PCAWhitening
+		X             [][]float64
+		expectedError error
+	}{
+		{NewPCAWhitening(0, -1e-5), training, fmt.Errorf("Can't fit PCAWhitening with an epsilon of -1e-05")},
+		{NewPCAWhitening(0, math.NaN()), training, fmt.Errorf("Can't fit PCAWhitening with an epsilon of NaN")},
+		{NewPCAWhitening(0, 0), [][]float64{{0, math.Inf(1), 1}, {1, 2, 3}}, fmt.Errorf("Can't whiten component 0 with a variance of NaN")},
+	}
+	for _, table := range tables {
+		if err := table.pca.Fit(newArray(table.X)); err == nil || err.Error() != table.expectedError.Error() {
+			t.Errorf("Expected: %v, Actual: %v\n", table.expectedError, err)
+		}
+		if table.pca.Components != nil {
+			t.Errorf("Expected no components after a failed fit, Actual: %v\n", table.pca.Components)
+		}
+	}
+}
+
+// Returns the covariance of the rows of X
+func covarianceOf(X matrix.NumberArray) [][]float64 {
+	covariance, _ := matrix.Dot(X, matrix.Copy(X).Transpose())
+	return matrix.ToSlice(matrix.MultiplyScalar(covariance, 1.0/float64(X.GetColumns())))
+}
+
+func TestPCAWhiteningLarge(t *testing.T) {
+	// 30 features that are noisy mixes of 3 latent ones, so that the first
+	// components stand out
+	random := rand.New(rand.NewSource(2))
+	mixing := make([][]float64, 30)
+	for i := range mixing {
+		mixing[i] = []float64{random.NormFloat64() * 5, random.NormFloat64() * 3, random.NormFloat64()}
+	}
+	values := make([][]float64, 30)
+	for i := range values {
+		values[i] = make([]float64, 60)
+	}
+	for j := 0; j < 60; j++ {
+		latent := []float64{random.NormFloat64(), random.NormFloat64(), random.NormFloat64()}
+		for i := range values {
+			for k := range latent {
+				values[i][j] += mixing[i][k] * latent[k]
+			}
+			values[i][j] += random.NormFloat64() * 0.01
+		}
+	}
+	X := newArray(values)
+
+	// the randomized components match the exact ones up to their sign
+	exact := NewPCAWhitening(0, 0)
+	if err := exact.Fit(X); err != nil {
+		t.Fatal(err)
+	}
+	randomized := NewPCAWhitening(2, 0)
+	if err := randomized.Fit(X); err != nil {
+		t.Fatal(err)
+	}
+	for k, component := range randomized.Components {
+		product, norm := 0.0, 0.0
+		for i := range component {
+			product += component[i] * exact.Components[k][i]
+			norm += exact.Components[k][i] * exact.Components[k][i]
+		}
+		if math.Abs(math.Abs(product/norm)-1) > 1e-6 {
+			t.Errorf("Component %v Expected: ±%v, Actual: %v\n", k, exact.Components[k], component)
+		}
+	}
+
+	// with fewer examples than features a component is kept per example, and
+	// the last one is left out since the centered examples only span 7
+	// directions
+	rows := matrix.ToSlice(X)
+	for i := range rows {
+		rows[i] = rows[i][:8]
+	}
+	few := newArray(rows)
+	gram := NewPCAWhitening(0, 0)
+	whitened, err := Pipeline{gram}.FitTransform(few)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whitened.GetRows() != 8 {
+		t.Fatalf("Expected: 8 components, Actual: %v\n", whitened.GetRows())
+	}
+	covariance := covarianceOf(whitened)
+	for k := 0; k < 8; k++ {
+		expected := 1.0
+		if k == 7 {
+			expected = 0
+		}
+		if math.Abs(covariance[k][k]-expected) > 1e-6 {
+			t.Errorf("Expected: %v, Actual: %v\n", expected, covariance[k][k])
+		}
+	}
+	if err := NewPCAWhitening(9, 0).Fit(few); err == nil {
+		t.Errorf("Expected an error keeping more components than examples")
+	}
+}
+
+func TestPipelineJSON(t *testing.T) {
+	pipeline := Pipeline{NewPixelRescaler(), NewStandardizer(), NewMinMaxScaler(-1, 1), NewPCAWhitening(1, 1e-5)}
+	expected, err := pipeline.FitTransform(newArray(training))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Pipeline
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pipeline, loaded) {
+		t.Errorf("Expected: %v, Actual: %v\n", pipeline, loaded)
+	}
+	actual, err := loaded.Transform(newArray(training))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalArrays(expected, actual) {
+		t.Errorf("Expected: %v, Actual: %v\n", matrix.ToSlice(expected), matrix.ToSlice(actual))
+	}
+	if err := json.Unmarshal([]byte(`[{"type": "unknown"}]`), &loaded); err == nil {
+		t.Errorf("Expected an error reading an unknown transform")
+	}
+}
+
+func TestPipelineGob(t *testing.T) {
+	pipeline := Pipeline{NewPixelRescaler(), NewStandardizer(), NewMinMaxScaler(-1, 1), NewPCAWhitening(1, 1e-5)}
+	if err := pipeline.Fit(newArray(training)); err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(pipeline); err != nil {
+		t.Fatal(err)
+	}
+	var loaded Pipeline
+	if err := gob.NewDecoder(&buffer).Decode(&loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pipeline, loaded) {
+		t.Errorf("Expected: %v, Actual: %v\n", pipeline, loaded)
+	}
+}
@@ -0,0 +1,95 @@
+package preprocessing
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// Names of the transforms in their serialized form
+const (
+	rescalerName     string = "rescale"
+	minMaxScalerName string = "min_max"
+	standardizerName string = "standardize"
+	pcaWhiteningName string = "pca_whitening"
+)
+
+// Registers the transforms so that pipelines can be encoded with gob, under
+// the names of their serialized form
+func init() {
+	gob.RegisterName(rescalerName, new(Rescaler))
+	gob.RegisterName(minMaxScalerName, new(MinMaxScaler))
+	gob.RegisterName(standardizerName, new(Standardizer))
+	gob.RegisterName(pcaWhiteningName, new(PCAWhitening))
+}
+
+// A transform as it's written in JSON, tagged with its name
+type transformJSON struct {
+	Type      string          `json:"type"`
+	Transform json.RawMessage `json:"transform"`
+}
+
+// TransformName returns the name of the transform in its serialized form, or
+// an empty string when it can't be serialized
+func TransformName(transform Transform) string {
+	switch transform.(type) {
+	case *Rescaler:
+		return rescalerName
+	case *MinMaxScaler:
+		return minMaxScalerName
+	case *Standardizer:
+		return standardizerName
+	case *PCAWhitening:
+		return pcaWhiteningName
+	default:
+		return ""
+	}
+}
+
+// MarshalJSON writes the transforms of the pipeline and their fitted
+// statistics as a JSON array
+func (pipeline Pipeline) MarshalJSON() ([]byte, error) {
+	tagged := make([]transformJSON, len(pipeline))
+	for i, transform := range pipeline {
+		name := TransformName(transform)
+		if name == "" {
+			return nil, fmt.Errorf("Can't serialize the transform of type %T", transform)
+		}
+		data, err := json.Marshal(transform)
+		if err != nil {
+			return nil, err
+		}
+		tagged[i] = transformJSON{Type: name, Transform: data}
+	}
+	return json.Marshal(tagged)
+}
+
+// UnmarshalJSON reads the transforms written by MarshalJSON
+func (pipeline *Pipeline) UnmarshalJSON(data []byte) error {
+	var tagged []transformJSON
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return err
+	}
+	transforms := make(Pipeline, len(tagged))
+	for i, t := range tagged {
+		var transform Transform
+		switch t.Type {
+		case rescalerName:
+			transform = new(Rescaler)
+		case minMaxScalerName:
+			transform = new(MinMaxScaler)
+		case standardizerName:
+			transform = new(Standardizer)
+		case pcaWhiteningName:
+			transform = new(PCAWhitening)
+		default:
+			return fmt.Errorf("Can't deserialize the transform of type %v", t.Type)
+		}
+		if err := json.Unmarshal(t.Transform, transform); err != nil {
+			return err
+		}
+		transforms[i] = transform
+	}
+	*pipeline = transforms
+	return nil
+}
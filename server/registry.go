@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+// Extensions of the files saved with model.SaveModel looked for by a Registry,
+// in either of the formats read by model.LoadModel
+var modelExtensions = []string{".json", model.GobExtension}
+
+// Paths of the endpoints of a Registry
+const (
+	// GET the active and previous Versions
+	VersionsPath string = "/v1/versions"
+	// POST to swap the previous version back in
+	RollbackPath string = "/v1/rollback"
+)
+
+// Version is a model loaded by a Registry
+type Version struct {
+	// Incremented with every model loaded, starting at 1
+	Number int `json:"number"`
+	// File the model was loaded from
+	Filename string `json:"filename"`
+	// Modification time of the file when it was loaded
+	ModTime time.Time `json:"modified_at"`
+	// Time at which the model was loaded
+	LoadedAt time.Time `json:"loaded_at"`
+	model    *Model
+}
+
+// Versions are the versions known by a Registry
+type Versions struct {
+	// Version being served, nil until a valid model is found
+	Active *Version `json:"active"`
+	// Version served before the active one, nil if there's none
+	Previous *Version `json:"previous,omitempty"`
+}
+
+// Registry watches a directory of model files and swaps the newest one into
+// a Server while it's running, e.g. to serve a model as soon as it's
+// retrained. A new file is validated before being swapped in, and the
+// previous version is kept so it can be rolled back to
+type Registry struct {
+	mutex     sync.Mutex
+	directory string
+	server    *Server
+	versions  Versions
+	// number of the last version loaded
+	number int
+	// modification time of each file already tried, whether it was loaded
+	// or rejected, so it isn't tried again until it's modified
+	tried map[string]time.Time
+	mux   *http.ServeMux
+}
+
+// NewRegistry creates a Registry serving the models of the directory with
+// the server, and swaps in the newest one. The server isn't ready until a
+// valid model is found
+func NewRegistry(directory string, server *Server) (*Registry, error) {
+	registry := &Registry{directory: directory, server: server, tried: make(map[string]time.Time), mux: http.NewServeMux()}
+	registry.mux.HandleFunc(VersionsPath, registry.handleVersions)
+	registry.mux.HandleFunc(RollbackPath, registry.handleRollback)
+	if _, err := ioutil.ReadDir(directory); err != nil {
+		return nil, fmt.Errorf("Can't read the model directory %v: %v", directory, err)
+	}
+	if _, err := registry.Poll(); err != nil {
+		log.Println(err)
+	}
+	return registry, nil
+}
+
+// Poll swaps in the newest model file of the directory, if it wasn't tried
+// yet and is valid. It returns whether a new version was swapped in
+func (registry *Registry) Poll() (bool, error) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	files, err := ioutil.ReadDir(registry.directory)
+	if err != nil {
+		return false, fmt.Errorf("Can't read the model directory %v: %v", registry.directory, err)
+	}
+	newest := -1
+	for i, file := range files {
+		if !file.Mode().IsRegular() || !isModelFile(file.Name()) {
+			continue
+		}
+		// the files are sorted by name, which breaks the ties
+		if newest < 0 || !file.ModTime().Before(files[newest].ModTime()) {
+			newest = i
+		}
+	}
+	if newest < 0 {
+		return false, nil
+	}
+	filename := filepath.Join(registry.directory, files[newest].Name())
+	modTime := files[newest].ModTime()
+	if tried, ok := registry.tried[filename]; ok && tried.Equal(modTime) {
+		return false, nil
+	}
+	registry.tried[filename] = modTime
+
+	candidate, err := LoadModel(filename)
+	if err != nil {
+		return false, fmt.Errorf("Can't load model %v: %v", filename, err)
+	}
+	var active *Model
+	if registry.versions.Active != nil {
+		active = registry.versions.Active.model
+	}
+	if err := validateModel(candidate, active); err != nil {
+		return false, fmt.Errorf("Can't swap in model %v: %v", filename, err)
+	}
+
+	registry.number++
+	candidate.Version = registry.number
+	version := &Version{
+		Number:   registry.number,
+		Filename: filename,
+		ModTime:  modTime,
+		LoadedAt: candidate.LoadedAt,
+		model:    candidate,
+	}
+	registry.versions.Previous, registry.versions.Active = registry.versions.Active, version
+	registry.server.SetModel(candidate)
+	return true, nil
+}
+
+// Returns whether the file has the extension of a saved model
+func isModelFile(name string) bool {
+	for _, extension := range modelExtensions {
+		if filepath.Ext(name) == extension {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the model can't be served, i.e. if the shapes
+// of its parameters don't match each other, any of them isn't finite or its
+// preprocessing doesn't output the inputs of the network
+func (served *Model) Validate() error {
+	return validateModel(served, nil)
+}
+
+// Returns an error if the model can't replace the active one, i.e. if the
+// shapes of its parameters don't match each other, any of them isn't finite,
+// its inputs don't have the same features as the active model, or its
+// preprocessing doesn't output the inputs of the network. active may be nil
+func validateModel(candidate, active *Model) error {
+	parameters := candidate.Parameters
+	if parameters == nil || parameters.W1 == nil {
+		return fmt.Errorf("Can't use a model without parameters")
+	}
+	hidden, inputs := parameters.W1.GetRows(), parameters.W1.GetColumns()
+	shapes := []struct {
+		name  string
+		array matrix.NumberArray
+		rows  int
+		cols  int
+	}{
+		{"W1", parameters.W1, hidden, inputs},
+		{"B1", parameters.B1, hidden, 1},
+		{"W2", parameters.W2, 1, hidden},
+		{"B2", parameters.B2, 1, 1},
+	}
+	for _, shape := range shapes {
+		if shape.array == nil {
+			return fmt.Errorf("Can't use a model without parameter %v", shape.name)
+		}
+		if shape.array.GetRows() != shape.rows || shape.array.GetColumns() != shape.cols {
+			return fmt.Errorf("Can't use parameter %v of shape %vx%v, expected %vx%v", shape.name,
+				shape.array.GetRows(), shape.array.GetColumns(), shape.rows, shape.cols)
+		}
+		for _, row := range matrix.ToSlice(shape.array) {
+			for _, value := range row {
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					return fmt.Errorf("Can't use parameter %v with value %v", shape.name, value)
+				}
+			}
+		}
+	}
+	if active != nil && candidate.NumberFeatures() != active.NumberFeatures() {
+		return fmt.Errorf("Can't replace a model of %v features with one of %v features",
+			active.NumberFeatures(), candidate.NumberFeatures())
+	}
+	// predicting an example checks that the preprocessing fits the network
+	predictor := model.NewPredictor(parameters, candidate.Pipeline)
+	if _, err := predictor.PredictExample(make([]float64, candidate.NumberFeatures())); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Watch polls the directory every interval until the context is done,
+// logging the models swapped in and the errors
+func (registry *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			swapped, err := registry.Poll()
+			if err != nil {
+				log.Println(err)
+			} else if swapped {
+				active := registry.Versions().Active
+				log.Printf("Swapped in version %v from %v\n", active.Number, active.Filename)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Rollback swaps the previous version back in, so that the active version
+// becomes the previous one. The file of the rolled back version isn't
+// swapped in again unless it's modified
+func (registry *Registry) Rollback() error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if registry.versions.Previous == nil {
+		return fmt.Errorf("Can't roll back without a previous version")
+	}
+	versions := &registry.versions
+	versions.Active, versions.Previous = versions.Previous, versions.Active
+	registry.server.SetModel(versions.Active.model)
+	return nil
+}
+
+// Versions returns the active and previous versions
+func (registry *Registry) Versions() Versions {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	return registry.versions
+}
+
+// ServeHTTP dispatches the request to the handler of its path
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	registry.mux.ServeHTTP(w, r)
+}
+
+// Reports the active and previous versions
+func (registry *Registry) handleVersions(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, registry.Versions())
+}
+
+// Rolls back to the previous version and reports the versions
+func (registry *Registry) handleRollback(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if err := registry.Rollback(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, registry.Versions())
+}
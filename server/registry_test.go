@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+)
+
+// Saves the parameters of the test model with W2 as the output weight to
+// the file of the directory, modified at the given time
+func saveVersion(t *testing.T, directory, name string, outputWeight float64, modTime time.Time) {
+	served := testModel()
+	served.Parameters.W2, _ = matrix.NewMatrixFromSlice([][]float64{{outputWeight}})
+	filename := filepath.Join(directory, name)
+	if err := model.SaveModel(filename, served.Parameters, served.Pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Returns the number of the active version of the server, 0 if there's none
+func servedVersion(server *Server) int {
+	if served := server.Model(); served != nil {
+		return served.Version
+	}
+	return 0
+}
+
+func TestRegistry(t *testing.T) {
+	directory := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	server := NewServer(nil, 0.5)
+	registry, err := NewRegistry(directory, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if servedVersion(server) != 0 || registry.Versions().Active != nil {
+		t.Fatalf("Expected no model in an empty directory, Actual: %v\n", registry.Versions())
+	}
+
+	// features of a model that can't replace the test model
+	wrongFeatures, _ := matrix.NewMatrixFromSlice([][]float64{{1, 2}})
+	tables := []struct {
+		write            func()
+		expectedSwapped  bool
+		expectedError    bool
+		expectedActive   int
+		expectedPrevious int
+	}{
+		{func() { saveVersion(t, directory, "a.json", 10, start) }, true, false, 1, 0},
+		// nothing new
+		{func() {}, false, false, 1, 0},
+		{func() { saveVersion(t, directory, "b.json", 20, start.Add(time.Minute)) }, true, false, 2, 1},
+		// older than the active version
+		{func() { saveVersion(t, directory, "0.json", 30, start.Add(-time.Minute)) }, false, false, 2, 1},
+		// not a model file
+		{func() { ioutil.WriteFile(filepath.Join(directory, "notes.txt"), []byte("retrained"), 0644) }, false, false, 2, 1},
+		// truncated file
+		{func() {
+			filename := filepath.Join(directory, "c.json")
+			ioutil.WriteFile(filename, []byte(`{"parameters": {`), 0644)
+			os.Chtimes(filename, start.Add(2*time.Minute), start.Add(2*time.Minute))
+		}, false, true, 2, 1},
+		// the rejected file isn't tried again
+		{func() {}, false, false, 2, 1},
+		// shapes that don't match
+		{func() {
+			served := testModel()
+			served.Parameters.W2 = wrongFeatures
+			filename := filepath.Join(directory, "d.json")
+			model.SaveModel(filename, served.Parameters, served.Pipeline)
+			os.Chtimes(filename, start.Add(3*time.Minute), start.Add(3*time.Minute))
+		}, false, true, 2, 1},
+		// a different number of features
+		{func() {
+			served := testModel()
+			served.Parameters.W1 = wrongFeatures
+			filename := filepath.Join(directory, "e.json")
+			model.SaveModel(filename, served.Parameters, nil)
+			os.Chtimes(filename, start.Add(4*time.Minute), start.Add(4*time.Minute))
+		}, false, true, 2, 1},
+		// the rejected file is tried again once modified
+		{func() { saveVersion(t, directory, "e.json", 40, start.Add(5*time.Minute)) }, true, false, 3, 2},
+		// a model in the gob format
+		{func() { saveVersion(t, directory, "f"+model.GobExtension, 50, start.Add(6*time.Minute)) }, true, false, 4, 3},
+	}
+	for i, table := range tables {
+		table.write()
+		swapped, err := registry.Poll()
+		if swapped != table.expectedSwapped || (err != nil) != table.expectedError {
+			t.Errorf("%v Expected: %v %v, Actual: %v %v\n", i, table.expectedSwapped, table.expectedError, swapped, err)
+		}
+		versions := registry.Versions()
+		previous := 0
+		if versions.Previous != nil {
+			previous = versions.Previous.Number
+		}
+		if versions.Active.Number != table.expectedActive || previous != table.expectedPrevious ||
+			servedVersion(server) != table.expectedActive {
+			t.Errorf("%v Expected: %v %v, Actual: %v %v %v\n", i, table.expectedActive, table.expectedPrevious,
+				versions.Active.Number, previous, servedVersion(server))
+		}
+	}
+
+	if err := registry.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if servedVersion(server) != 3 || registry.Versions().Previous.Number != 4 {
+		t.Errorf("Expected: 3, Actual: %v %v\n", servedVersion(server), registry.Versions())
+	}
+	// the rolled back file isn't swapped in again
+	if swapped, err := registry.Poll(); swapped || err != nil || servedVersion(server) != 3 {
+		t.Errorf("Expected: 3, Actual: %v %v %v\n", swapped, err, servedVersion(server))
+	}
+}
+
+func TestRegistryEndpoints(t *testing.T) {
+	directory := t.TempDir()
+	server := NewServer(nil, 0.5)
+	saveVersion(t, directory, "a.json", 10, time.Now().Add(-time.Minute))
+	registry, err := NewRegistry(directory, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder := do(registry, http.MethodPost, RollbackPath, ""); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected: %v, Actual: %v\n", http.StatusConflict, recorder.Code)
+	}
+	saveVersion(t, directory, "b.json", 20, time.Now())
+	registry.Poll()
+
+	tables := []struct {
+		method           string
+		path             string
+		expectedStatus   int
+		expectedActive   int
+		expectedPrevious int
+	}{
+		{http.MethodGet, VersionsPath, http.StatusOK, 2, 1},
+		{http.MethodPost, RollbackPath, http.StatusOK, 1, 2},
+		{http.MethodPost, RollbackPath, http.StatusOK, 2, 1},
+		{http.MethodGet, RollbackPath, http.StatusMethodNotAllowed, 0, 0},
+	}
+	for _, table := range tables {
+		recorder := do(registry, table.method, table.path, "")
+		if recorder.Code != table.expectedStatus {
+			t.Errorf("Expected: %v, Actual: %v %v\n", table.expectedStatus, recorder.Code, recorder.Body)
+			continue
+		}
+		if table.expectedStatus != http.StatusOK {
+			continue
+		}
+		var versions Versions
+		if err := json.Unmarshal(recorder.Body.Bytes(), &versions); err != nil {
+			t.Fatal(err)
+		}
+		if versions.Active.Number != table.expectedActive || versions.Previous.Number != table.expectedPrevious {
+			t.Errorf("Expected: %v %v, Actual: %+v %+v\n", table.expectedActive, table.expectedPrevious,
+				versions.Active, versions.Previous)
+		}
+	}
+
+	var metadata Metadata
+	json.Unmarshal(do(server, http.MethodGet, MetadataPath, "").Body.Bytes(), &metadata)
+	if metadata.Version != 2 {
+		t.Errorf("Expected: 2, Actual: %v\n", metadata.Version)
+	}
+	if _, err := NewRegistry(filepath.Join(directory, "missing"), server); err == nil {
+		t.Errorf("Expected an error watching a missing directory\n")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := testModel().Validate(); err != nil {
+		t.Errorf("Expected no error, Actual: %v\n", err)
+	}
+	nonFinite := testModel()
+	nonFinite.Parameters.W2, _ = matrix.NewMatrixFromSlice([][]float64{{math.NaN()}})
+	wrongShape := testModel()
+	wrongShape.Parameters.B1, _ = matrix.NewMatrixFromSlice([][]float64{{1, 2}})
+	for _, served := range []*Model{nonFinite, wrongShape} {
+		if err := served.Validate(); err == nil {
+			t.Errorf("Expected an error validating %v\n", served.Parameters)
+		}
+	}
+}
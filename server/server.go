@@ -0,0 +1,475 @@
+// Package server serves the predictions of a trained model over HTTP with a
+// JSON API, along with the health, readiness and metadata endpoints used by
+// orchestrators and clients
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	// decoders of the supported image formats
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/dataset"
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+// Paths of the endpoints
+const (
+	// POST a PredictRequest to get a PredictResponse
+	PredictPath string = "/v1/predict"
+	// GET the Metadata of the model
+	MetadataPath string = "/v1/metadata"
+	// GET returns 200 while the server is running
+	HealthPath string = "/healthz"
+	// GET returns 200 once a model is loaded and 503 before
+	ReadinessPath string = "/readyz"
+)
+
+// Names of the APIs given to the PredictionObserver
+const (
+	HTTPAPI string = "http"
+	GRPCAPI string = "grpc"
+)
+
+// Largest request body accepted, in bytes
+const maxRequestSize int64 = 32 << 20
+
+// Each pixel is coded in RGB, therefore contains 3 values
+const colorsChannel int = 3
+
+// Largest image accepted, in pixels. It's far larger than any input of the
+// models, which images are resized to, and only guards against small files
+// that decode to huge images
+const maxImagePixels int = 4096 * 4096
+
+// Model is a trained model ready to be served
+type Model struct {
+	// Name identifying the model, e.g. the file it was loaded from
+	Name       string
+	Parameters *model.Parameters
+	// Applied to the inputs before predicting, may be nil
+	Pipeline preprocessing.Pipeline
+	// Predicts with a copy of the parameters and the preprocessing, from
+	// many requests at once
+	Predictor *model.Predictor
+	// Time at which the model was loaded
+	LoadedAt time.Time
+	// Version given by the Registry that loaded the model, 0 otherwise
+	Version int
+	// Size the images are resized to, zero when the inputs of the model
+	// aren't square RGB images
+	ImageWidth  int
+	ImageHeight int
+}
+
+// NewModel creates a Model from the parameters and preprocessing returned by
+// model.LoadModel. When the number of features is that of a square RGB image,
+// as for the cat datasets, the images sent for prediction are resized to it
+func NewModel(name string, parameters *model.Parameters, pipeline preprocessing.Pipeline) *Model {
+	served := &Model{
+		Name:       name,
+		Parameters: parameters,
+		Pipeline:   pipeline,
+		Predictor:  model.NewPredictor(parameters, pipeline),
+		LoadedAt:   time.Now(),
+	}
+	features := served.NumberFeatures()
+	side := int(math.Sqrt(float64(features / colorsChannel)))
+	if features%colorsChannel == 0 && side*side*colorsChannel == features {
+		served.ImageWidth, served.ImageHeight = side, side
+	}
+	return served
+}
+
+// LoadModel loads the model saved with model.SaveModel to filename
+func LoadModel(filename string) (*Model, error) {
+	parameters, pipeline, err := model.LoadModel(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewModel(filename, parameters, pipeline), nil
+}
+
+// NumberFeatures returns the number of features of the inputs of the model,
+// before the preprocessing
+func (served *Model) NumberFeatures() int {
+	return served.Predictor.NumberFeatures()
+}
+
+// PredictRequest holds the examples to predict. Each instance is the feature
+// vector of an example, while each image is the base64 encoding of a PNG,
+// JPEG or GIF file that is resized to the input of the model. Images of more
+// than 16 megapixels are rejected before being decoded, so that a small file
+// can't make the server allocate a huge image. The
+// predictions of the instances are followed by those of the images
+type PredictRequest struct {
+	Instances [][]float64 `json:"instances,omitempty"`
+	Images    []string    `json:"images,omitempty"`
+}
+
+// Prediction of a single example
+type Prediction struct {
+	// Probability of the example belonging to the class 1
+	Probability float64 `json:"probability"`
+	// 1 when the probability is above the threshold, 0 otherwise
+	Label int `json:"label"`
+}
+
+// PredictResponse holds the predictions of the examples of the request, in
+// the same order
+type PredictResponse struct {
+	Model       string       `json:"model"`
+	Predictions []Prediction `json:"predictions"`
+}
+
+// Metadata describes the model being served
+type Metadata struct {
+	Name           string    `json:"name"`
+	Version        int       `json:"version,omitempty"`
+	LoadedAt       time.Time `json:"loaded_at"`
+	NumberFeatures int       `json:"number_features"`
+	NumberHidden   int       `json:"number_hidden_units"`
+	Threshold      float64   `json:"threshold"`
+	ImageWidth     int       `json:"image_width,omitempty"`
+	ImageHeight    int       `json:"image_height,omitempty"`
+	Preprocessing  []string  `json:"preprocessing,omitempty"`
+}
+
+// Body of the responses of the failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// PredictionObserver is notified of every prediction request answered, e.g.
+// to export metrics about them
+type PredictionObserver interface {
+	// ObservePrediction is called with the API that answered the request,
+	// the number of examples predicted, the time taken to answer and the
+	// error answered, nil if the request succeeded
+	ObservePrediction(api string, examples int, latency time.Duration, err error)
+}
+
+// Batcher of the model being served. It's closed once the model is replaced
+// and the requests using it are done
+type batching struct {
+	served  *Model
+	batcher *model.Batcher
+	// requests predicting with the batcher
+	users sync.WaitGroup
+}
+
+// Server is an http.Handler serving the predictions of a model. The model
+// can be replaced with SetModel while serving
+type Server struct {
+	mutex     sync.RWMutex
+	model     *Model
+	observer  PredictionObserver
+	threshold float64
+	mux       *http.ServeMux
+	// Batching of the single examples, none when maxBatchSize is below 2
+	maxBatchSize int
+	maxLatency   time.Duration
+	batching     *batching
+}
+
+// NewServer creates a Server of the model, labelling as 1 the examples whose
+// probability is above the threshold. The model may be nil, in which case the
+// server isn't ready until one is set
+func NewServer(served *Model, threshold float64) *Server {
+	server := &Server{model: served, threshold: threshold, mux: http.NewServeMux()}
+	server.mux.HandleFunc(PredictPath, server.handlePredict)
+	server.mux.HandleFunc(MetadataPath, server.handleMetadata)
+	server.mux.HandleFunc(HealthPath, server.handleHealth)
+	server.mux.HandleFunc(ReadinessPath, server.handleReadiness)
+	return server
+}
+
+// SetModel replaces the model being served
+func (server *Server) SetModel(served *Model) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.model = served
+	server.replaceBatcher()
+}
+
+// SetBatching makes the requests of a single example wait up to maxLatency
+// for others to be predicted together in a batch of up to maxBatchSize
+// examples, which takes a single forward pass. There's no batching when
+// maxBatchSize is below 2, as by default
+func (server *Server) SetBatching(maxBatchSize int, maxLatency time.Duration) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.maxBatchSize = maxBatchSize
+	server.maxLatency = maxLatency
+	server.replaceBatcher()
+}
+
+// Replaces the batcher with one of the model being served, if there's
+// batching. The previous batcher is closed in the background once the
+// requests using it are done. It must be called with the mutex locked
+func (server *Server) replaceBatcher() {
+	if previous := server.batching; previous != nil {
+		go func() {
+			previous.users.Wait()
+			previous.batcher.Close()
+		}()
+	}
+	server.batching = nil
+	if server.model != nil && server.maxBatchSize > 1 {
+		server.batching = &batching{
+			served:  server.model,
+			batcher: model.NewBatcher(server.model.Predictor, server.maxBatchSize, server.maxLatency),
+		}
+	}
+}
+
+// Returns the batching of served, nil if there's no batching or served isn't
+// the model being served anymore. Done must be called on its users once the
+// prediction is done
+func (server *Server) acquireBatching(served *Model) *batching {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+	if server.batching == nil || server.batching.served != served {
+		return nil
+	}
+	server.batching.users.Add(1)
+	return server.batching
+}
+
+// Predict returns the probability of each example of X, with one example per
+// column, belonging to the class 1 according to served, running the forward
+// pass over chunks of chunkSize examples and stopping with the error of the
+// context as soon as it's done. A single example is predicted in a batch with
+// those of other requests when there's batching and served is still the
+// model being served
+func (server *Server) Predict(ctx context.Context, served *Model, X matrix.NumberArray, chunkSize int) (matrix.NumberArray, error) {
+	if X.GetColumns() == 1 {
+		if current := server.acquireBatching(served); current != nil {
+			defer current.users.Done()
+			features := make([]float64, X.GetRows())
+			for i := range features {
+				features[i], _ = X.GetValue(i, 0)
+			}
+			probability, err := current.batcher.Predict(ctx, features)
+			if err != nil {
+				return nil, err
+			}
+			A2, _ := matrix.NewMatrix(1, 1)
+			A2.SetValue(0, 0, probability)
+			return A2, nil
+		}
+	}
+	return served.Predictor.PredictContext(ctx, X, chunkSize)
+}
+
+// Model returns the model being served, nil if there's none
+func (server *Server) Model() *Model {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+	return server.model
+}
+
+// SetObserver sets the observer notified of the prediction requests, none
+// when nil
+func (server *Server) SetObserver(observer PredictionObserver) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.observer = observer
+}
+
+// Observer returns the observer notified of the prediction requests, nil if
+// there's none
+func (server *Server) Observer() PredictionObserver {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+	return server.observer
+}
+
+// ServeHTTP dispatches the request to the handler of its path
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+// Writes value as the JSON body of the response with the given status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// Writes the error as the JSON body of the response with the given status
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// Returns false and writes an error response if the method of the request
+// isn't the given one
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Can't %v %v, only %v is allowed", r.Method, r.URL.Path, method))
+	return false
+}
+
+// Returns the model being served, writing an error response if there's none
+func (server *Server) readyModel(w http.ResponseWriter) *Model {
+	served := server.Model()
+	if served == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("No model loaded"))
+	}
+	return served
+}
+
+// Builds the inputs of the model, with one example per column, from the
+// instances and images of the request
+func inputs(served *Model, request *PredictRequest) (matrix.NumberArray, error) {
+	numberFeatures := served.NumberFeatures()
+	examples := make([][]float64, 0, len(request.Instances)+len(request.Images))
+	for i, instance := range request.Instances {
+		if len(instance) != numberFeatures {
+			return nil, fmt.Errorf("Can't predict instance %v with %v features, the model has %v", i, len(instance), numberFeatures)
+		}
+		examples = append(examples, instance)
+	}
+	if len(request.Images) > 0 && served.ImageWidth == 0 {
+		return nil, fmt.Errorf("Can't predict images, the inputs of the model aren't images")
+	}
+	for i, encoded := range request.Images {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("Can't decode the base64 of image %v: %v", i, err)
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("Can't decode image %v: %v", i, err)
+		}
+		// compare without multiplying, as the dimensions come from the file
+		if config.Width > 0 && config.Height > maxImagePixels/config.Width {
+			return nil, fmt.Errorf("Can't predict image %v of %vx%v, larger than %v pixels",
+				i, config.Width, config.Height, maxImagePixels)
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("Can't decode image %v: %v", i, err)
+		}
+		features, err := dataset.ImageFeatures(img, served.ImageWidth, served.ImageHeight)
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, features)
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("Can't predict a request without instances nor images")
+	}
+
+	X, err := matrix.NewMatrix(numberFeatures, len(examples))
+	if err != nil {
+		return nil, err
+	}
+	for j, example := range examples {
+		for i, value := range example {
+			X.SetValue(i, j, value)
+		}
+	}
+	return X, nil
+}
+
+// Predicts the examples of the PredictRequest in the body
+func (server *Server) handlePredict(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	examples, err := server.predict(w, r)
+	if observer := server.Observer(); observer != nil {
+		observer.ObservePrediction(HTTPAPI, examples, time.Since(start), err)
+	}
+}
+
+// Writes the response to the PredictRequest in the body, returning the
+// number of examples predicted and the error written instead, if any
+func (server *Server) predict(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return 0, fmt.Errorf("Can't %v %v", r.Method, r.URL.Path)
+	}
+	served := server.readyModel(w)
+	if served == nil {
+		return 0, fmt.Errorf("No model loaded")
+	}
+	var request PredictRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&request); err != nil {
+		err = fmt.Errorf("Can't decode the request: %v", err)
+		writeError(w, http.StatusBadRequest, err)
+		return 0, err
+	}
+	X, err := inputs(served, &request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return 0, err
+	}
+
+	A2, err := server.Predict(r.Context(), served, X, 0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return 0, err
+	}
+	response := PredictResponse{Model: served.Name, Predictions: make([]Prediction, A2.GetColumns())}
+	for j := range response.Predictions {
+		probability, _ := A2.GetValue(0, j)
+		response.Predictions[j].Probability = probability
+		if probability > server.threshold {
+			response.Predictions[j].Label = 1
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+	return len(response.Predictions), nil
+}
+
+// Describes the model being served
+func (server *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	served := server.readyModel(w)
+	if served == nil {
+		return
+	}
+	metadata := Metadata{
+		Name:           served.Name,
+		Version:        served.Version,
+		LoadedAt:       served.LoadedAt,
+		NumberFeatures: served.NumberFeatures(),
+		NumberHidden:   served.Parameters.W1.GetRows(),
+		Threshold:      server.threshold,
+		ImageWidth:     served.ImageWidth,
+		ImageHeight:    served.ImageHeight,
+	}
+	for _, transform := range served.Pipeline {
+		metadata.Preprocessing = append(metadata.Preprocessing, preprocessing.TransformName(transform))
+	}
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+// Answers while the server is running
+func (server *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Answers once a model is loaded
+func (server *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	if server.readyModel(w) == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
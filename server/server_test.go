@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chibby0ne/micro_neural_network/matrix"
+	"github.com/chibby0ne/micro_neural_network/model"
+	"github.com/chibby0ne/micro_neural_network/preprocessing"
+)
+
+// Model of 2x2 RGB images that predicts the class 1 when the red value of
+// the first pixel is high
+func testModel() *Model {
+	weights := make([]float64, 12)
+	weights[0] = 0.01
+	W1, _ := matrix.NewMatrixFromSlice([][]float64{weights})
+	B1, _ := matrix.NewMatrixFromSlice([][]float64{{-1}})
+	W2, _ := matrix.NewMatrixFromSlice([][]float64{{10}})
+	B2, _ := matrix.NewMatrixFromSlice([][]float64{{0}})
+	parameters := &model.Parameters{W1: W1, B1: B1, W2: W2, B2: B2}
+	pipeline := preprocessing.Pipeline{preprocessing.NewRescaler(1)}
+	return NewModel("test", parameters, pipeline)
+}
+
+// Returns a feature vector of the test model whose first value is red
+func instance(red float64) []float64 {
+	features := make([]float64, 12)
+	features[0] = red
+	return features
+}
+
+// Returns the base64 encoding of a 2x2 PNG image whose first pixel has the
+// given red value
+func encodedImage(t *testing.T, red uint8) string {
+	return encodedImageOfSize(t, red, 2, 2)
+}
+
+// Returns the base64 encoding of a PNG image of the given size whose first
+// pixel has the given red value
+func encodedImageOfSize(t *testing.T, red uint8, width, height int) string {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{red, 0, 0, 255})
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buffer.Bytes())
+}
+
+// Returns the base64 encoding of the signature and header of a PNG image of
+// the given size, which is enough to read its size but not to decode it
+func encodedPNGHeader(width, height int) string {
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:4], uint32(width))
+	binary.BigEndian.PutUint32(header[4:8], uint32(height))
+	// 8 bits per channel of RGBA pixels
+	header[8], header[9] = 8, 6
+	var buffer bytes.Buffer
+	buffer.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buffer, binary.BigEndian, uint32(len(header)))
+	chunk := append([]byte("IHDR"), header...)
+	buffer.Write(chunk)
+	binary.Write(&buffer, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return base64.StdEncoding.EncodeToString(buffer.Bytes())
+}
+
+// Sends the request to the server and returns the response
+func do(server http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	switch body := body.(type) {
+	case string:
+		reader = bytes.NewReader([]byte(body))
+	default:
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(method, path, reader))
+	return recorder
+}
+
+func TestPredict(t *testing.T) {
+	server := NewServer(testModel(), 0.5)
+	tables := []struct {
+		request        interface{}
+		expectedStatus int
+		expectedLabels []int
+	}{
+		{PredictRequest{Instances: [][]float64{instance(255), instance(0)}}, http.StatusOK, []int{1, 0}},
+		{PredictRequest{Images: []string{encodedImage(t, 0), encodedImage(t, 255)}}, http.StatusOK, []int{0, 1}},
+		{PredictRequest{Instances: [][]float64{instance(0)}, Images: []string{encodedImage(t, 255)}}, http.StatusOK, []int{0, 1}},
+		{PredictRequest{Instances: [][]float64{{1, 2}}}, http.StatusBadRequest, nil},
+		{PredictRequest{Images: []string{"not base64!"}}, http.StatusBadRequest, nil},
+		// images are resized to the 2x2 input, whether they are smaller or larger
+		{PredictRequest{Images: []string{encodedImageOfSize(t, 255, 1, 1)}}, http.StatusOK, []int{1}},
+		{PredictRequest{Images: []string{encodedImageOfSize(t, 255, 3, 2)}}, http.StatusOK, []int{1}},
+		{PredictRequest{Images: []string{encodedImageOfSize(t, 0, 3, 2)}}, http.StatusOK, []int{0}},
+		// images too large are rejected from their header, without decoding them
+		{PredictRequest{Images: []string{encodedPNGHeader(1<<20, 1<<20)}}, http.StatusBadRequest, nil},
+		{PredictRequest{Images: []string{encodedPNGHeader(maxImagePixels+1, 1)}}, http.StatusBadRequest, nil},
+		{PredictRequest{}, http.StatusBadRequest, nil},
+		{"{not json", http.StatusBadRequest, nil},
+	}
+	for _, table := range tables {
+		recorder := do(server, http.MethodPost, PredictPath, table.request)
+		if recorder.Code != table.expectedStatus {
+			t.Errorf("Expected: %v, Actual: %v %v\n", table.expectedStatus, recorder.Code, recorder.Body)
+			continue
+		}
+		if table.expectedStatus != http.StatusOK {
+			var response errorResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Error == "" {
+				t.Errorf("Expected an error message, Actual: %v\n", recorder.Body)
+			}
+			continue
+		}
+		var response PredictResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Predictions) != len(table.expectedLabels) {
+			t.Fatalf("Expected: %v predictions, Actual: %v\n", len(table.expectedLabels), response.Predictions)
+		}
+		for i, prediction := range response.Predictions {
+			if prediction.Label != table.expectedLabels[i] {
+				t.Errorf("Expected: %v, Actual: %v\n", table.expectedLabels[i], prediction)
+			}
+		}
+	}
+	if recorder := do(server, http.MethodGet, PredictPath, ""); recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected: %v, Actual: %v\n", http.StatusMethodNotAllowed, recorder.Code)
+	}
+}
+
+// Sends a request of a single instance whose first feature is red and
+// returns the label predicted, -1 if the request failed
+func predictLabel(server http.Handler, red float64) int {
+	recorder := do(server, http.MethodPost, PredictPath, PredictRequest{Instances: [][]float64{instance(red)}})
+	var response PredictResponse
+	if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &response) != nil || len(response.Predictions) != 1 {
+		return -1
+	}
+	return response.Predictions[0].Label
+}
+
+func TestBatching(t *testing.T) {
+	server := NewServer(testModel(), 0.5)
+	// batches are only predicted once full, so the requests only succeed
+	// when they're predicted together
+	server.SetBatching(4, time.Hour)
+	labels := make([]int, 4)
+	var wait sync.WaitGroup
+	for i := range labels {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			labels[i] = predictLabel(server, float64(255*(i%2)))
+		}(i)
+	}
+	wait.Wait()
+	for i, label := range labels {
+		if label != i%2 {
+			t.Errorf("Expected: %v, Actual: %v\n", i%2, label)
+		}
+	}
+	// requests of several examples aren't batched
+	recorder := do(server, http.MethodPost, PredictPath, PredictRequest{Instances: [][]float64{instance(255), instance(0)}})
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected: %v, Actual: %v %v\n", http.StatusOK, recorder.Code, recorder.Body)
+	}
+
+	// a batch that isn't full is predicted after the latency, with the
+	// batcher of the model replacing the previous one
+	server.SetBatching(4, time.Millisecond)
+	server.SetModel(testModel())
+	if label := predictLabel(server, 255); label != 1 {
+		t.Errorf("Expected: %v, Actual: %v\n", 1, label)
+	}
+	server.SetBatching(0, 0)
+	if label := predictLabel(server, 0); label != 0 {
+		t.Errorf("Expected: %v, Actual: %v\n", 0, label)
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	server := NewServer(nil, 0.5)
+	tables := []struct {
+		path           string
+		served         *Model
+		expectedStatus int
+	}{
+		{HealthPath, nil, http.StatusOK},
+		{ReadinessPath, nil, http.StatusServiceUnavailable},
+		{MetadataPath, nil, http.StatusServiceUnavailable},
+		{HealthPath, testModel(), http.StatusOK},
+		{ReadinessPath, testModel(), http.StatusOK},
+	}
+	for _, table := range tables {
+		server.SetModel(table.served)
+		if recorder := do(server, http.MethodGet, table.path, ""); recorder.Code != table.expectedStatus {
+			t.Errorf("%v Expected: %v, Actual: %v\n", table.path, table.expectedStatus, recorder.Code)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	server := NewServer(testModel(), 0.7)
+	recorder := do(server, http.MethodGet, MetadataPath, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected: %v, Actual: %v\n", http.StatusOK, recorder.Code)
+	}
+	var metadata Metadata
+	if err := json.Unmarshal(recorder.Body.Bytes(), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Name != "test" || metadata.NumberFeatures != 12 || metadata.NumberHidden != 1 ||
+		metadata.Threshold != 0.7 || metadata.ImageWidth != 2 || metadata.ImageHeight != 2 ||
+		strings.Join(metadata.Preprocessing, ",") != "rescale" {
+		t.Errorf("Unexpected metadata: %+v\n", metadata)
+	}
+}